@@ -1,6 +1,10 @@
 package backend
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +17,9 @@ type Backend interface {
 	Destroy(handle string) error
 	Containers() ([]Container, error)
 	Lookup(handle string) (Container, error)
+
+	// BulkInfo gets the info of many containers at once, keyed by handle.
+	BulkInfo(handles []string) map[string]ContainerInfoEntry
 }
 
 type ContainerSpec struct {
@@ -21,6 +28,23 @@ type ContainerSpec struct {
 	RootFSPath string
 	BindMounts []BindMount
 	Network    string
+	Properties Properties
+}
+
+// Properties are arbitrary metadata given to a container when it is
+// created, e.g. to tell which app it runs.
+type Properties map[string]string
+
+// Matches reports whether the properties include every one in the filter.
+func (p Properties) Matches(filter Properties) bool {
+	for key, value := range filter {
+		actual, found := p[key]
+		if !found || actual != value {
+			return false
+		}
+	}
+
+	return true
 }
 
 type BindMount struct {
@@ -39,3 +63,82 @@ type BindMountOrigin uint8
 
 const BindMountOriginHost BindMountOrigin = 0
 const BindMountOriginContainer BindMountOrigin = 1
+
+// ErrorCode says what kind of error a backend returned, so that clients can
+// tell them apart, e.g. to retry when the host is out of capacity but not
+// when a container does not exist.
+type ErrorCode int
+
+const (
+	ErrorCodeUnknown ErrorCode = iota
+	ErrorCodeUnknownHandle
+	ErrorCodeUnknownProcess
+	ErrorCodeCapacityExhausted
+	ErrorCodeInvalidRequest
+	ErrorCodeTimedOut
+)
+
+// CodedError is implemented by errors that say what kind they are. Errors
+// that do not are of unknown kind.
+type CodedError interface {
+	error
+	Code() ErrorCode
+}
+
+// HandleExistsError is returned when creating a container with a handle
+// that is already in use.
+type HandleExistsError struct {
+	Handle string
+}
+
+func (e HandleExistsError) Error() string {
+	return "handle already exists: " + e.Handle
+}
+
+func (e HandleExistsError) Code() ErrorCode {
+	return ErrorCodeInvalidRequest
+}
+
+// InvalidUserError is returned for a user that is not a name or id, e.g.
+// one that would be taken for a flag by the commands it is given to.
+type InvalidUserError struct {
+	User string
+}
+
+func (e InvalidUserError) Error() string {
+	return fmt.Sprintf("invalid user: %q", e.User)
+}
+
+func (e InvalidUserError) Code() ErrorCode {
+	return ErrorCodeInvalidRequest
+}
+
+var userPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*(:[A-Za-z0-9_][A-Za-z0-9_.-]*)?$`)
+
+// ValidateUser checks that a user to run or copy files as, or to give
+// copied files to, is a name or id with an optional group, e.g. "vcap" or
+// "1000:1000". An empty user is valid, meaning the default.
+func ValidateUser(user string) error {
+	if user != "" && !userPattern.MatchString(user) {
+		return InvalidUserError{user}
+	}
+
+	return nil
+}
+
+// IsRoot reports whether running or copying files as the user would be as
+// root, i.e. it is root by name or has uid or gid 0, e.g. "0:0".
+func IsRoot(user string) bool {
+	for i, id := range strings.SplitN(user, ":", 2) {
+		if i == 0 && id == "root" {
+			return true
+		}
+
+		number, err := strconv.ParseUint(id, 10, 32)
+		if err == nil && number == 0 {
+			return true
+		}
+	}
+
+	return false
+}
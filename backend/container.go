@@ -1,6 +1,8 @@
 package backend
 
 import (
+	"io"
+	"os"
 	"time"
 )
 
@@ -8,14 +10,19 @@ type Container interface {
 	ID() string
 	Handle() string
 	GraceTime() time.Duration
+	Properties() Properties
 
 	Start() error
 	Stop(kill bool) error
 
 	Info() (ContainerInfo, error)
 
-	CopyIn(srcPath, dstPath string) error
-	CopyOut(srcPath, dstPath, owner string) error
+	CopyIn(srcPath, dstPath, user string) error
+	CopyOut(srcPath, dstPath, owner, user string) error
+
+	// IsRootUser reports whether running or copying files as the user would
+	// be as root, resolving names against the container's own users.
+	IsRootUser(user string) (bool, error)
 
 	LimitBandwidth(limits BandwidthLimits) error
 	CurrentBandwidthLimits() (BandwidthLimits, error)
@@ -30,22 +37,107 @@ type Container interface {
 	CurrentMemoryLimits() (MemoryLimits, error)
 
 	Run(ProcessSpec) (uint32, <-chan ProcessStream, error)
-	Attach(processID uint32) (<-chan ProcessStream, error)
+	Attach(processID uint32, offsets ProcessStreamOffsets) (<-chan ProcessStream, error)
+	Stdin(processID uint32) (io.WriteCloser, error)
+	SetWindowSize(processID uint32, size WindowSize) error
+	Signal(processID uint32, signal os.Signal) error
+	Wait(processID uint32, timeout time.Duration) (uint32, error)
+	Processes() ([]ProcessInfo, error)
+
+	Supervise(ProcessSpec, RestartPolicy) (uint32, error)
+
+	// SetHealthCheck replaces any health check the container already has.
+	SetHealthCheck(HealthCheckSpec) error
+	RemoveHealthCheck() error
 
 	NetIn(hostPort, containerPort uint32) (uint32, uint32, error)
 	NetOut(network string, port uint32) error
 }
 
 type ProcessSpec struct {
-	Script     string
-	Privileged bool
-	Limits     ResourceLimits
+	Script string
+
+	// Path, when given, is executed directly with Args instead of running
+	// Script with bash.
+	Path string
+	Args []string
+
+	// Env is a list of KEY=VALUE pairs set in the process's environment.
+	Env []string
+	Dir string
+
+	// User is a user name or numeric uid[:gid] in the container. When empty,
+	// Privileged chooses between root and the default unprivileged user.
+	User string
+
+	Privileged  bool
+	Limits      ResourceLimits
+	StreamStdin bool
+	TTY         *TTYSpec
+
+	// Timeout, when nonzero, is how long the process may run before it is
+	// terminated.
+	Timeout time.Duration
+}
+
+type TTYSpec struct {
+	WindowSize *WindowSize
+}
+
+type WindowSize struct {
+	Columns int
+	Rows    int
+}
+
+type RestartMode int
+
+const (
+	RestartNever RestartMode = iota
+	RestartOnFailure
+	RestartAlways
+)
+
+// RestartPolicy says when a supervised process is run again after it exits.
+type RestartPolicy struct {
+	Mode RestartMode
+
+	// MaxRestarts limits how many times the process is restarted; zero means
+	// there is no limit.
+	MaxRestarts int
+
+	// Backoff is the delay before the first restart, doubling with each
+	// restart after that.
+	Backoff time.Duration
 }
 
 type ProcessStream struct {
-	Source     ProcessStreamSource
-	Data       []byte
+	Source ProcessStreamSource
+	Data   []byte
+
+	// Offset is the position of Data in everything the process has written
+	// to Source.
+	Offset uint64
+
 	ExitStatus *uint32
+
+	// TimedOut is set along with ExitStatus if the process was terminated
+	// for running past its timeout.
+	TimedOut bool
+}
+
+// ProcessStreamOffsets are the positions from which to replay each of a
+// process's outputs when attaching to it. A nil offset streams only output
+// written from then on.
+type ProcessStreamOffsets struct {
+	Stdout *uint64
+	Stderr *uint64
+}
+
+// DrainProcessStream keeps a process's output moving when nobody is there
+// to read it; it is still kept in the process's output history.
+func DrainProcessStream(stream <-chan ProcessStream) {
+	for _ = range stream {
+	}
 }
 
 type ProcessStreamSource int32
@@ -57,17 +149,89 @@ const (
 	ProcessStreamSourceStderr
 )
 
+// ProcessInfo describes a process run in a container, whether still running
+// or recently finished.
+type ProcessInfo struct {
+	ID        uint32
+	Command   string
+	StartedAt time.Time
+	Timeout   time.Duration
+
+	// FinishedAt, ExitStatus and TimedOut are only set once the process has
+	// exited.
+	FinishedAt time.Time
+	ExitStatus *uint32
+	TimedOut   bool
+}
+
 type ContainerInfo struct {
-	State         string
-	Events        []string
-	HostIP        string
-	ContainerIP   string
-	ContainerPath string
-	ProcessIDs    []uint32
-	MemoryStat    ContainerMemoryStat
-	CPUStat       ContainerCPUStat
-	DiskStat      ContainerDiskStat
-	BandwidthStat ContainerBandwidthStat
+	State               string
+	Events              []string
+	Properties          Properties
+	HostIP              string
+	ContainerIP         string
+	ContainerPath       string
+	ProcessIDs          []uint32
+	SupervisedProcesses []SupervisedProcessInfo
+	HealthCheck         *HealthCheckInfo
+	MemoryStat          ContainerMemoryStat
+	CPUStat             ContainerCPUStat
+	DiskStat            ContainerDiskStat
+	BandwidthStat       ContainerBandwidthStat
+}
+
+// ContainerInfoEntry is one container's result of a BulkInfo; Err is set
+// if its info could not be gathered.
+type ContainerInfoEntry struct {
+	Info ContainerInfo
+	Err  error
+}
+
+// SupervisedProcessInfo describes a supervised process, which is identified
+// by the ID of the first process it ran.
+type SupervisedProcessInfo struct {
+	ID     uint32
+	Policy RestartPolicy
+
+	// ProcessID is the process currently or most recently run.
+	ProcessID uint32
+	Running   bool
+
+	Restarts       int
+	LastExitStatus *uint32
+}
+
+// HealthCheckSpec describes a process run periodically in a container to
+// tell whether it is healthy; a check passes if the process exits 0.
+type HealthCheckSpec struct {
+	Process ProcessSpec
+
+	Interval time.Duration
+
+	// Timeout is how long a check may run before it counts as failed.
+	Timeout time.Duration
+
+	// FailureThreshold is how many checks in a row must fail before the
+	// container is considered unhealthy.
+	FailureThreshold int
+}
+
+type HealthStatus string
+
+const (
+	HealthStatusUnknown   HealthStatus = "unknown"
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+type HealthCheckInfo struct {
+	Spec HealthCheckSpec
+
+	Status              HealthStatus
+	ConsecutiveFailures int
+
+	// LastCheckedAt is zero until the first check has finished.
+	LastCheckedAt time.Time
 }
 
 type ContainerMemoryStat struct {
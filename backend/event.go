@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTypeCreated       EventType = "created"
+	EventTypeStarted       EventType = "started"
+	EventTypeStopped       EventType = "stopped"
+	EventTypeOutOfMemory   EventType = "out of memory"
+	EventTypeProcessExited EventType = "process exited"
+	EventTypeHealthy       EventType = "healthy"
+	EventTypeUnhealthy     EventType = "unhealthy"
+	EventTypeReaped        EventType = "reaped"
+	EventTypeDestroyed     EventType = "destroyed"
+)
+
+// ContainerEvent is something that happened to a container.
+type ContainerEvent struct {
+	Type   EventType
+	Time   time.Time
+	Handle string
+
+	// Details depend on the type of event, e.g. a process's ID and exit
+	// status when it exits.
+	Details map[string]string
+}
@@ -1,6 +1,7 @@
 package fake_backend
 
 import (
+	"fmt"
 	"io"
 	"sync"
 
@@ -15,6 +16,7 @@ type FakeBackend struct {
 
 	CreateResult    *FakeContainer
 	CreateError     error
+	CreateCallback  func(backend.ContainerSpec)
 	RestoreError    error
 	DestroyError    error
 	ContainersError error
@@ -34,6 +36,34 @@ func (e UnknownHandleError) Error() string {
 	return "unknown handle: " + e.Handle
 }
 
+func (e UnknownHandleError) Code() backend.ErrorCode {
+	return backend.ErrorCodeUnknownHandle
+}
+
+type UnknownProcessError struct {
+	ProcessID uint32
+}
+
+func (e UnknownProcessError) Error() string {
+	return fmt.Sprintf("unknown process: %d", e.ProcessID)
+}
+
+func (e UnknownProcessError) Code() backend.ErrorCode {
+	return backend.ErrorCodeUnknownProcess
+}
+
+type WaitTimedOutError struct {
+	ProcessID uint32
+}
+
+func (e WaitTimedOutError) Error() string {
+	return "timed out waiting for process"
+}
+
+func (e WaitTimedOutError) Code() backend.ErrorCode {
+	return backend.ErrorCodeTimedOut
+}
+
 func New() *FakeBackend {
 	return &FakeBackend{
 		CreatedContainers: make(map[string]*FakeContainer),
@@ -59,6 +89,10 @@ func (b *FakeBackend) Stop() {
 }
 
 func (b *FakeBackend) Create(spec backend.ContainerSpec) (backend.Container, error) {
+	if b.CreateCallback != nil {
+		b.CreateCallback(spec)
+	}
+
 	if b.CreateError != nil {
 		return nil, b.CreateError
 	}
@@ -134,3 +168,20 @@ func (b *FakeBackend) Lookup(handle string) (backend.Container, error) {
 
 	return container, nil
 }
+
+func (b *FakeBackend) BulkInfo(handles []string) map[string]backend.ContainerInfoEntry {
+	infos := make(map[string]backend.ContainerInfoEntry)
+
+	for _, handle := range handles {
+		container, err := b.Lookup(handle)
+		if err != nil {
+			infos[handle] = backend.ContainerInfoEntry{Err: err}
+			continue
+		}
+
+		info, err := container.Info()
+		infos[handle] = backend.ContainerInfoEntry{Info: info, Err: err}
+	}
+
+	return infos
+}
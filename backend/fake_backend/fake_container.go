@@ -2,6 +2,7 @@ package fake_backend
 
 import (
 	"io"
+	"os"
 	"sync"
 	"time"
 
@@ -27,12 +28,33 @@ type FakeContainer struct {
 	CopyOutError error
 	CopiedOut    [][]string
 
+	// RootUsers are users that are root in the container besides those
+	// that are root by name or id, e.g. aliases of uid 0
+	RootUsers       []string
+	IsRootUserError error
+
 	RunError         error
 	RunningProcessID uint32
 	RunningProcesses []backend.ProcessSpec
 
 	AttachError error
-	Attached    []uint32
+	Attached    []AttachSpec
+
+	StdinError  error
+	StdinWriter *FakeStdin
+
+	SignalError error
+	Signalled   []SignalSpec
+
+	WaitError      error
+	WaitExitStatus uint32
+	WaitTimesOut   bool
+	waited         []WaitSpec
+	waitedMutex    *sync.RWMutex
+
+	SetWindowSizeError error
+	windowSizes        []WindowSizeSpec
+	windowSizesMutex   *sync.RWMutex
 
 	StreamedProcessChunks []backend.ProcessStream
 	StreamDelay           time.Duration
@@ -74,6 +96,18 @@ type FakeContainer struct {
 	InfoError    error
 	ReportedInfo backend.ContainerInfo
 
+	ReportedQuotaUID uint32
+
+	ProcessesError    error
+	ReportedProcesses []backend.ProcessInfo
+
+	SuperviseError      error
+	SupervisedProcesses []SuperviseSpec
+
+	SetHealthCheckError    error
+	RemoveHealthCheckError error
+	HealthCheck            *backend.HealthCheckSpec
+
 	SnapshotError  error
 	SavedSnapshots []io.Writer
 	snapshotMutex  *sync.RWMutex
@@ -88,12 +122,80 @@ type StopSpec struct {
 	Killed bool
 }
 
+type AttachSpec struct {
+	ProcessID uint32
+	Offsets   backend.ProcessStreamOffsets
+}
+
+type SignalSpec struct {
+	ProcessID uint32
+	Signal    os.Signal
+}
+
+type SuperviseSpec struct {
+	Spec   backend.ProcessSpec
+	Policy backend.RestartPolicy
+}
+
+type WaitSpec struct {
+	ProcessID uint32
+	Timeout   time.Duration
+}
+
+type WindowSizeSpec struct {
+	ProcessID  uint32
+	WindowSize backend.WindowSize
+}
+
+type FakeStdin struct {
+	written []byte
+	closed  bool
+
+	sync.RWMutex
+}
+
+func (s *FakeStdin) Write(data []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.written = append(s.written, data...)
+
+	return len(data), nil
+}
+
+func (s *FakeStdin) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.closed = true
+
+	return nil
+}
+
+func (s *FakeStdin) Written() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return string(s.written)
+}
+
+func (s *FakeStdin) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
+
 func NewFakeContainer(spec backend.ContainerSpec) *FakeContainer {
 	return &FakeContainer{
 		Spec: spec,
 
-		stopMutex:     new(sync.RWMutex),
-		snapshotMutex: new(sync.RWMutex),
+		StdinWriter: new(FakeStdin),
+
+		stopMutex:        new(sync.RWMutex),
+		windowSizesMutex: new(sync.RWMutex),
+		waitedMutex:      new(sync.RWMutex),
+		snapshotMutex:    new(sync.RWMutex),
 	}
 }
 
@@ -109,6 +211,10 @@ func (c *FakeContainer) GraceTime() time.Duration {
 	return c.Spec.GraceTime
 }
 
+func (c *FakeContainer) Properties() backend.Properties {
+	return c.Spec.Properties
+}
+
 func (c *FakeContainer) Snapshot(snapshot io.Writer) error {
 	if c.SnapshotError != nil {
 		return c.SnapshotError
@@ -166,22 +272,59 @@ func (c *FakeContainer) Info() (backend.ContainerInfo, error) {
 	return c.ReportedInfo, nil
 }
 
-func (c *FakeContainer) CopyIn(src, dst string) error {
+func (c *FakeContainer) QuotaUID() uint32 {
+	return c.ReportedQuotaUID
+}
+
+func (c *FakeContainer) InfoWithDiskStat(diskStat backend.ContainerDiskStat) (backend.ContainerInfo, error) {
+	if c.InfoError != nil {
+		return backend.ContainerInfo{}, c.InfoError
+	}
+
+	info := c.ReportedInfo
+	info.DiskStat = diskStat
+
+	return info, nil
+}
+
+func (c *FakeContainer) Processes() ([]backend.ProcessInfo, error) {
+	if c.ProcessesError != nil {
+		return nil, c.ProcessesError
+	}
+
+	return c.ReportedProcesses, nil
+}
+
+func (c *FakeContainer) IsRootUser(user string) (bool, error) {
+	if c.IsRootUserError != nil {
+		return false, c.IsRootUserError
+	}
+
+	for _, rootUser := range c.RootUsers {
+		if user == rootUser {
+			return true, nil
+		}
+	}
+
+	return backend.IsRoot(user), nil
+}
+
+func (c *FakeContainer) CopyIn(src, dst, user string) error {
 	if c.CopyInError != nil {
 		return c.CopyInError
 	}
 
-	c.CopiedIn = append(c.CopiedIn, []string{src, dst})
+	c.CopiedIn = append(c.CopiedIn, []string{src, dst, user})
 
 	return nil
 }
 
-func (c *FakeContainer) CopyOut(src, dst, owner string) error {
+func (c *FakeContainer) CopyOut(src, dst, owner, user string) error {
 	if c.CopyOutError != nil {
 		return c.CopyOutError
 	}
 
-	c.CopiedOut = append(c.CopiedOut, []string{src, dst, owner})
+	c.CopiedOut = append(c.CopiedOut, []string{src, dst, owner, user})
 
 	return nil
 }
@@ -276,16 +419,109 @@ func (c *FakeContainer) Run(spec backend.ProcessSpec) (uint32, <-chan backend.Pr
 	return c.RunningProcessID, c.fakeAttach(), nil
 }
 
-func (c *FakeContainer) Attach(processID uint32) (<-chan backend.ProcessStream, error) {
+func (c *FakeContainer) Attach(processID uint32, offsets backend.ProcessStreamOffsets) (<-chan backend.ProcessStream, error) {
 	if c.AttachError != nil {
 		return nil, c.AttachError
 	}
 
-	c.Attached = append(c.Attached, processID)
+	c.Attached = append(c.Attached, AttachSpec{processID, offsets})
 
 	return c.fakeAttach(), nil
 }
 
+func (c *FakeContainer) Stdin(processID uint32) (io.WriteCloser, error) {
+	if c.StdinError != nil {
+		return nil, c.StdinError
+	}
+
+	return c.StdinWriter, nil
+}
+
+func (c *FakeContainer) Signal(processID uint32, signal os.Signal) error {
+	if c.SignalError != nil {
+		return c.SignalError
+	}
+
+	c.Signalled = append(c.Signalled, SignalSpec{processID, signal})
+
+	return nil
+}
+
+func (c *FakeContainer) Supervise(spec backend.ProcessSpec, policy backend.RestartPolicy) (uint32, error) {
+	if c.SuperviseError != nil {
+		return 0, c.SuperviseError
+	}
+
+	c.SupervisedProcesses = append(c.SupervisedProcesses, SuperviseSpec{spec, policy})
+
+	return c.RunningProcessID, nil
+}
+
+func (c *FakeContainer) SetHealthCheck(spec backend.HealthCheckSpec) error {
+	if c.SetHealthCheckError != nil {
+		return c.SetHealthCheckError
+	}
+
+	c.HealthCheck = &spec
+
+	return nil
+}
+
+func (c *FakeContainer) RemoveHealthCheck() error {
+	if c.RemoveHealthCheckError != nil {
+		return c.RemoveHealthCheckError
+	}
+
+	c.HealthCheck = nil
+
+	return nil
+}
+
+func (c *FakeContainer) Wait(processID uint32, timeout time.Duration) (uint32, error) {
+	if c.WaitError != nil {
+		return 0, c.WaitError
+	}
+
+	// the server waits on a goroutine of its own, so we need a mutex here
+	c.waitedMutex.Lock()
+	c.waited = append(c.waited, WaitSpec{processID, timeout})
+	c.waitedMutex.Unlock()
+
+	if c.WaitTimesOut {
+		time.Sleep(timeout)
+		return 0, WaitTimedOutError{processID}
+	}
+
+	return c.WaitExitStatus, nil
+}
+
+func (c *FakeContainer) Waited() []WaitSpec {
+	c.waitedMutex.RLock()
+	defer c.waitedMutex.RUnlock()
+
+	return c.waited
+}
+
+func (c *FakeContainer) SetWindowSize(processID uint32, size backend.WindowSize) error {
+	if c.SetWindowSizeError != nil {
+		return c.SetWindowSizeError
+	}
+
+	c.windowSizesMutex.Lock()
+	defer c.windowSizesMutex.Unlock()
+
+	c.windowSizes = append(c.windowSizes, WindowSizeSpec{processID, size})
+
+	return nil
+}
+
+func (c *FakeContainer) WindowSizes() []WindowSizeSpec {
+	c.windowSizesMutex.RLock()
+	defer c.windowSizesMutex.RUnlock()
+
+	return c.windowSizes
+}
+
 func (c *FakeContainer) NetIn(hostPort uint32, containerPort uint32) (uint32, uint32, error) {
 	if c.NetInError != nil {
 		return 0, 0, c.NetInError
@@ -0,0 +1,392 @@
+// Package client talks to a warden server. Requests and responses are the
+// protocol's own messages, so that fields added to them can be used without
+// changing the client.
+package client
+
+import (
+	"fmt"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	"github.com/pivotal-cf-experimental/garden/message_reader"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+)
+
+// MaxIdleConnections is how many connections a client keeps open for
+// later requests.
+const MaxIdleConnections = 8
+
+type SendError struct {
+	OriginalError error
+}
+
+func (e SendError) Error() string {
+	return fmt.Sprintf("failed to send request: %s", e.OriginalError)
+}
+
+// UnstreamedRunError is returned by Run for processes that the server does
+// not stream, which are started with Spawn instead.
+type UnstreamedRunError struct{}
+
+func (e UnstreamedRunError) Error() string {
+	return "detached and supervised processes are not streamed; use Spawn"
+}
+
+// ErrorCode says what kind of error the server responded with, e.g. so
+// that requests can be retried when it is out of capacity. Errors that are
+// not from the server, such as a lost connection, are of unknown kind.
+func ErrorCode(err error) protocol.ErrorResponse_Code {
+	wardenError, ok := err.(*message_reader.WardenError)
+	if !ok {
+		return protocol.ErrorResponse_unknown
+	}
+
+	return wardenError.Code
+}
+
+// Client makes requests to a warden server, keeping a pool of connections
+// to it. Connections that fail are discarded and new ones made as needed.
+type Client struct {
+	connectionProvider ConnectionProvider
+	idle               chan *Connection
+}
+
+func New(connectionProvider ConnectionProvider) *Client {
+	return &Client{
+		connectionProvider: connectionProvider,
+		idle:               make(chan *Connection, MaxIdleConnections),
+	}
+}
+
+// Close closes the client's idle connections. Connections still in use
+// are closed when they are done with.
+func (c *Client) Close() {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (c *Client) Ping() error {
+	return c.roundTrip(&protocol.PingRequest{}, &protocol.PingResponse{})
+}
+
+func (c *Client) Echo(message string) (string, error) {
+	response := &protocol.EchoResponse{}
+
+	err := c.roundTrip(&protocol.EchoRequest{Message: proto.String(message)}, response)
+	if err != nil {
+		return "", err
+	}
+
+	return response.GetMessage(), nil
+}
+
+func (c *Client) Create(request *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	response := &protocol.CreateResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) Destroy(handle string) error {
+	return c.roundTrip(&protocol.DestroyRequest{Handle: proto.String(handle)}, &protocol.DestroyResponse{})
+}
+
+func (c *Client) Stop(request *protocol.StopRequest) error {
+	return c.roundTrip(request, &protocol.StopResponse{})
+}
+
+func (c *Client) List(request *protocol.ListRequest) ([]string, error) {
+	response := &protocol.ListResponse{}
+
+	err := c.roundTrip(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.GetHandles(), nil
+}
+
+func (c *Client) Info(handle string) (*protocol.InfoResponse, error) {
+	response := &protocol.InfoResponse{}
+	return response, c.roundTrip(&protocol.InfoRequest{Handle: proto.String(handle)}, response)
+}
+
+func (c *Client) BulkInfo(handles ...string) (*protocol.BulkInfoResponse, error) {
+	response := &protocol.BulkInfoResponse{}
+	return response, c.roundTrip(&protocol.BulkInfoRequest{Handles: handles}, response)
+}
+
+func (c *Client) CopyIn(request *protocol.CopyInRequest) error {
+	return c.roundTrip(request, &protocol.CopyInResponse{})
+}
+
+func (c *Client) CopyOut(request *protocol.CopyOutRequest) error {
+	return c.roundTrip(request, &protocol.CopyOutResponse{})
+}
+
+func (c *Client) LimitMemory(request *protocol.LimitMemoryRequest) (*protocol.LimitMemoryResponse, error) {
+	response := &protocol.LimitMemoryResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) LimitDisk(request *protocol.LimitDiskRequest) (*protocol.LimitDiskResponse, error) {
+	response := &protocol.LimitDiskResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) LimitBandwidth(request *protocol.LimitBandwidthRequest) (*protocol.LimitBandwidthResponse, error) {
+	response := &protocol.LimitBandwidthResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) LimitCpu(request *protocol.LimitCpuRequest) (*protocol.LimitCpuResponse, error) {
+	response := &protocol.LimitCpuResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) NetIn(request *protocol.NetInRequest) (*protocol.NetInResponse, error) {
+	response := &protocol.NetInResponse{}
+	return response, c.roundTrip(request, response)
+}
+
+func (c *Client) NetOut(request *protocol.NetOutRequest) error {
+	return c.roundTrip(request, &protocol.NetOutResponse{})
+}
+
+func (c *Client) Signal(request *protocol.SignalRequest) error {
+	return c.roundTrip(request, &protocol.SignalResponse{})
+}
+
+// Wait waits for a process to exit, or for the request's timeout, and
+// returns its exit status.
+func (c *Client) Wait(request *protocol.WaitRequest) (uint32, error) {
+	response := &protocol.WaitResponse{}
+
+	err := c.roundTrip(request, response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.GetExitStatus(), nil
+}
+
+func (c *Client) ListProcesses(handle string) ([]*protocol.ListProcessesResponse_Process, error) {
+	response := &protocol.ListProcessesResponse{}
+
+	err := c.roundTrip(&protocol.ListProcessesRequest{Handle: proto.String(handle)}, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.GetProcesses(), nil
+}
+
+func (c *Client) HealthCheck(request *protocol.HealthCheckRequest) error {
+	return c.roundTrip(request, &protocol.HealthCheckResponse{})
+}
+
+// Spawn starts a process without streaming it, i.e. one that is detached
+// or supervised, and returns its ID.
+func (c *Client) Spawn(request *protocol.RunRequest) (uint32, error) {
+	response := &protocol.ProcessPayload{}
+
+	err := c.roundTrip(request, response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.GetProcessId(), nil
+}
+
+// Run starts a process and streams it. The connection it streams over is
+// not used for anything else until the process exits. If the connection is
+// lost, the process is attached to again from where its output left off.
+func (c *Client) Run(request *protocol.RunRequest) (*Process, error) {
+	if request.GetDetach() || request.Restart != nil {
+		return nil, UnstreamedRunError{}
+	}
+
+	binaryRequest := *request
+	binaryRequest.BinaryPayloads = proto.Bool(true)
+
+	conn, err := c.send(&binaryRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	started := &protocol.ProcessPayload{}
+
+	err = conn.Receive(started)
+	if err != nil {
+		c.release(conn)
+		return nil, err
+	}
+
+	processID := started.GetProcessId()
+
+	return newProcess(
+		processID,
+		conn,
+		c.release,
+		c.reattacher(request.GetHandle(), processID),
+		proto.Uint64(0),
+		proto.Uint64(0),
+	), nil
+}
+
+// Attach streams a running process, from the request's offsets. Errors,
+// e.g. for an unknown process, are returned by the process's Wait. If the
+// connection is lost, the process is attached to again from where its
+// output left off.
+func (c *Client) Attach(request *protocol.AttachRequest) (*Process, error) {
+	binaryRequest := *request
+	binaryRequest.BinaryPayloads = proto.Bool(true)
+
+	conn, err := c.send(&binaryRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProcess(
+		request.GetProcessId(),
+		conn,
+		c.release,
+		c.reattacher(request.GetHandle(), request.GetProcessId()),
+		request.StdoutOffset,
+		request.StderrOffset,
+	), nil
+}
+
+// reattacher attaches to the process again over a new connection, as any
+// idle ones may have been lost along with the process's.
+func (c *Client) reattacher(handle string, processID uint32) reattachFunc {
+	return func(stdoutOffset, stderrOffset *uint64) (*Connection, error) {
+		conn, err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+
+		err = conn.Send(&protocol.AttachRequest{
+			Handle:         proto.String(handle),
+			ProcessId:      proto.Uint32(processID),
+			StdoutOffset:   stdoutOffset,
+			StderrOffset:   stderrOffset,
+			BinaryPayloads: proto.Bool(true),
+		})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
+
+// Events streams events until the stream is closed. Its connection is
+// closed along with it.
+func (c *Client) Events(request *protocol.EventsRequest) (*EventStream, error) {
+	conn, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEventStream(conn), nil
+}
+
+func (c *Client) roundTrip(request, response proto.Message) error {
+	conn, err := c.send(request)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Receive(response)
+
+	// the connection may have been closed by the server while idle;
+	// requests that change nothing are safe to make again
+	if conn.Broken() && readOnly(request) {
+		c.release(conn)
+
+		conn, err = c.connect()
+		if err != nil {
+			return err
+		}
+
+		err = conn.RoundTrip(request, response)
+	}
+
+	c.release(conn)
+
+	return err
+}
+
+// send sends the request over an idle connection, or a new one if there
+// are none or it fails to send.
+func (c *Client) send(request proto.Message) (*Connection, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Send(request)
+	if err == nil {
+		return conn, nil
+	}
+
+	c.release(conn)
+
+	conn, err = c.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.Send(request)
+	if err != nil {
+		c.release(conn)
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (c *Client) acquire() (*Connection, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+		return c.connect()
+	}
+}
+
+func (c *Client) connect() (*Connection, error) {
+	return c.connectionProvider.ProvideConnection()
+}
+
+// release puts the connection back in the pool, unless it is broken or the
+// pool is full.
+func (c *Client) release(conn *Connection) {
+	if conn.Broken() {
+		conn.Close()
+		return
+	}
+
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func readOnly(request proto.Message) bool {
+	switch request.(type) {
+	case *protocol.PingRequest, *protocol.EchoRequest,
+		*protocol.ListRequest, *protocol.InfoRequest, *protocol.BulkInfoRequest,
+		*protocol.ListProcessesRequest, *protocol.WaitRequest:
+		return true
+	default:
+		return false
+	}
+}
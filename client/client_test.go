@@ -0,0 +1,505 @@
+package client_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
+	"github.com/pivotal-cf-experimental/garden/client"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+	"github.com/pivotal-cf-experimental/garden/server"
+)
+
+type recordingProvider struct {
+	socketPath string
+
+	conns []net.Conn
+	lock  sync.Mutex
+}
+
+func (p *recordingProvider) ProvideConnection() (*client.Connection, error) {
+	conn, err := net.Dial("unix", p.socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	p.conns = append(p.conns, conn)
+	p.lock.Unlock()
+
+	return client.NewConnection(conn), nil
+}
+
+func (p *recordingProvider) Conns() []net.Conn {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.conns
+}
+
+var _ = Describe("Client", func() {
+	var socketPath string
+
+	var serverBackend *fake_backend.FakeBackend
+	var eventHub *event_hub.Hub
+
+	var wardenServer *server.WardenServer
+
+	var provider *recordingProvider
+	var wardenClient *client.Client
+
+	BeforeEach(func() {
+		tmpdir, err := ioutil.TempDir(os.TempDir(), "warden-client-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		socketPath = path.Join(tmpdir, "warden.sock")
+		serverBackend = fake_backend.New()
+		eventHub = event_hub.New()
+
+		wardenServer = server.New("unix", socketPath, 0, serverBackend, eventHub, nil, nil)
+
+		err = wardenServer.Start()
+		Expect(err).ToNot(HaveOccurred())
+
+		provider = &recordingProvider{socketPath: socketPath}
+		wardenClient = client.New(provider)
+	})
+
+	AfterEach(func() {
+		wardenClient.Close()
+		wardenServer.Stop()
+	})
+
+	createContainer := func() *fake_backend.FakeContainer {
+		response, err := wardenClient.Create(&protocol.CreateRequest{
+			Handle: proto.String("some-handle"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.GetHandle()).To(Equal("some-handle"))
+
+		container, err := serverBackend.Lookup("some-handle")
+		Expect(err).ToNot(HaveOccurred())
+
+		return container.(*fake_backend.FakeContainer)
+	}
+
+	It("creates, lists, gets info for, and destroys containers", func() {
+		fakeContainer := createContainer()
+		fakeContainer.ReportedInfo = backend.ContainerInfo{State: "active"}
+
+		handles, err := wardenClient.List(&protocol.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handles).To(Equal([]string{"some-handle"}))
+
+		info, err := wardenClient.Info("some-handle")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.GetState()).To(Equal("active"))
+
+		err = wardenClient.Destroy("some-handle")
+		Expect(err).ToNot(HaveOccurred())
+
+		handles, err = wardenClient.List(&protocol.ListRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handles).To(BeEmpty())
+	})
+
+	It("reuses connections between requests", func() {
+		for i := 0; i < 3; i++ {
+			err := wardenClient.Ping()
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(provider.Conns()).To(HaveLen(1))
+	})
+
+	Describe("error codes", func() {
+		It("exposes the kind of error the server responded with", func() {
+			_, err := wardenClient.Info("bogus-handle")
+			Expect(err).To(HaveOccurred())
+			Expect(client.ErrorCode(err)).To(Equal(protocol.ErrorResponse_unknown_handle))
+		})
+
+		It("keeps the connection after an error response", func() {
+			_, err := wardenClient.Info("bogus-handle")
+			Expect(err).To(HaveOccurred())
+
+			err = wardenClient.Ping()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.Conns()).To(HaveLen(1))
+		})
+	})
+
+	Context("when an idle connection has been closed", func() {
+		BeforeEach(func() {
+			err := wardenClient.Ping()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.Conns()).To(HaveLen(1))
+			provider.Conns()[0].Close()
+		})
+
+		It("makes the request over a new connection", func() {
+			err := wardenClient.Ping()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.Conns()).To(HaveLen(2))
+		})
+	})
+
+	Describe("running a process", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			fakeContainer = createContainer()
+			fakeContainer.RunningProcessID = 123
+		})
+
+		It("streams its output and exit status", func(done Done) {
+			exitStatus := uint32(42)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("process out\n"),
+				},
+				{
+					Source: backend.ProcessStreamSourceStderr,
+					Data:   []byte("process err\n"),
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			process, err := wardenClient.Run(&protocol.RunRequest{
+				Handle: proto.String("some-handle"),
+				Script: proto.String("/some/script"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(process.ID).To(Equal(uint32(123)))
+
+			var output []client.ProcessOutput
+			for chunk := range process.Output() {
+				output = append(output, chunk)
+			}
+
+			Expect(output).To(HaveLen(2))
+			Expect(output[0].Source).To(Equal(protocol.ProcessPayload_stdout))
+			Expect(string(output[0].Data)).To(Equal("process out\n"))
+			Expect(output[1].Source).To(Equal(protocol.ProcessPayload_stderr))
+			Expect(string(output[1].Data)).To(Equal("process err\n"))
+
+			status, err := process.Wait()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(uint32(42)))
+
+			close(done)
+		}, 2.0)
+
+		It("writes to its stdin", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamDelay = 500 * time.Millisecond
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			process, err := wardenClient.Run(&protocol.RunRequest{
+				Handle:      proto.String("some-handle"),
+				Script:      proto.String("/some/script"),
+				StreamStdin: proto.Bool(true),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = process.WriteStdin([]byte("hello\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = process.CloseStdin()
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(fakeContainer.StdinWriter.Written).Should(Equal("hello\n"))
+			Eventually(fakeContainer.StdinWriter.IsClosed).Should(BeTrue())
+
+			for _ = range process.Output() {
+			}
+
+			_, err = process.Wait()
+			Expect(err).ToNot(HaveOccurred())
+
+			err = process.WriteStdin([]byte("too late\n"))
+			Expect(err).To(Equal(client.ProcessFinishedError{123}))
+
+			close(done)
+		}, 2.0)
+
+		It("returns the connection to the pool once the process exits", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			process, err := wardenClient.Run(&protocol.RunRequest{
+				Handle: proto.String("some-handle"),
+				Script: proto.String("/some/script"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _ = range process.Output() {
+			}
+
+			_, err = process.Wait()
+			Expect(err).ToNot(HaveOccurred())
+
+			err = wardenClient.Ping()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.Conns()).To(HaveLen(1))
+
+			close(done)
+		}, 2.0)
+
+		It("returns the error the stream ends with from Wait", func(done Done) {
+			process, err := wardenClient.Attach(&protocol.AttachRequest{
+				Handle:    proto.String("bogus-handle"),
+				ProcessId: proto.Uint32(123),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _ = range process.Output() {
+			}
+
+			_, err = process.Wait()
+			Expect(err).To(HaveOccurred())
+			Expect(client.ErrorCode(err)).To(Equal(protocol.ErrorResponse_unknown_handle))
+
+			close(done)
+		}, 2.0)
+
+		It("reports where each chunk starts in its source", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("more out\n"),
+					Offset: 42,
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			process, err := wardenClient.Attach(&protocol.AttachRequest{
+				Handle:    proto.String("some-handle"),
+				ProcessId: proto.Uint32(123),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			chunk := <-process.Output()
+			Expect(chunk.Offset).To(Equal(uint64(42)))
+
+			for _ = range process.Output() {
+			}
+
+			close(done)
+		}, 2.0)
+
+		It("attaches to a running process", func(done Done) {
+			exitStatus := uint32(3)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("more out\n"),
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			process, err := wardenClient.Attach(&protocol.AttachRequest{
+				Handle:    proto.String("some-handle"),
+				ProcessId: proto.Uint32(123),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			chunk := <-process.Output()
+			Expect(string(chunk.Data)).To(Equal("more out\n"))
+
+			for _ = range process.Output() {
+			}
+
+			status, err := process.Wait()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(uint32(3)))
+
+			Expect(fakeContainer.Attached).To(HaveLen(1))
+			Expect(fakeContainer.Attached[0].ProcessID).To(Equal(uint32(123)))
+
+			close(done)
+		}, 2.0)
+
+		Context("when the connection is dropped while streaming", func() {
+			It("attaches again from where each source left off", func(done Done) {
+				exitStatus := uint32(4)
+
+				fakeContainer.RunningProcessID = 42
+				fakeContainer.StreamDelay = 100 * time.Millisecond
+				fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+					{
+						Source: backend.ProcessStreamSourceStdout,
+						Data:   []byte("some out\n"),
+						Offset: 0,
+					},
+					{
+						Source: backend.ProcessStreamSourceStderr,
+						Data:   []byte("some err\n"),
+						Offset: 0,
+					},
+					{
+						ExitStatus: &exitStatus,
+					},
+				}
+
+				process, err := wardenClient.Run(&protocol.RunRequest{
+					Handle: proto.String("some-handle"),
+					Script: proto.String("lol"),
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				chunk := <-process.Output()
+				Expect(string(chunk.Data)).To(Equal("some out\n"))
+
+				Expect(provider.Conns()).To(HaveLen(1))
+				provider.Conns()[0].Close()
+
+				for _ = range process.Output() {
+				}
+
+				status, err := process.Wait()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(uint32(4)))
+
+				Expect(provider.Conns()).To(HaveLen(2))
+
+				Expect(fakeContainer.Attached).To(HaveLen(1))
+				Expect(fakeContainer.Attached[0].ProcessID).To(Equal(uint32(42)))
+
+				offsets := fakeContainer.Attached[0].Offsets
+				Expect(offsets.Stdout).ToNot(BeNil())
+				Expect(*offsets.Stdout).To(Equal(uint64(len("some out\n"))))
+				Expect(offsets.Stderr).ToNot(BeNil())
+				Expect(*offsets.Stderr).To(Equal(uint64(0)))
+
+				close(done)
+			}, 3.0)
+
+			Context("and attaching again fails", func() {
+				It("returns the error from Wait", func(done Done) {
+					fakeContainer.StreamDelay = 100 * time.Millisecond
+					fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+						{
+							Source: backend.ProcessStreamSourceStdout,
+							Data:   []byte("some out\n"),
+						},
+						{
+							Source: backend.ProcessStreamSourceStdout,
+							Data:   []byte("more out\n"),
+						},
+					}
+
+					process, err := wardenClient.Run(&protocol.RunRequest{
+						Handle: proto.String("some-handle"),
+						Script: proto.String("lol"),
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					<-process.Output()
+
+					fakeContainer.AttachError = errors.New("oh no!")
+					provider.Conns()[0].Close()
+
+					for _ = range process.Output() {
+					}
+
+					_, err = process.Wait()
+					Expect(err).To(HaveOccurred())
+
+					close(done)
+				}, 3.0)
+			})
+		})
+
+		Context("when it is detached", func() {
+			It("must be spawned instead", func() {
+				_, err := wardenClient.Run(&protocol.RunRequest{
+					Handle: proto.String("some-handle"),
+					Script: proto.String("/some/script"),
+					Detach: proto.Bool(true),
+				})
+				Expect(err).To(Equal(client.UnstreamedRunError{}))
+
+				processID, err := wardenClient.Spawn(&protocol.RunRequest{
+					Handle: proto.String("some-handle"),
+					Script: proto.String("/some/script"),
+					Detach: proto.Bool(true),
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(processID).To(Equal(uint32(123)))
+			})
+		})
+	})
+
+	Describe("streaming events", func() {
+		It("streams events until closed", func(done Done) {
+			stream, err := wardenClient.Events(&protocol.EventsRequest{})
+			Expect(err).ToNot(HaveOccurred())
+
+			stopPublishing := make(chan struct{})
+			defer close(stopPublishing)
+
+			// the subscription is made some time after the request is sent
+			go func() {
+				for {
+					eventHub.Publish(backend.ContainerEvent{
+						Type:   backend.EventTypeStarted,
+						Time:   time.Unix(123, 0),
+						Handle: "some-handle",
+					})
+
+					select {
+					case <-stopPublishing:
+						return
+					case <-time.After(10 * time.Millisecond):
+					}
+				}
+			}()
+
+			event := <-stream.Events()
+			Expect(event.GetType()).To(Equal("started"))
+			Expect(event.GetHandle()).To(Equal("some-handle"))
+
+			err = stream.Close()
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(stream.Events()).Should(BeClosed())
+			Expect(stream.Err()).ToNot(HaveOccurred())
+
+			close(done)
+		}, 2.0)
+	})
+})
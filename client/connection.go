@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	"github.com/pivotal-cf-experimental/garden/message_reader"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+)
+
+// Connection is a single connection to a warden server, over which one
+// request is made at a time.
+type Connection struct {
+	conn net.Conn
+	read *bufio.Reader
+
+	writeLock sync.Mutex
+
+	broken     bool
+	brokenLock sync.RWMutex
+}
+
+// ConnectionProvider makes new connections to the server, e.g. when a
+// client needs one and has none idle.
+type ConnectionProvider interface {
+	ProvideConnection() (*Connection, error)
+}
+
+// ConnectionInfo provides connections to the server at the address, over
+// TLS if a config is given.
+type ConnectionInfo struct {
+	Network string
+	Addr    string
+
+	TLSConfig *tls.Config
+}
+
+func (i *ConnectionInfo) ProvideConnection() (*Connection, error) {
+	return Connect(i.Network, i.Addr, i.TLSConfig)
+}
+
+func Connect(network, addr string, tlsConfig *tls.Config) (*Connection, error) {
+	var conn net.Conn
+	var err error
+
+	if tlsConfig != nil {
+		conn, err = tls.Dial(network, addr, tlsConfig)
+	} else {
+		conn, err = net.Dial(network, addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnection(conn), nil
+}
+
+func NewConnection(conn net.Conn) *Connection {
+	return &Connection{
+		conn: conn,
+		read: bufio.NewReader(conn),
+	}
+}
+
+// RoundTrip sends the request and reads its response.
+func (c *Connection) RoundTrip(request, response proto.Message) error {
+	err := c.Send(request)
+	if err != nil {
+		return err
+	}
+
+	return c.Receive(response)
+}
+
+// Send writes a message to the server. If it fails, the server did not
+// receive the whole message, and so did not act on it.
+func (c *Connection) Send(message proto.Message) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	_, err := protocol.Messages(message).WriteTo(c.conn)
+	if err != nil {
+		c.breaks()
+		return SendError{err}
+	}
+
+	return nil
+}
+
+// Receive reads the next message from the server into the response. An
+// error response is returned as a *message_reader.WardenError; any other
+// error leaves the connection unusable.
+func (c *Connection) Receive(response proto.Message) error {
+	err := message_reader.ReadMessage(c.read, response)
+	if err != nil {
+		if _, ok := err.(*message_reader.WardenError); !ok {
+			c.breaks()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Broken reports whether the connection has failed, and so should not be
+// used again.
+func (c *Connection) Broken() bool {
+	c.brokenLock.RLock()
+	defer c.brokenLock.RUnlock()
+
+	return c.broken
+}
+
+func (c *Connection) Close() error {
+	c.breaks()
+	return c.conn.Close()
+}
+
+func (c *Connection) breaks() {
+	c.brokenLock.Lock()
+	c.broken = true
+	c.brokenLock.Unlock()
+}
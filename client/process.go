@@ -0,0 +1,311 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+)
+
+type ProcessFinishedError struct {
+	ProcessID uint32
+}
+
+func (e ProcessFinishedError) Error() string {
+	return fmt.Sprintf("process is no longer streaming: %d", e.ProcessID)
+}
+
+// ProcessOutput is a chunk of a process's stdout or stderr.
+type ProcessOutput struct {
+	Source protocol.ProcessPayload_Source
+	Data   []byte
+
+	// Offset is how far into the source the chunk starts. Attaching again
+	// from Offset + len(Data) carries on from where it left off.
+	Offset uint64
+}
+
+// reattachFunc attaches to a process again from the given offsets, which
+// are nil to stream from the process's live output.
+type reattachFunc func(stdoutOffset, stderrOffset *uint64) (*Connection, error)
+
+// Process is a process being streamed from the server.
+type Process struct {
+	ID uint32
+
+	output chan ProcessOutput
+
+	// conn is only changed by the stream, with inputMutex held
+	conn     *Connection
+	release  func(*Connection)
+	reattach reattachFunc
+
+	// where each source's output is to carry on from if the process is
+	// attached to again; only used by the stream
+	stdoutOffset *uint64
+	stderrOffset *uint64
+
+	finished   bool
+	inputMutex *sync.Mutex
+
+	exited     chan struct{}
+	exitStatus uint32
+	timedOut   bool
+	err        error
+}
+
+func newProcess(
+	id uint32,
+	conn *Connection,
+	release func(*Connection),
+	reattach reattachFunc,
+	stdoutOffset, stderrOffset *uint64,
+) *Process {
+	process := &Process{
+		ID: id,
+
+		output: make(chan ProcessOutput),
+
+		conn:     conn,
+		release:  release,
+		reattach: reattach,
+
+		stdoutOffset: stdoutOffset,
+		stderrOffset: stderrOffset,
+
+		inputMutex: &sync.Mutex{},
+
+		exited: make(chan struct{}),
+	}
+
+	go process.stream()
+
+	return process
+}
+
+// Output is the process's output, closed once it exits or its stream
+// fails. It must be read until it is closed for the process to be seen to
+// exit.
+func (p *Process) Output() <-chan ProcessOutput {
+	return p.output
+}
+
+// Wait waits for the process to exit, returning its exit status, or the
+// error that ended its stream.
+func (p *Process) Wait() (uint32, error) {
+	<-p.exited
+	return p.exitStatus, p.err
+}
+
+// TimedOut reports whether the process exited because it ran for longer
+// than its timeout. It is only meaningful once Wait has returned.
+func (p *Process) TimedOut() bool {
+	<-p.exited
+	return p.timedOut
+}
+
+// WriteStdin writes to the process's stdin, if it was run with stdin
+// streamed.
+func (p *Process) WriteStdin(data []byte) error {
+	return p.sendInput(&protocol.ProcessPayload{
+		ProcessId: proto.Uint32(p.ID),
+		Source:    protocol.ProcessPayload_stdin.Enum(),
+		DataBytes: data,
+	})
+}
+
+func (p *Process) CloseStdin() error {
+	return p.sendInput(&protocol.ProcessPayload{
+		ProcessId: proto.Uint32(p.ID),
+		Source:    protocol.ProcessPayload_stdin.Enum(),
+		Eof:       proto.Bool(true),
+	})
+}
+
+// SetWindowSize resizes the process's tty, if it was run with one.
+func (p *Process) SetWindowSize(columns, rows uint32) error {
+	return p.sendInput(&protocol.ProcessPayload{
+		ProcessId: proto.Uint32(p.ID),
+		Tty: &protocol.TTY{
+			WindowSize: &protocol.TTY_WindowSize{
+				Columns: proto.Uint32(columns),
+				Rows:    proto.Uint32(rows),
+			},
+		},
+	})
+}
+
+func (p *Process) sendInput(payload *protocol.ProcessPayload) error {
+	p.inputMutex.Lock()
+	defer p.inputMutex.Unlock()
+
+	// the connection is back in the pool once the process has exited
+	if p.finished {
+		return ProcessFinishedError{p.ID}
+	}
+
+	return p.conn.Send(payload)
+}
+
+func (p *Process) stream() {
+	// only attach again if the last connection got somewhere, so that a
+	// server that keeps dropping it is not retried forever
+	progressed := true
+
+	for {
+		payload := &protocol.ProcessPayload{}
+
+		err := p.conn.Receive(payload)
+		if err != nil {
+			if p.conn.Broken() && progressed && p.resume() {
+				progressed = false
+				continue
+			}
+
+			p.err = err
+			break
+		}
+
+		progressed = true
+
+		if payload.ExitStatus != nil {
+			p.exitStatus = payload.GetExitStatus()
+			p.timedOut = payload.GetTimedOut()
+			break
+		}
+
+		data := payload.GetDataBytes()
+		if data == nil && payload.Data != nil {
+			data = []byte(payload.GetData())
+		}
+
+		if data == nil {
+			continue
+		}
+
+		if payload.Offset != nil {
+			next := payload.GetOffset() + uint64(len(data))
+
+			if payload.GetSource() == protocol.ProcessPayload_stderr {
+				p.stderrOffset = &next
+			} else {
+				p.stdoutOffset = &next
+			}
+		}
+
+		p.output <- ProcessOutput{
+			Source: payload.GetSource(),
+			Data:   data,
+			Offset: payload.GetOffset(),
+		}
+	}
+
+	close(p.output)
+
+	p.inputMutex.Lock()
+	p.finished = true
+	p.inputMutex.Unlock()
+
+	p.release(p.conn)
+
+	close(p.exited)
+}
+
+// resume attaches to the process again from where its output left off,
+// after its connection was lost, e.g. to a restarting server.
+func (p *Process) resume() bool {
+	conn, err := p.reattach(p.stdoutOffset, p.stderrOffset)
+	if err != nil {
+		return false
+	}
+
+	p.inputMutex.Lock()
+	lost := p.conn
+	p.conn = conn
+	p.inputMutex.Unlock()
+
+	p.release(lost)
+
+	return true
+}
+
+// EventStream is a stream of events from the server over a connection of
+// its own.
+type EventStream struct {
+	events chan *protocol.Event
+
+	conn *Connection
+
+	closed      chan struct{}
+	closeOnce   *sync.Once
+	err         error
+	streamEnded chan struct{}
+}
+
+func newEventStream(conn *Connection) *EventStream {
+	stream := &EventStream{
+		events: make(chan *protocol.Event),
+
+		conn: conn,
+
+		closed:      make(chan struct{}),
+		closeOnce:   &sync.Once{},
+		streamEnded: make(chan struct{}),
+	}
+
+	go stream.stream()
+
+	return stream
+}
+
+// Events is closed once the stream is closed or fails.
+func (s *EventStream) Events() <-chan *protocol.Event {
+	return s.events
+}
+
+// Err is the error that ended the stream, or nil if it was closed. It is
+// only meaningful once Events has been closed.
+func (s *EventStream) Err() error {
+	<-s.streamEnded
+	return s.err
+}
+
+func (s *EventStream) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		err = s.conn.Close()
+	})
+
+	return err
+}
+
+func (s *EventStream) stream() {
+	defer close(s.streamEnded)
+	defer close(s.events)
+
+	for {
+		event := &protocol.Event{}
+
+		err := s.conn.Receive(event)
+		if err != nil {
+			select {
+			case <-s.closed:
+			default:
+				s.err = err
+				s.conn.Close()
+			}
+
+			return
+		}
+
+		select {
+		case s.events <- event:
+		case <-s.closed:
+			return
+		}
+	}
+}
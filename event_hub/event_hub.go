@@ -0,0 +1,124 @@
+package event_hub
+
+import (
+	"log"
+	"sync"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+// SubscriptionBufferSize is how many events a subscriber may fall behind
+// before further events are dropped for it.
+const SubscriptionBufferSize = 1024
+
+// Hub fans container events out to everyone subscribed to them.
+type Hub struct {
+	subscriptions      map[*Subscription]bool
+	subscriptionsMutex *sync.RWMutex
+}
+
+type Subscription struct {
+	handles map[string]bool
+	types   map[backend.EventType]bool
+	visible func(backend.ContainerEvent) bool
+
+	hub    *Hub
+	events chan backend.ContainerEvent
+}
+
+func New() *Hub {
+	return &Hub{
+		subscriptions:      make(map[*Subscription]bool),
+		subscriptionsMutex: &sync.RWMutex{},
+	}
+}
+
+// Publish sends the event to every matching subscription without waiting on
+// any of them; a subscriber that has fallen too far behind misses it.
+func (h *Hub) Publish(event backend.ContainerEvent) {
+	h.subscriptionsMutex.RLock()
+	defer h.subscriptionsMutex.RUnlock()
+
+	for subscription := range h.subscriptions {
+		if !subscription.matches(event) {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			log.Println("dropping event for slow subscriber:", event.Type, event.Handle)
+		}
+	}
+}
+
+// Subscribe starts collecting events for the given containers and of the
+// given types; either being empty means all of them.
+func (h *Hub) Subscribe(handles []string, types []backend.EventType) *Subscription {
+	return h.SubscribeMatching(handles, types, nil)
+}
+
+// SubscribeMatching is like Subscribe, but further only collects events for
+// which visible returns true. It is called as each event is published.
+func (h *Hub) SubscribeMatching(
+	handles []string,
+	types []backend.EventType,
+	visible func(backend.ContainerEvent) bool,
+) *Subscription {
+	subscription := &Subscription{
+		handles: make(map[string]bool),
+		types:   make(map[backend.EventType]bool),
+		visible: visible,
+
+		hub:    h,
+		events: make(chan backend.ContainerEvent, SubscriptionBufferSize),
+	}
+
+	for _, handle := range handles {
+		subscription.handles[handle] = true
+	}
+
+	for _, eventType := range types {
+		subscription.types[eventType] = true
+	}
+
+	h.subscriptionsMutex.Lock()
+	h.subscriptions[subscription] = true
+	h.subscriptionsMutex.Unlock()
+
+	return subscription
+}
+
+func (s *Subscription) Events() <-chan backend.ContainerEvent {
+	return s.events
+}
+
+// Close stops the subscription and closes its events channel.
+func (s *Subscription) Close() {
+	s.hub.subscriptionsMutex.Lock()
+	defer s.hub.subscriptionsMutex.Unlock()
+
+	if !s.hub.subscriptions[s] {
+		return
+	}
+
+	delete(s.hub.subscriptions, s)
+
+	close(s.events)
+}
+
+func (s *Subscription) matches(event backend.ContainerEvent) bool {
+	if len(s.handles) > 0 && !s.handles[event.Handle] {
+		return false
+	}
+
+	if len(s.types) > 0 && !s.types[event.Type] {
+		return false
+	}
+
+	if s.visible != nil && !s.visible(event) {
+		return false
+	}
+
+	return true
+}
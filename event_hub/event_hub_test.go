@@ -0,0 +1,132 @@
+package event_hub_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
+)
+
+var _ = Describe("Event hub", func() {
+	var hub *event_hub.Hub
+
+	oom := backend.ContainerEvent{
+		Type:   backend.EventTypeOutOfMemory,
+		Time:   time.Unix(123, 0),
+		Handle: "some-handle",
+	}
+
+	started := backend.ContainerEvent{
+		Type:   backend.EventTypeStarted,
+		Time:   time.Unix(456, 0),
+		Handle: "some-other-handle",
+	}
+
+	BeforeEach(func() {
+		hub = event_hub.New()
+	})
+
+	It("sends published events to subscribers", func() {
+		subscription := hub.Subscribe(nil, nil)
+
+		hub.Publish(oom)
+		hub.Publish(started)
+
+		Expect(<-subscription.Events()).To(Equal(oom))
+		Expect(<-subscription.Events()).To(Equal(started))
+	})
+
+	It("sends each event to every subscriber", func() {
+		subscription1 := hub.Subscribe(nil, nil)
+		subscription2 := hub.Subscribe(nil, nil)
+
+		hub.Publish(oom)
+
+		Expect(<-subscription1.Events()).To(Equal(oom))
+		Expect(<-subscription2.Events()).To(Equal(oom))
+	})
+
+	It("does not send events published before subscribing", func() {
+		hub.Publish(oom)
+
+		subscription := hub.Subscribe(nil, nil)
+
+		Consistently(subscription.Events()).ShouldNot(Receive())
+	})
+
+	Context("when subscribed to some containers", func() {
+		It("only sends events for those containers", func() {
+			subscription := hub.Subscribe([]string{"some-other-handle"}, nil)
+
+			hub.Publish(oom)
+			hub.Publish(started)
+
+			Expect(<-subscription.Events()).To(Equal(started))
+			Consistently(subscription.Events()).ShouldNot(Receive())
+		})
+	})
+
+	Context("when subscribed to some types of event", func() {
+		It("only sends events of those types", func() {
+			subscription := hub.Subscribe(nil, []backend.EventType{backend.EventTypeOutOfMemory})
+
+			hub.Publish(started)
+			hub.Publish(oom)
+
+			Expect(<-subscription.Events()).To(Equal(oom))
+			Consistently(subscription.Events()).ShouldNot(Receive())
+		})
+	})
+
+	Context("when subscribed with a visibility check", func() {
+		It("only sends events it lets through, checking as they are published", func() {
+			visibleHandles := map[string]bool{"some-handle": true}
+
+			subscription := hub.SubscribeMatching(nil, nil, func(event backend.ContainerEvent) bool {
+				return visibleHandles[event.Handle]
+			})
+
+			hub.Publish(started)
+			hub.Publish(oom)
+
+			delete(visibleHandles, "some-handle")
+
+			Expect(<-subscription.Events()).To(Equal(oom))
+			Consistently(subscription.Events()).ShouldNot(Receive())
+		})
+	})
+
+	Context("when a subscriber falls behind", func() {
+		It("drops events for it rather than blocking", func() {
+			subscription := hub.Subscribe(nil, nil)
+
+			for i := 0; i < event_hub.SubscriptionBufferSize+10; i++ {
+				hub.Publish(oom)
+			}
+
+			Expect(subscription.Events()).To(HaveLen(event_hub.SubscriptionBufferSize))
+		})
+	})
+
+	Describe("closing a subscription", func() {
+		It("closes its events channel and stops sending to it", func() {
+			subscription := hub.Subscribe(nil, nil)
+
+			subscription.Close()
+
+			hub.Publish(oom)
+
+			Eventually(subscription.Events()).Should(BeClosed())
+		})
+
+		It("can be done more than once", func() {
+			subscription := hub.Subscribe(nil, nil)
+
+			subscription.Close()
+			subscription.Close()
+		})
+	})
+})
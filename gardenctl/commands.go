@@ -0,0 +1,872 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	"github.com/pivotal-cf-experimental/garden/client"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+)
+
+type UsageError struct {
+	Command string
+}
+
+func (e UsageError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s", e.Command)
+}
+
+var commands = map[string]command{
+	"create": {
+		usage:       "[-handle H] [-graceTime SECONDS] [-network CIDR|IP] [-rootfs NAME] [-bindMount SRC:DST[:rw]]... [-property KEY:VALUE]...",
+		description: "create a container and print its handle",
+		run:         create,
+	},
+	"destroy": {
+		usage:       "HANDLE...",
+		description: "destroy containers",
+		run:         destroy,
+	},
+	"list": {
+		usage:       "[-property KEY:VALUE]...",
+		description: "list containers, optionally only those with the properties",
+		run:         list,
+	},
+	"info": {
+		usage:       "[HANDLE...]",
+		description: "show one container's info in full, or a summary of several (all if none are given)",
+		run:         info,
+	},
+	"run": {
+		usage:       "[-script SCRIPT] [-stdin] [-tty [-columns N -rows N]] [-privileged] [-env KEY=VALUE]... [-dir DIR] [-user USER] [-timeout SECONDS] [-detach] HANDLE [PATH [ARG...]]",
+		description: "run a process, streaming its output and exiting with its exit status",
+		run:         run,
+	},
+	"attach": {
+		usage:       "[-stdoutOffset BYTES] [-stderrOffset BYTES] HANDLE PROCESS_ID",
+		description: "stream a running process's output, exiting with its exit status",
+		run:         attach,
+	},
+	"stop": {
+		usage:       "[-kill] [-background] HANDLE",
+		description: "stop a container's processes",
+		run:         stop,
+	},
+	"copy-in": {
+		usage:       "[-user USER] HANDLE SRC DST",
+		description: "copy a path on the server's host into a container",
+		run:         copyIn,
+	},
+	"copy-out": {
+		usage:       "[-owner OWNER] [-user USER] HANDLE SRC DST",
+		description: "copy a path in a container out to the server's host",
+		run:         copyOut,
+	},
+	"limit-memory": {
+		usage:       "HANDLE [BYTES]",
+		description: "set or show a container's memory limit",
+		run:         limitMemory,
+	},
+	"limit-disk": {
+		usage:       "[-byteSoft N] [-byteHard N] [-inodeSoft N] [-inodeHard N] [-blockSoft N] [-blockHard N] HANDLE",
+		description: "set or show a container's disk limits",
+		run:         limitDisk,
+	},
+	"limit-bandwidth": {
+		usage:       "HANDLE RATE BURST",
+		description: "set a container's bandwidth limits, in bytes per second",
+		run:         limitBandwidth,
+	},
+	"limit-cpu": {
+		usage:       "HANDLE [SHARES]",
+		description: "set or show a container's CPU shares",
+		run:         limitCpu,
+	},
+	"net-in": {
+		usage:       "[-hostPort PORT] [-containerPort PORT] HANDLE",
+		description: "forward a port on the host to a container",
+		run:         netIn,
+	},
+	"net-out": {
+		usage:       "[-network CIDR] [-port PORT] HANDLE",
+		description: "allow a container to connect out to a network",
+		run:         netOut,
+	},
+}
+
+func create(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+
+	handle := flags.String("handle", "", "handle to give the container (generated if empty)")
+	graceTime := flags.Uint("graceTime", 0, "seconds after which to destroy the container when idle")
+	network := flags.String("network", "", "/30 network, or container IP within one, to give the container from the pool (the next free one if empty)")
+	rootfs := flags.String("rootfs", "", "named rootfs to create the container with")
+
+	var bindMounts, properties stringsFlag
+	flags.Var(&bindMounts, "bindMount", "path on the host to mount into the container, read-only unless suffixed with :rw")
+	flags.Var(&properties, "property", "property to give the container")
+
+	_, err := parseArgs("create", flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.CreateRequest{}
+
+	if set["handle"] {
+		request.Handle = proto.String(*handle)
+	}
+
+	if set["graceTime"] {
+		request.GraceTime = proto.Uint32(uint32(*graceTime))
+	}
+
+	if set["network"] {
+		request.Network = proto.String(*network)
+	}
+
+	if set["rootfs"] {
+		request.Rootfs = proto.String(*rootfs)
+	}
+
+	for _, spec := range bindMounts {
+		bindMount, err := parseBindMount(spec)
+		if err != nil {
+			return err
+		}
+
+		request.BindMounts = append(request.BindMounts, bindMount)
+	}
+
+	request.Properties, err = parseProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.Create(request)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, response.GetHandle())
+	})
+}
+
+func destroy(c *client.Client, p *printer, args []string) error {
+	handles, err := parseArgs("destroy", flag.NewFlagSet("destroy", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, handle := range handles {
+		err := c.Destroy(handle)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func list(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+
+	var properties stringsFlag
+	flags.Var(&properties, "property", "only list containers with the property")
+
+	_, err := parseArgs("list", flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	handles, err := c.List(&protocol.ListRequest{Properties: filter})
+	if err != nil {
+		return err
+	}
+
+	if handles == nil {
+		handles = []string{}
+	}
+
+	return p.print(handles, func(w io.Writer) {
+		for _, handle := range handles {
+			row(w, handle)
+		}
+	})
+}
+
+func info(c *client.Client, p *printer, args []string) error {
+	handles, err := parseArgs("info", flag.NewFlagSet("info", flag.ContinueOnError), args, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	if len(handles) == 1 {
+		response, err := c.Info(handles[0])
+		if err != nil {
+			return err
+		}
+
+		return p.print(response, func(w io.Writer) {
+			infoRows(w, response)
+		})
+	}
+
+	response, err := c.BulkInfo(handles...)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "HANDLE", "STATE", "CONTAINER IP", "MEMORY", "DISK", "PROCESSES")
+
+		for _, entry := range response.GetEntries() {
+			if entry.Error != nil {
+				row(w, entry.GetHandle(), "error: "+entry.GetError().GetMessage())
+				continue
+			}
+
+			info := entry.GetInfo()
+
+			row(
+				w,
+				entry.GetHandle(),
+				info.GetState(),
+				info.GetContainerIp(),
+				bytesize(info.GetMemoryStat().GetTotalRss()),
+				bytesize(info.GetDiskStat().GetBytesUsed()),
+				len(info.GetProcessIds()),
+			)
+		}
+	})
+}
+
+func infoRows(w io.Writer, info *protocol.InfoResponse) {
+	memory := info.GetMemoryStat()
+	cpu := info.GetCpuStat()
+	disk := info.GetDiskStat()
+	bandwidth := info.GetBandwidthStat()
+
+	row(w, "state", info.GetState())
+	row(w, "events", strings.Join(info.GetEvents(), ", "))
+	row(w, "host ip", info.GetHostIp())
+	row(w, "container ip", info.GetContainerIp())
+	row(w, "container path", info.GetContainerPath())
+	row(w, "memory", fmt.Sprintf("%s rss, %s cache", bytesize(memory.GetTotalRss()), bytesize(memory.GetTotalCache())))
+	row(w, "cpu", fmt.Sprintf("%dns (%d user, %d system)", cpu.GetUsage(), cpu.GetUser(), cpu.GetSystem()))
+	row(w, "disk", fmt.Sprintf("%s, %d inodes", bytesize(disk.GetBytesUsed()), disk.GetInodesUsed()))
+	row(w, "bandwidth", fmt.Sprintf("in %d/%d, out %d/%d", bandwidth.GetInRate(), bandwidth.GetInBurst(), bandwidth.GetOutRate(), bandwidth.GetOutBurst()))
+
+	processIDs := []string{}
+	for _, id := range info.GetProcessIds() {
+		processIDs = append(processIDs, strconv.FormatUint(id, 10))
+	}
+
+	row(w, "processes", strings.Join(processIDs, ", "))
+
+	for _, process := range info.GetSupervisedProcesses() {
+		row(
+			w,
+			fmt.Sprintf("supervised %d", process.GetProcessId()),
+			fmt.Sprintf("running: %t, restarts: %d, last exit status: %d", process.GetRunning(), process.GetRestarts(), process.GetLastExitStatus()),
+		)
+	}
+
+	if info.HealthCheck != nil {
+		row(w, "health", info.GetHealthCheck().GetStatus())
+	}
+
+	for _, property := range info.GetProperties() {
+		row(w, "property "+property.GetKey(), property.GetValue())
+	}
+}
+
+func run(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+
+	script := flags.String("script", "", "script to run with bash, instead of a path and arguments")
+	stdin := flags.Bool("stdin", false, "stream gardenctl's stdin to the process")
+	tty := flags.Bool("tty", false, "run the process in a pseudo-terminal")
+	columns := flags.Uint("columns", 0, "width of the process's pseudo-terminal")
+	rows := flags.Uint("rows", 0, "height of the process's pseudo-terminal")
+	privileged := flags.Bool("privileged", false, "run the process as root in the container")
+	dir := flags.String("dir", "", "working directory to run the process in")
+	user := flags.String("user", "", "user to run the process as")
+	timeout := flags.Uint("timeout", 0, "seconds after which to kill the process")
+	detach := flags.Bool("detach", false, "print the process's ID rather than streaming it")
+
+	var env stringsFlag
+	flags.Var(&env, "env", "environment variable to run the process with")
+
+	positional, err := parseArgs("run", flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	if (*script == "") == (len(positional) == 1) {
+		return UsageError{"run"}
+	}
+
+	set := setFlags(flags)
+
+	if set["columns"] != set["rows"] || (set["columns"] && !*tty) {
+		return UsageError{"run"}
+	}
+
+	request := &protocol.RunRequest{
+		Handle:         proto.String(positional[0]),
+		Privileged:     proto.Bool(*privileged),
+		StreamStdin:    proto.Bool(*stdin),
+		BinaryPayloads: proto.Bool(true),
+	}
+
+	if *script != "" {
+		request.Script = proto.String(*script)
+	} else {
+		request.Path = proto.String(positional[1])
+		request.Args = positional[2:]
+	}
+
+	for _, variable := range env {
+		segs := strings.SplitN(variable, "=", 2)
+		if len(segs) != 2 {
+			return fmt.Errorf("invalid environment variable: %s", variable)
+		}
+
+		request.Env = append(request.Env, &protocol.EnvironmentVariable{
+			Key:   proto.String(segs[0]),
+			Value: proto.String(segs[1]),
+		})
+	}
+
+	if *tty {
+		request.Tty = &protocol.TTY{}
+
+		if set["columns"] {
+			request.Tty.WindowSize = &protocol.TTY_WindowSize{
+				Columns: proto.Uint32(uint32(*columns)),
+				Rows:    proto.Uint32(uint32(*rows)),
+			}
+		}
+	}
+
+	if set["dir"] {
+		request.Dir = proto.String(*dir)
+	}
+
+	if set["user"] {
+		request.User = proto.String(*user)
+	}
+
+	if set["timeout"] {
+		request.Timeout = proto.Uint32(uint32(*timeout))
+	}
+
+	if *detach {
+		request.Detach = proto.Bool(true)
+
+		processID, err := c.Spawn(request)
+		if err != nil {
+			return err
+		}
+
+		return p.print(processJSON{processID}, func(w io.Writer) {
+			row(w, processID)
+		})
+	}
+
+	process, err := c.Run(request)
+	if err != nil {
+		return err
+	}
+
+	if *stdin {
+		go forwardStdin(process)
+	}
+
+	return streamProcess(process, p)
+}
+
+func attach(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("attach", flag.ContinueOnError)
+
+	stdoutOffset := flags.Uint64("stdoutOffset", 0, "how far into the process's stdout to stream from (live output if not given)")
+	stderrOffset := flags.Uint64("stderrOffset", 0, "how far into the process's stderr to stream from (live output if not given)")
+
+	positional, err := parseArgs("attach", flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	processID, err := strconv.ParseUint(positional[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid process ID: %s", positional[1])
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.AttachRequest{
+		Handle:         proto.String(positional[0]),
+		ProcessId:      proto.Uint32(uint32(processID)),
+		BinaryPayloads: proto.Bool(true),
+	}
+
+	if set["stdoutOffset"] {
+		request.StdoutOffset = proto.Uint64(*stdoutOffset)
+	}
+
+	if set["stderrOffset"] {
+		request.StderrOffset = proto.Uint64(*stderrOffset)
+	}
+
+	process, err := c.Attach(request)
+	if err != nil {
+		return err
+	}
+
+	return streamProcess(process, p)
+}
+
+type processJSON struct {
+	ProcessID uint32 `json:"process_id"`
+}
+
+// outputJSON's Data is encoded as base64, as process output need not be
+// valid UTF-8.
+type outputJSON struct {
+	Source string `json:"source"`
+	Data   []byte `json:"data"`
+	Offset uint64 `json:"offset"`
+}
+
+type exitJSON struct {
+	ExitStatus uint32 `json:"exit_status"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+}
+
+// streamProcess writes the process's output to gardenctl's own stdout and
+// stderr, or as a JSON object per chunk, until it exits.
+func streamProcess(process *client.Process, p *printer) error {
+	for chunk := range process.Output() {
+		if p.json {
+			err := p.print(outputJSON{chunk.Source.String(), chunk.Data, chunk.Offset}, nil)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if chunk.Source == protocol.ProcessPayload_stderr {
+			os.Stderr.Write(chunk.Data)
+		} else {
+			os.Stdout.Write(chunk.Data)
+		}
+	}
+
+	exitStatus, err := process.Wait()
+	if err != nil {
+		return err
+	}
+
+	if p.json {
+		err := p.print(exitJSON{exitStatus, process.TimedOut()}, nil)
+		if err != nil {
+			return err
+		}
+	} else if process.TimedOut() {
+		fmt.Fprintln(os.Stderr, "process timed out")
+	}
+
+	if exitStatus != 0 {
+		return ExitStatusError{exitStatus}
+	}
+
+	return nil
+}
+
+// forwardStdin streams gardenctl's stdin to the process until it is closed
+// or the process exits.
+func forwardStdin(process *client.Process) {
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			if process.WriteStdin(buf[:n]) != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			process.CloseStdin()
+			return
+		}
+	}
+}
+
+func stop(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("stop", flag.ContinueOnError)
+
+	kill := flags.Bool("kill", false, "kill the processes rather than terminating them")
+	background := flags.Bool("background", false, "return without waiting for the processes to exit")
+
+	positional, err := parseArgs("stop", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	return c.Stop(&protocol.StopRequest{
+		Handle:     proto.String(positional[0]),
+		Kill:       proto.Bool(*kill),
+		Background: proto.Bool(*background),
+	})
+}
+
+func copyIn(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("copy-in", flag.ContinueOnError)
+
+	user := flags.String("user", "", "user to copy the files in as")
+
+	positional, err := parseArgs("copy-in", flags, args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	request := &protocol.CopyInRequest{
+		Handle:  proto.String(positional[0]),
+		SrcPath: proto.String(positional[1]),
+		DstPath: proto.String(positional[2]),
+	}
+
+	if setFlags(flags)["user"] {
+		request.User = proto.String(*user)
+	}
+
+	return c.CopyIn(request)
+}
+
+func copyOut(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("copy-out", flag.ContinueOnError)
+
+	owner := flags.String("owner", "", "user on the host to give the copied files to")
+	user := flags.String("user", "", "user to copy the files out as")
+
+	positional, err := parseArgs("copy-out", flags, args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.CopyOutRequest{
+		Handle:  proto.String(positional[0]),
+		SrcPath: proto.String(positional[1]),
+		DstPath: proto.String(positional[2]),
+	}
+
+	if set["owner"] {
+		request.Owner = proto.String(*owner)
+	}
+
+	if set["user"] {
+		request.User = proto.String(*user)
+	}
+
+	return c.CopyOut(request)
+}
+
+func limitMemory(c *client.Client, p *printer, args []string) error {
+	positional, err := parseArgs("limit-memory", flag.NewFlagSet("limit-memory", flag.ContinueOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	request := &protocol.LimitMemoryRequest{Handle: proto.String(positional[0])}
+
+	if len(positional) == 2 {
+		limit, err := strconv.ParseUint(positional[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid memory limit: %s", positional[1])
+		}
+
+		request.LimitInBytes = proto.Uint64(limit)
+	}
+
+	response, err := c.LimitMemory(request)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "memory", response.GetLimitInBytes())
+	})
+}
+
+func limitDisk(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("limit-disk", flag.ContinueOnError)
+
+	byteSoft := flags.Uint64("byteSoft", 0, "soft limit on bytes used")
+	byteHard := flags.Uint64("byteHard", 0, "hard limit on bytes used")
+	inodeSoft := flags.Uint64("inodeSoft", 0, "soft limit on inodes used")
+	inodeHard := flags.Uint64("inodeHard", 0, "hard limit on inodes used")
+	blockSoft := flags.Uint64("blockSoft", 0, "soft limit on blocks used")
+	blockHard := flags.Uint64("blockHard", 0, "hard limit on blocks used")
+
+	positional, err := parseArgs("limit-disk", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.LimitDiskRequest{Handle: proto.String(positional[0])}
+
+	if set["byteSoft"] {
+		request.ByteSoft = proto.Uint64(*byteSoft)
+	}
+
+	if set["byteHard"] {
+		request.ByteHard = proto.Uint64(*byteHard)
+	}
+
+	if set["inodeSoft"] {
+		request.InodeSoft = proto.Uint64(*inodeSoft)
+	}
+
+	if set["inodeHard"] {
+		request.InodeHard = proto.Uint64(*inodeHard)
+	}
+
+	if set["blockSoft"] {
+		request.BlockSoft = proto.Uint64(*blockSoft)
+	}
+
+	if set["blockHard"] {
+		request.BlockHard = proto.Uint64(*blockHard)
+	}
+
+	response, err := c.LimitDisk(request)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "", "SOFT", "HARD")
+		row(w, "bytes", response.GetByteSoft(), response.GetByteHard())
+		row(w, "inodes", response.GetInodeSoft(), response.GetInodeHard())
+		row(w, "blocks", response.GetBlockSoft(), response.GetBlockHard())
+	})
+}
+
+func limitBandwidth(c *client.Client, p *printer, args []string) error {
+	positional, err := parseArgs("limit-bandwidth", flag.NewFlagSet("limit-bandwidth", flag.ContinueOnError), args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	rate, err := strconv.ParseUint(positional[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid rate: %s", positional[1])
+	}
+
+	burst, err := strconv.ParseUint(positional[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid burst: %s", positional[2])
+	}
+
+	response, err := c.LimitBandwidth(&protocol.LimitBandwidthRequest{
+		Handle: proto.String(positional[0]),
+		Rate:   proto.Uint64(rate),
+		Burst:  proto.Uint64(burst),
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "rate", response.GetRate())
+		row(w, "burst", response.GetBurst())
+	})
+}
+
+func limitCpu(c *client.Client, p *printer, args []string) error {
+	positional, err := parseArgs("limit-cpu", flag.NewFlagSet("limit-cpu", flag.ContinueOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	request := &protocol.LimitCpuRequest{Handle: proto.String(positional[0])}
+
+	if len(positional) == 2 {
+		shares, err := strconv.ParseUint(positional[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid CPU shares: %s", positional[1])
+		}
+
+		request.LimitInShares = proto.Uint64(shares)
+	}
+
+	response, err := c.LimitCpu(request)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "cpu shares", response.GetLimitInShares())
+	})
+}
+
+func netIn(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("net-in", flag.ContinueOnError)
+
+	hostPort := flags.Uint("hostPort", 0, "port on the host to forward (allocated if not given)")
+	containerPort := flags.Uint("containerPort", 0, "port in the container to forward to (the host port if not given)")
+
+	positional, err := parseArgs("net-in", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.NetInRequest{Handle: proto.String(positional[0])}
+
+	if set["hostPort"] {
+		request.HostPort = proto.Uint32(uint32(*hostPort))
+	}
+
+	if set["containerPort"] {
+		request.ContainerPort = proto.Uint32(uint32(*containerPort))
+	}
+
+	response, err := c.NetIn(request)
+	if err != nil {
+		return err
+	}
+
+	return p.print(response, func(w io.Writer) {
+		row(w, "host port", response.GetHostPort())
+		row(w, "container port", response.GetContainerPort())
+	})
+}
+
+func netOut(c *client.Client, p *printer, args []string) error {
+	flags := flag.NewFlagSet("net-out", flag.ContinueOnError)
+
+	network := flags.String("network", "", "network to allow connections to")
+	port := flags.Uint("port", 0, "port to allow connections to")
+
+	positional, err := parseArgs("net-out", flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	set := setFlags(flags)
+
+	request := &protocol.NetOutRequest{Handle: proto.String(positional[0])}
+
+	if set["network"] {
+		request.Network = proto.String(*network)
+	}
+
+	if set["port"] {
+		request.Port = proto.Uint32(uint32(*port))
+	}
+
+	return c.NetOut(request)
+}
+
+// parseArgs parses the command's flags and returns its remaining
+// arguments, of which there must be at least min and at most max (or any
+// number if max is negative).
+func parseArgs(name string, flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, UsageError{name}
+	}
+
+	positional := flags.Args()
+
+	if len(positional) < min || (max >= 0 && len(positional) > max) {
+		return nil, UsageError{name}
+	}
+
+	return positional, nil
+}
+
+func setFlags(flags *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+// stringsFlag is a flag that may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func parseProperties(specs []string) ([]*protocol.Property, error) {
+	properties := []*protocol.Property{}
+
+	for _, spec := range specs {
+		segs := strings.SplitN(spec, ":", 2)
+		if len(segs) != 2 {
+			return nil, fmt.Errorf("invalid property: %s", spec)
+		}
+
+		properties = append(properties, &protocol.Property{
+			Key:   proto.String(segs[0]),
+			Value: proto.String(segs[1]),
+		})
+	}
+
+	return properties, nil
+}
+
+func parseBindMount(spec string) (*protocol.CreateRequest_BindMount, error) {
+	segs := strings.Split(spec, ":")
+
+	mode := protocol.CreateRequest_BindMount_RO
+
+	switch {
+	case len(segs) == 3 && segs[2] == "rw":
+		mode = protocol.CreateRequest_BindMount_RW
+	case len(segs) == 3 && segs[2] == "ro":
+	case len(segs) == 2:
+	default:
+		return nil, fmt.Errorf("invalid bind mount: %s", spec)
+	}
+
+	return &protocol.CreateRequest_BindMount{
+		SrcPath: proto.String(segs[0]),
+		DstPath: proto.String(segs[1]),
+		Mode:    mode.Enum(),
+		Origin:  protocol.CreateRequest_BindMount_Host.Enum(),
+	}, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
+	"github.com/pivotal-cf-experimental/garden/client"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+	"github.com/pivotal-cf-experimental/garden/server"
+)
+
+var _ = Describe("Commands", func() {
+	var tmpdir string
+
+	var serverBackend *fake_backend.FakeBackend
+	var wardenServer *server.WardenServer
+
+	var wardenClient *client.Client
+
+	var output *bytes.Buffer
+	var jsonPrinter *printer
+
+	var fakeContainer *fake_backend.FakeContainer
+
+	BeforeEach(func() {
+		var err error
+
+		tmpdir, err = ioutil.TempDir(os.TempDir(), "gardenctl-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		socketPath := path.Join(tmpdir, "warden.sock")
+		serverBackend = fake_backend.New()
+
+		wardenServer = server.New("unix", socketPath, 0, serverBackend, event_hub.New(), nil, nil)
+
+		err = wardenServer.Start()
+		Expect(err).ToNot(HaveOccurred())
+
+		wardenClient = client.New(&client.ConnectionInfo{
+			Network: "unix",
+			Addr:    socketPath,
+		})
+
+		output = new(bytes.Buffer)
+		jsonPrinter = &printer{json: true, out: output}
+
+		err = create(wardenClient, &printer{out: new(bytes.Buffer)}, []string{"-handle", "some-handle"})
+		Expect(err).ToNot(HaveOccurred())
+
+		container, err := serverBackend.Lookup("some-handle")
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeContainer = container.(*fake_backend.FakeContainer)
+	})
+
+	AfterEach(func() {
+		wardenClient.Close()
+		wardenServer.Stop()
+		os.RemoveAll(tmpdir)
+	})
+
+	decodeOutput := func() []map[string]interface{} {
+		objects := []map[string]interface{}{}
+
+		decoder := json.NewDecoder(output)
+
+		for decoder.More() {
+			var object map[string]interface{}
+
+			err := decoder.Decode(&object)
+			Expect(err).ToNot(HaveOccurred())
+
+			objects = append(objects, object)
+		}
+
+		return objects
+	}
+
+	Describe("create", func() {
+		It("prints the container's handle", func() {
+			err := create(wardenClient, jsonPrinter, []string{"-handle", "other-handle", "-property", "a:b"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(decodeOutput()[0]["handle"]).To(Equal("other-handle"))
+
+			container, err := serverBackend.Lookup("other-handle")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(container.Properties()).To(Equal(backend.Properties{"a": "b"}))
+		})
+
+		Context("with an invalid property", func() {
+			It("returns an error without creating a container", func() {
+				err := create(wardenClient, jsonPrinter, []string{"-handle", "other-handle", "-property", "bogus"})
+				Expect(err).To(HaveOccurred())
+
+				_, err = serverBackend.Lookup("other-handle")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with unexpected arguments", func() {
+			It("returns a usage error", func() {
+				err := create(wardenClient, jsonPrinter, []string{"bogus"})
+				Expect(err).To(Equal(UsageError{"create"}))
+			})
+		})
+	})
+
+	Describe("run", func() {
+		It("runs the process as root in the container when -privileged is given", func() {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			err := run(wardenClient, jsonPrinter, []string{"-privileged", "some-handle", "/bin/echo", "hi"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeContainer.RunningProcesses).To(HaveLen(1))
+			Expect(fakeContainer.RunningProcesses[0].Privileged).To(BeTrue())
+			Expect(fakeContainer.RunningProcesses[0].Path).To(Equal("/bin/echo"))
+			Expect(fakeContainer.RunningProcesses[0].Args).To(Equal([]string{"hi"}))
+		})
+
+		It("runs the process in a pseudo-terminal of the given size when -tty is given", func() {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			err := run(wardenClient, jsonPrinter, []string{"-tty", "-columns", "80", "-rows", "24", "some-handle", "/bin/bash"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeContainer.RunningProcesses).To(HaveLen(1))
+			Expect(fakeContainer.RunningProcesses[0].TTY).To(Equal(&backend.TTYSpec{
+				WindowSize: &backend.WindowSize{Columns: 80, Rows: 24},
+			}))
+		})
+
+		It("runs the process without a pseudo-terminal by default", func() {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			err := run(wardenClient, jsonPrinter, []string{"some-handle", "/bin/echo"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeContainer.RunningProcesses).To(HaveLen(1))
+			Expect(fakeContainer.RunningProcesses[0].TTY).To(BeNil())
+		})
+
+		Context("with a window size but no -tty", func() {
+			It("returns a usage error", func() {
+				err := run(wardenClient, jsonPrinter, []string{"-columns", "80", "-rows", "24", "some-handle", "/bin/bash"})
+				Expect(err).To(Equal(UsageError{"run"}))
+			})
+		})
+
+		It("prints each chunk of output with its data base64-encoded", func() {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte{0xff, 0xfe, '\n'},
+					Offset: 3,
+				},
+				{ExitStatus: &exitStatus},
+			}
+
+			err := run(wardenClient, jsonPrinter, []string{"-script", "/some/script", "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			objects := decodeOutput()
+			Expect(objects).To(HaveLen(2))
+
+			Expect(objects[0]["source"]).To(Equal("stdout"))
+			Expect(objects[0]["data"]).To(Equal("//4K"))
+			Expect(objects[0]["offset"]).To(Equal(float64(3)))
+
+			Expect(objects[1]["exit_status"]).To(Equal(float64(0)))
+		})
+
+		It("returns the process's exit status as an error when it is non-zero", func() {
+			exitStatus := uint32(42)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+
+			err := run(wardenClient, jsonPrinter, []string{"-script", "/some/script", "some-handle"})
+			Expect(err).To(Equal(ExitStatusError{42}))
+		})
+
+		Context("with both a script and a path", func() {
+			It("returns a usage error", func() {
+				err := run(wardenClient, jsonPrinter, []string{"-script", "/some/script", "some-handle", "/bin/echo"})
+				Expect(err).To(Equal(UsageError{"run"}))
+			})
+		})
+	})
+
+	Describe("attach", func() {
+		BeforeEach(func() {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{ExitStatus: &exitStatus},
+			}
+		})
+
+		It("streams live output when no offsets are given", func() {
+			err := attach(wardenClient, jsonPrinter, []string{"some-handle", "123"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeContainer.Attached).To(HaveLen(1))
+			Expect(fakeContainer.Attached[0].ProcessID).To(Equal(uint32(123)))
+			Expect(fakeContainer.Attached[0].Offsets.Stdout).To(BeNil())
+			Expect(fakeContainer.Attached[0].Offsets.Stderr).To(BeNil())
+		})
+
+		It("streams from the offsets that are given", func() {
+			err := attach(wardenClient, jsonPrinter, []string{"-stdoutOffset", "0", "some-handle", "123"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeContainer.Attached).To(HaveLen(1))
+			Expect(*fakeContainer.Attached[0].Offsets.Stdout).To(Equal(uint64(0)))
+			Expect(fakeContainer.Attached[0].Offsets.Stderr).To(BeNil())
+		})
+
+		Context("with an invalid process ID", func() {
+			It("returns an error", func() {
+				err := attach(wardenClient, jsonPrinter, []string{"some-handle", "bogus"})
+				Expect(err).To(HaveOccurred())
+				Expect(fakeContainer.Attached).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("destroy", func() {
+		It("destroys each container", func() {
+			err := destroy(wardenClient, jsonPrinter, []string{"some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = serverBackend.Lookup("some-handle")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("info", func() {
+		It("prints the container's info", func() {
+			fakeContainer.ReportedInfo = backend.ContainerInfo{State: "active"}
+
+			err := info(wardenClient, jsonPrinter, []string{"some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(decodeOutput()[0]["state"]).To(Equal("active"))
+		})
+
+		Context("when the container does not exist", func() {
+			It("returns the server's error", func() {
+				err := info(wardenClient, jsonPrinter, []string{"bogus-handle"})
+				Expect(err).To(HaveOccurred())
+				Expect(client.ErrorCode(err)).To(Equal(protocol.ErrorResponse_unknown_handle))
+			})
+		})
+	})
+})
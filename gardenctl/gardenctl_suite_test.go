@@ -0,0 +1,13 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGardenctl(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gardenctl Suite")
+}
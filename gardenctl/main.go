@@ -0,0 +1,176 @@
+// Command gardenctl makes requests to a warden server by hand, e.g. to
+// inspect or fix up its containers during an incident.
+//
+//	gardenctl [flags] <command> [command flags] <args>
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/pivotal-cf-experimental/garden/client"
+	"github.com/pivotal-cf-experimental/garden/message_reader"
+)
+
+var network = flag.String(
+	"network",
+	"unix",
+	"how to connect to the server (unix, tcp, etc.)",
+)
+
+var addr = flag.String(
+	"addr",
+	"/tmp/warden.sock",
+	"address of the server",
+)
+
+var tlsCert = flag.String(
+	"tlsCert",
+	"",
+	"client certificate to connect over TLS with (disabled if empty)",
+)
+
+var tlsKey = flag.String(
+	"tlsKey",
+	"",
+	"private key for the client certificate",
+)
+
+var tlsCA = flag.String(
+	"tlsCA",
+	"",
+	"CA certificates to verify the server against (system CAs if empty)",
+)
+
+var jsonOutput = flag.Bool(
+	"json",
+	false,
+	"print responses as JSON rather than tables",
+)
+
+// ExitStatusError is returned by commands that stream a process, so that
+// gardenctl exits as the process did.
+type ExitStatusError struct {
+	ExitStatus uint32
+}
+
+func (e ExitStatusError) Error() string {
+	return fmt.Sprintf("process exited with status %d", e.ExitStatus)
+}
+
+type command struct {
+	usage       string
+	description string
+
+	run func(*client.Client, *printer, []string) error
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, found := commands[flag.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	tlsConfig, err := clientTLSConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to configure TLS:", err)
+		os.Exit(1)
+	}
+
+	wardenClient := client.New(&client.ConnectionInfo{
+		Network:   *network,
+		Addr:      *addr,
+		TLSConfig: tlsConfig,
+	})
+
+	err = cmd.run(wardenClient, &printer{json: *jsonOutput, out: os.Stdout}, flag.Args()[1:])
+
+	wardenClient.Close()
+
+	switch err := err.(type) {
+	case nil:
+	case ExitStatusError:
+		os.Exit(int(err.ExitStatus))
+	case UsageError:
+		fmt.Fprintf(os.Stderr, "usage: gardenctl %s %s\n", err.Command, commands[err.Command].usage)
+		os.Exit(2)
+	case *message_reader.WardenError:
+		fmt.Fprintf(os.Stderr, "error: %s (%s)\n", err.Message, err.Code)
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gardenctl [flags] <command> [command flags] <args>")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+		fmt.Fprintf(os.Stderr, "    \t%s\n", commands[name].description)
+	}
+}
+
+func clientTLSConfig() (*tls.Config, error) {
+	if *tlsCert == "" && *tlsKey == "" {
+		if *tlsCA != "" {
+			return nil, errors.New("-tlsCA requires -tlsCert and -tlsKey")
+		}
+
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if *tlsCA != "" {
+		caPEM, err := ioutil.ReadFile(*tlsCA)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", *tlsCA)
+		}
+
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// printer prints responses either as JSON, for scripts, or as tables.
+type printer struct {
+	json bool
+	out  io.Writer
+}
+
+// print prints the value as JSON, or calls table to print it as rows of
+// tab-separated columns.
+func (p *printer) print(value interface{}, table func(io.Writer)) error {
+	if p.json {
+		return json.NewEncoder(p.out).Encode(value)
+	}
+
+	writer := tabwriter.NewWriter(p.out, 0, 8, 2, ' ', 0)
+
+	table(writer)
+
+	return writer.Flush()
+}
+
+func row(w io.Writer, columns ...interface{}) {
+	for i, column := range columns {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+
+		fmt.Fprint(w, column)
+	}
+
+	fmt.Fprintln(w)
+}
+
+func bytesize(bytes uint64) string {
+	units := []string{"B", "K", "M", "G", "T"}
+
+	size := float64(bytes)
+	unit := 0
+
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	return fmt.Sprintf("%.1f%s", size, units[unit])
+}
@@ -378,6 +378,24 @@ setup_fs
 		})
 	})
 
+	Context("when signalling a process it did not spawn", func() {
+		It("refuses, as though there were no such process", func() {
+			pidFile := path.Join(containerPath, "run", "some.pid")
+
+			// wshd itself, the init process of the container
+			err := ioutil.WriteFile(pidFile, []byte("1\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			signal := exec.Command(wsh, "--socket", socketPath, "--pid-file", pidFile, "--signal", "15")
+
+			signalSession, err := cmdtest.StartWrapped(signal, outWrapper, outWrapper)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(signalSession).To(SayError("No such process"))
+			Expect(signalSession).To(ExitWith(1))
+		})
+	})
+
 	Context("when in rsh compatibility mode", func() {
 		It("respects -l, discards -t [X], -46dn, skips the host, and runs the command", func() {
 			pwd := exec.Command(
@@ -7,18 +7,23 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os/exec"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/linux_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/bandwidth_manager"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/cgroups_manager"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/network"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/network_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/uid_pool"
 )
 
@@ -27,6 +32,8 @@ type LinuxContainerPool struct {
 	depotPath  string
 	rootFSPath string
 
+	rootFSCatalog rootfs_catalog.RootFSCatalog
+
 	uidPool     uid_pool.UIDPool
 	networkPool network_pool.NetworkPool
 	portPool    linux_backend.PortPool
@@ -35,22 +42,43 @@ type LinuxContainerPool struct {
 
 	quotaManager quota_manager.QuotaManager
 
+	processOutputHistory uint64
+
+	eventHub *event_hub.Hub
+
 	containerIDs chan string
 }
 
+type InvalidNetworkError struct {
+	Network string
+}
+
+func (e InvalidNetworkError) Error() string {
+	return "invalid network (must be a /30 subnet or container IP in the pool): " + e.Network
+}
+
+func (e InvalidNetworkError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
 func New(
 	binPath, depotPath, rootFSPath string,
+	rootFSCatalog rootfs_catalog.RootFSCatalog,
 	uidPool uid_pool.UIDPool,
 	networkPool network_pool.NetworkPool,
 	portPool linux_backend.PortPool,
 	runner command_runner.CommandRunner,
 	quotaManager quota_manager.QuotaManager,
+	processOutputHistory uint64,
+	eventHub *event_hub.Hub,
 ) *LinuxContainerPool {
 	pool := &LinuxContainerPool{
 		binPath:    binPath,
 		depotPath:  depotPath,
 		rootFSPath: rootFSPath,
 
+		rootFSCatalog: rootFSCatalog,
+
 		uidPool:     uidPool,
 		networkPool: networkPool,
 		portPool:    portPool,
@@ -59,6 +87,10 @@ func New(
 
 		quotaManager: quotaManager,
 
+		processOutputHistory: processOutputHistory,
+
+		eventHub: eventHub,
+
 		containerIDs: make(chan string),
 	}
 
@@ -137,12 +169,23 @@ func (p *LinuxContainerPool) Prune(keep map[string]bool) error {
 }
 
 func (p *LinuxContainerPool) Create(spec backend.ContainerSpec) (linux_backend.Container, error) {
+	rootFSPath := p.rootFSPath
+
+	if spec.RootFSPath != "" {
+		resolvedPath, err := p.rootFSCatalog.Resolve(spec.RootFSPath)
+		if err != nil {
+			return nil, err
+		}
+
+		rootFSPath = resolvedPath
+	}
+
 	uid, err := p.uidPool.Acquire()
 	if err != nil {
 		return nil, err
 	}
 
-	network, err := p.networkPool.Acquire()
+	network, err := p.acquireNetwork(spec.Network)
 	if err != nil {
 		p.uidPool.Release(uid)
 		return nil, err
@@ -165,13 +208,17 @@ func (p *LinuxContainerPool) Create(spec backend.ContainerSpec) (linux_backend.C
 		id,
 		handle,
 		containerPath,
+		rootFSPath,
 		spec.GraceTime,
+		spec.Properties,
+		p.processOutputHistory,
 		linux_backend.NewResources(uid, network, []uint32{}),
 		p.portPool,
 		p.runner,
 		cgroupsManager,
 		p.quotaManager,
 		bandwidthManager,
+		p.eventHub,
 	)
 
 	create := &exec.Cmd{
@@ -179,7 +226,7 @@ func (p *LinuxContainerPool) Create(spec backend.ContainerSpec) (linux_backend.C
 		Args: []string{containerPath},
 		Env: []string{
 			"id=" + container.ID(),
-			"rootfs_path=" + p.rootFSPath,
+			"rootfs_path=" + rootFSPath,
 			fmt.Sprintf("user_uid=%d", uid),
 			fmt.Sprintf("network_host_ip=%s", network.HostIP()),
 			fmt.Sprintf("network_container_ip=%s", network.ContainerIP()),
@@ -198,9 +245,18 @@ func (p *LinuxContainerPool) Create(spec backend.ContainerSpec) (linux_backend.C
 
 	err = p.writeBindMounts(containerPath, spec.BindMounts)
 	if err != nil {
+		destroyErr := p.destroy(id)
+		if destroyErr != nil {
+			log.Println("failed to clean up", id+":", destroyErr)
+		}
+
+		p.uidPool.Release(uid)
+		p.networkPool.Release(network)
 		return nil, err
 	}
 
+	p.publishEvent(backend.EventTypeCreated, container.Handle())
+
 	return container, nil
 }
 
@@ -245,6 +301,11 @@ func (p *LinuxContainerPool) Restore(snapshot io.Reader) (linux_backend.Containe
 
 	containerPath := path.Join(p.depotPath, id)
 
+	rootFSPath := containerSnapshot.RootFSPath
+	if rootFSPath == "" {
+		rootFSPath = p.rootFSPath
+	}
+
 	cgroupsManager := cgroups_manager.New("/tmp/warden/cgroup", id)
 
 	bandwidthManager := bandwidth_manager.New(containerPath, id, p.runner)
@@ -253,7 +314,10 @@ func (p *LinuxContainerPool) Restore(snapshot io.Reader) (linux_backend.Containe
 		id,
 		containerSnapshot.Handle,
 		containerPath,
+		rootFSPath,
 		containerSnapshot.GraceTime,
+		containerSnapshot.Properties,
+		p.processOutputHistory,
 		linux_backend.NewResources(
 			resources.UID,
 			resources.Network,
@@ -264,6 +328,7 @@ func (p *LinuxContainerPool) Restore(snapshot io.Reader) (linux_backend.Containe
 		cgroupsManager,
 		p.quotaManager,
 		bandwidthManager,
+		p.eventHub,
 	)
 
 	err = container.Restore(containerSnapshot)
@@ -282,6 +347,8 @@ func (p *LinuxContainerPool) Destroy(container linux_backend.Container) error {
 
 	linuxContainer := container.(*linux_backend.LinuxContainer)
 
+	linuxContainer.ForgetProcesses()
+
 	resources := linuxContainer.Resources()
 
 	for _, port := range resources.Ports {
@@ -292,6 +359,8 @@ func (p *LinuxContainerPool) Destroy(container linux_backend.Container) error {
 
 	p.networkPool.Release(resources.Network)
 
+	p.publishEvent(backend.EventTypeDestroyed, container.Handle())
+
 	return nil
 }
 
@@ -304,6 +373,63 @@ func (p *LinuxContainerPool) destroy(id string) error {
 	return p.runner.Run(destroy)
 }
 
+func (p *LinuxContainerPool) publishEvent(eventType backend.EventType, handle string) {
+	p.eventHub.Publish(backend.ContainerEvent{
+		Type:   eventType,
+		Time:   time.Now(),
+		Handle: handle,
+	})
+}
+
+func (p *LinuxContainerPool) acquireNetwork(requested string) (*network.Network, error) {
+	if requested == "" {
+		return p.networkPool.Acquire()
+	}
+
+	var ipNet *net.IPNet
+
+	if strings.Contains(requested, "/") {
+		ip, subnet, err := net.ParseCIDR(requested)
+		if err != nil {
+			return nil, InvalidNetworkError{requested}
+		}
+
+		ones, bits := subnet.Mask.Size()
+		if ones != 30 || bits != 32 || !ip.Equal(subnet.IP) {
+			return nil, InvalidNetworkError{requested}
+		}
+
+		ipNet = subnet
+	} else {
+		ip := net.ParseIP(requested)
+		if ip == nil || ip.To4() == nil {
+			return nil, InvalidNetworkError{requested}
+		}
+
+		ipNet = &net.IPNet{
+			IP:   ip.Mask(net.CIDRMask(30, 32)),
+			Mask: net.CIDRMask(30, 32),
+		}
+
+		if !network.New(ipNet).ContainerIP().Equal(ip) {
+			return nil, InvalidNetworkError{requested}
+		}
+	}
+
+	if !p.networkPool.Network().Contains(ipNet.IP) {
+		return nil, InvalidNetworkError{requested}
+	}
+
+	requestedNetwork := network.New(ipNet)
+
+	err := p.networkPool.Remove(requestedNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	return requestedNetwork, nil
+}
+
 func (p *LinuxContainerPool) generateContainerIDs() string {
 	for containerNum := time.Now().UnixNano(); ; containerNum++ {
 		containerID := []byte{}
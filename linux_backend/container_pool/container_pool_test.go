@@ -15,12 +15,16 @@ import (
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner/fake_command_runner"
 	. "github.com/pivotal-cf-experimental/garden/command_runner/fake_command_runner/matchers"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/linux_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/container_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/network"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/network_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/network_pool/fake_network_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/port_pool/fake_port_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager/fake_quota_manager"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog/fake_rootfs_catalog"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/uid_pool/fake_uid_pool"
 )
 
@@ -30,6 +34,8 @@ var _ = Describe("Container pool", func() {
 	var fakeNetworkPool *fake_network_pool.FakeNetworkPool
 	var fakeQuotaManager *fake_quota_manager.FakeQuotaManager
 	var fakePortPool *fake_port_pool.FakePortPool
+	var fakeRootFSCatalog *fake_rootfs_catalog.FakeRootFSCatalog
+	var eventHub *event_hub.Hub
 	var pool *container_pool.LinuxContainerPool
 
 	BeforeEach(func() {
@@ -42,16 +48,21 @@ var _ = Describe("Container pool", func() {
 		fakeRunner = fake_command_runner.New()
 		fakeQuotaManager = fake_quota_manager.New()
 		fakePortPool = fake_port_pool.New(1000)
+		fakeRootFSCatalog = fake_rootfs_catalog.New()
+		eventHub = event_hub.New()
 
 		pool = container_pool.New(
 			"/root/path",
 			"/depot/path",
 			"/rootfs/path",
+			fakeRootFSCatalog,
 			fakeUIDPool,
 			fakeNetworkPool,
 			fakePortPool,
 			fakeRunner,
 			fakeQuotaManager,
+			1024,
+			eventHub,
 		)
 	})
 
@@ -111,6 +122,20 @@ var _ = Describe("Container pool", func() {
 			Expect(container1.ID()).ToNot(Equal(container2.ID()))
 		})
 
+		It("publishes a created event", func() {
+			subscription := eventHub.Subscribe(nil, nil)
+
+			container, err := pool.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			var event backend.ContainerEvent
+			Eventually(subscription.Events()).Should(Receive(&event))
+
+			Expect(event.Type).To(Equal(backend.EventTypeCreated))
+			Expect(event.Handle).To(Equal(container.Handle()))
+			Expect(event.Time.IsZero()).To(BeFalse())
+		})
+
 		It("creates containers with the correct grace time", func() {
 			container, err := pool.Create(backend.ContainerSpec{
 				GraceTime: 1 * time.Second,
@@ -120,6 +145,15 @@ var _ = Describe("Container pool", func() {
 			Expect(container.GraceTime()).To(Equal(1 * time.Second))
 		})
 
+		It("creates containers with the given properties", func() {
+			container, err := pool.Create(backend.ContainerSpec{
+				Properties: backend.Properties{"owner": "team-a"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.Properties()).To(Equal(backend.Properties{"owner": "team-a"}))
+		})
+
 		It("executes create.sh with the correct args and environment", func() {
 			container, err := pool.Create(backend.ContainerSpec{})
 			Expect(err).ToNot(HaveOccurred())
@@ -142,6 +176,193 @@ var _ = Describe("Container pool", func() {
 			))
 		})
 
+		It("uses the default rootfs", func() {
+			container, err := pool.Create(backend.ContainerSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRootFSCatalog.Resolved).To(BeEmpty())
+
+			linuxContainer := container.(*linux_backend.LinuxContainer)
+			Expect(linuxContainer.RootFSPath()).To(Equal("/rootfs/path"))
+		})
+
+		Context("when a rootfs is specified", func() {
+			BeforeEach(func() {
+				fakeRootFSCatalog.RootFSes["some-rootfs"] = "/catalog/some-rootfs"
+			})
+
+			It("executes create.sh with the resolved rootfs", func() {
+				container, err := pool.Create(backend.ContainerSpec{
+					RootFSPath: "some-rootfs",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRootFSCatalog.Resolved).To(Equal([]string{"some-rootfs"}))
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/create.sh",
+						Args: []string{"/depot/path/" + container.ID()},
+						Env: []string{
+							"id=" + container.ID(),
+							"rootfs_path=/catalog/some-rootfs",
+							"user_uid=10000",
+							"network_host_ip=1.2.0.1",
+							"network_container_ip=1.2.0.2",
+							"network_netmask=255.255.255.252",
+
+							"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+						},
+					},
+				))
+
+				linuxContainer := container.(*linux_backend.LinuxContainer)
+				Expect(linuxContainer.RootFSPath()).To(Equal("/catalog/some-rootfs"))
+			})
+
+			Context("and it is not known", func() {
+				It("returns the error and does not acquire anything", func() {
+					_, err := pool.Create(backend.ContainerSpec{
+						RootFSPath: "bogus-rootfs",
+					})
+					Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{RootFS: "bogus-rootfs"}))
+
+					Expect(fakeRunner).ToNot(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: "/root/path/create.sh",
+						},
+					))
+
+					container, err := pool.Create(backend.ContainerSpec{})
+					Expect(err).ToNot(HaveOccurred())
+
+					linuxContainer := container.(*linux_backend.LinuxContainer)
+					Expect(linuxContainer.Resources().UID).To(Equal(uint32(10000)))
+				})
+			})
+		})
+
+		Context("when a network is specified", func() {
+			It("removes the requested subnet from the pool", func() {
+				container, err := pool.Create(backend.ContainerSpec{
+					Network: "1.2.0.4/30",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeNetworkPool.Removed).To(Equal([]string{"1.2.0.4/30"}))
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/create.sh",
+						Args: []string{"/depot/path/" + container.ID()},
+						Env: []string{
+							"id=" + container.ID(),
+							"rootfs_path=/rootfs/path",
+							"user_uid=10000",
+							"network_host_ip=1.2.0.5",
+							"network_container_ip=1.2.0.6",
+							"network_netmask=255.255.255.252",
+
+							"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+						},
+					},
+				))
+			})
+
+			Context("as a container IP", func() {
+				It("removes the subnet containing it from the pool", func() {
+					container, err := pool.Create(backend.ContainerSpec{
+						Network: "1.2.0.10",
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeNetworkPool.Removed).To(Equal([]string{"1.2.0.8/30"}))
+
+					linuxContainer := container.(*linux_backend.LinuxContainer)
+					Expect(linuxContainer.Resources().Network.ContainerIP().String()).To(Equal("1.2.0.10"))
+				})
+
+				Context("that is not a container IP", func() {
+					It("returns an InvalidNetworkError and releases the uid", func() {
+						_, err := pool.Create(backend.ContainerSpec{
+							Network: "1.2.0.9",
+						})
+						Expect(err).To(Equal(container_pool.InvalidNetworkError{"1.2.0.9"}))
+
+						Expect(fakeNetworkPool.Removed).To(BeEmpty())
+						Expect(fakeUIDPool.Released).To(ContainElement(uint32(10000)))
+					})
+				})
+			})
+
+			Context("that is not a /30", func() {
+				It("returns an InvalidNetworkError", func() {
+					_, err := pool.Create(backend.ContainerSpec{
+						Network: "1.2.0.0/29",
+					})
+					Expect(err).To(Equal(container_pool.InvalidNetworkError{"1.2.0.0/29"}))
+
+					Expect(fakeNetworkPool.Removed).To(BeEmpty())
+				})
+			})
+
+			Context("that is outside of the pool's range", func() {
+				It("returns an InvalidNetworkError", func() {
+					_, err := pool.Create(backend.ContainerSpec{
+						Network: "10.0.0.0/30",
+					})
+					Expect(err).To(Equal(container_pool.InvalidNetworkError{"10.0.0.0/30"}))
+
+					Expect(fakeNetworkPool.Removed).To(BeEmpty())
+				})
+			})
+
+			Context("that is already taken", func() {
+				var takenError error
+
+				JustBeforeEach(func() {
+					_, ipNet, err := net.ParseCIDR("1.2.0.4/30")
+					Expect(err).ToNot(HaveOccurred())
+
+					takenError = network_pool.NetworkTakenError{Network: network.New(ipNet)}
+
+					fakeNetworkPool.RemoveError = takenError
+				})
+
+				It("returns the error and releases the uid", func() {
+					_, err := pool.Create(backend.ContainerSpec{
+						Network: "1.2.0.4/30",
+					})
+					Expect(err).To(Equal(takenError))
+
+					Expect(fakeUIDPool.Released).To(ContainElement(uint32(10000)))
+				})
+			})
+
+			Context("and executing create.sh fails", func() {
+				nastyError := errors.New("oh no!")
+
+				BeforeEach(func() {
+					fakeRunner.WhenRunning(
+						fake_command_runner.CommandSpec{
+							Path: "/root/path/create.sh",
+						}, func(*exec.Cmd) error {
+							return nastyError
+						},
+					)
+				})
+
+				It("releases the requested network", func() {
+					_, err := pool.Create(backend.ContainerSpec{
+						Network: "1.2.0.4/30",
+					})
+					Expect(err).To(Equal(nastyError))
+
+					Expect(fakeNetworkPool.Released).To(Equal([]string{"1.2.0.4/30"}))
+				})
+			})
+		})
+
 		Context("when bind mounts are specified", func() {
 			It("appends mount commands to hook-child-before-pivot.sh", func() {
 				container, err := pool.Create(backend.ContainerSpec{
@@ -270,7 +491,16 @@ var _ = Describe("Container pool", func() {
 					})
 				})
 
-				It("returns the error", func() {
+				It("returns the error, removes the container's directory, and releases the uid and network", func() {
+					var containerPath string
+
+					fakeRunner.WhenRunning(fake_command_runner.CommandSpec{
+						Path: "/root/path/create.sh",
+					}, func(cmd *exec.Cmd) error {
+						containerPath = cmd.Args[0]
+						return nil
+					})
+
 					_, err := pool.Create(backend.ContainerSpec{
 						BindMounts: []backend.BindMount{
 							{
@@ -287,6 +517,16 @@ var _ = Describe("Container pool", func() {
 					})
 
 					Expect(err).To(Equal(disaster))
+
+					Expect(fakeRunner).To(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: "/root/path/destroy.sh",
+							Args: []string{containerPath},
+						},
+					))
+
+					Expect(fakeUIDPool.Released).To(ContainElement(uint32(10000)))
+					Expect(fakeNetworkPool.Released).To(ContainElement("1.2.0.0/30"))
 				})
 			})
 		})
@@ -362,8 +602,12 @@ var _ = Describe("Container pool", func() {
 					ID:     "some-restored-id",
 					Handle: "some-restored-handle",
 
+					RootFSPath: "/some/restored/rootfs",
+
 					GraceTime: 1 * time.Second,
 
+					Properties: backend.Properties{"owner": "team-a"},
+
 					State: "some-restored-state",
 					Events: []string{
 						"some-restored-event",
@@ -387,9 +631,11 @@ var _ = Describe("Container pool", func() {
 			Expect(container.ID()).To(Equal("some-restored-id"))
 			Expect(container.Handle()).To(Equal("some-restored-handle"))
 			Expect(container.GraceTime()).To(Equal(1 * time.Second))
+			Expect(container.Properties()).To(Equal(backend.Properties{"owner": "team-a"}))
 
 			linuxContainer := container.(*linux_backend.LinuxContainer)
 
+			Expect(linuxContainer.RootFSPath()).To(Equal("/some/restored/rootfs"))
 			Expect(linuxContainer.State()).To(Equal(linux_backend.State("some-restored-state")))
 			Expect(linuxContainer.Events()).To(Equal([]string{
 				"some-restored-event",
@@ -599,6 +845,19 @@ var _ = Describe("Container pool", func() {
 			))
 		})
 
+		It("publishes a destroyed event", func() {
+			subscription := eventHub.Subscribe(nil, nil)
+
+			err := pool.Destroy(createdContainer)
+			Expect(err).ToNot(HaveOccurred())
+
+			var event backend.ContainerEvent
+			Eventually(subscription.Events()).Should(Receive(&event))
+
+			Expect(event.Type).To(Equal(backend.EventTypeDestroyed))
+			Expect(event.Handle).To(Equal(createdContainer.Handle()))
+		})
+
 		It("releases the container's ports, uid, and network", func() {
 			err := pool.Destroy(createdContainer)
 			Expect(err).ToNot(HaveOccurred())
@@ -610,5 +869,28 @@ var _ = Describe("Container pool", func() {
 
 			Expect(fakeNetworkPool.Released).To(ContainElement("1.2.0.0/30"))
 		})
+
+		It("forgets the container's processes", func() {
+			exitStatus := uint32(0)
+
+			err := createdContainer.Restore(linux_backend.ContainerSnapshot{
+				State: "active",
+				Processes: []linux_backend.ProcessSnapshot{
+					{ID: 1, ExitStatus: &exitStatus},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			processes, err := createdContainer.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(HaveLen(1))
+
+			err = pool.Destroy(createdContainer)
+			Expect(err).ToNot(HaveOccurred())
+
+			processes, err = createdContainer.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(BeEmpty())
+		})
 	})
 })
@@ -0,0 +1,198 @@
+package linux_backend
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+const defaultHealthCheckInterval = 30 * time.Second
+
+// healthChecker periodically runs a container's health check, registering
+// a "healthy" or "unhealthy" event on the container whenever its status
+// changes.
+type healthChecker struct {
+	spec backend.HealthCheckSpec
+
+	container *LinuxContainer
+
+	status        backend.HealthStatus
+	failures      int
+	lastCheckedAt time.Time
+
+	stopped bool
+
+	stopping chan struct{}
+	stopOnce *sync.Once
+
+	mutex *sync.RWMutex
+}
+
+func newHealthChecker(container *LinuxContainer, spec backend.HealthCheckSpec) *healthChecker {
+	if spec.Interval == 0 {
+		spec.Interval = defaultHealthCheckInterval
+	}
+
+	if spec.Timeout == 0 || spec.Timeout > spec.Interval {
+		spec.Timeout = spec.Interval
+	}
+
+	if spec.FailureThreshold < 1 {
+		spec.FailureThreshold = 1
+	}
+
+	// nothing would ever write to or close its stdin
+	spec.Process.StreamStdin = false
+	spec.Process.Timeout = spec.Timeout
+
+	return &healthChecker{
+		spec: spec,
+
+		container: container,
+
+		status: backend.HealthStatusUnknown,
+
+		stopping: make(chan struct{}),
+		stopOnce: &sync.Once{},
+
+		mutex: &sync.RWMutex{},
+	}
+}
+
+func restoreHealthChecker(container *LinuxContainer, snapshot HealthCheckSnapshot) *healthChecker {
+	checker := newHealthChecker(container, snapshot.Spec)
+
+	checker.status = snapshot.Status
+	checker.failures = snapshot.ConsecutiveFailures
+	checker.lastCheckedAt = snapshot.LastCheckedAt
+	checker.stopped = snapshot.Stopped
+
+	return checker
+}
+
+func (h *healthChecker) Check() {
+	for {
+		timer := time.NewTimer(h.spec.Interval)
+
+		select {
+		case <-timer.C:
+		case <-h.stopping:
+			timer.Stop()
+			return
+		}
+
+		h.record(h.check())
+	}
+}
+
+func (h *healthChecker) Stop() {
+	h.stopOnce.Do(func() {
+		h.mutex.Lock()
+		h.stopped = true
+		h.mutex.Unlock()
+
+		close(h.stopping)
+	})
+}
+
+func (h *healthChecker) Info() backend.HealthCheckInfo {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return backend.HealthCheckInfo{
+		Spec: h.spec,
+
+		Status:              h.status,
+		ConsecutiveFailures: h.failures,
+		LastCheckedAt:       h.lastCheckedAt,
+	}
+}
+
+func (h *healthChecker) Snapshot() HealthCheckSnapshot {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return HealthCheckSnapshot{
+		Spec: h.spec,
+
+		Status:              h.status,
+		ConsecutiveFailures: h.failures,
+		LastCheckedAt:       h.lastCheckedAt,
+
+		Stopped: h.stopped,
+	}
+}
+
+// check runs the health check once, reporting whether it passed in time.
+func (h *healthChecker) check() bool {
+	processID, stream, err := h.container.runProcess(h.spec.Process)
+	if err != nil {
+		log.Println(h.container.id, "failed to run health check -", err)
+		return false
+	}
+
+	// checks run far too often to be listed with the container's processes
+	h.container.processTracker.Forget(processID)
+
+	passed := make(chan bool, 1)
+
+	go func() {
+		succeeded := false
+
+		for chunk := range stream {
+			if chunk.ExitStatus != nil {
+				succeeded = *chunk.ExitStatus == 0 && !chunk.TimedOut
+			}
+		}
+
+		passed <- succeeded
+	}()
+
+	timer := time.NewTimer(h.spec.Timeout)
+	defer timer.Stop()
+
+	select {
+	case succeeded := <-passed:
+		return succeeded
+	case <-timer.C:
+		// the process itself is terminated by its own timeout
+		return false
+	}
+}
+
+// record updates the status with a check's result, unless the checker was
+// stopped while the check ran, e.g. because it was replaced, so that it
+// does not report on behalf of its successor.
+func (h *healthChecker) record(passed bool) {
+	h.mutex.Lock()
+
+	if h.stopped {
+		h.mutex.Unlock()
+		return
+	}
+
+	h.lastCheckedAt = time.Now()
+
+	previous := h.status
+
+	if passed {
+		h.failures = 0
+		h.status = backend.HealthStatusHealthy
+	} else {
+		h.failures++
+
+		if h.failures >= h.spec.FailureThreshold {
+			h.status = backend.HealthStatusUnhealthy
+		}
+	}
+
+	current := h.status
+
+	h.mutex.Unlock()
+
+	if current != previous {
+		h.container.registerEvent(backend.EventType(current), nil)
+	}
+}
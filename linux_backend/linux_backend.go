@@ -10,12 +10,18 @@ import (
 	"sync"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager"
 )
 
 type Container interface {
 	Snapshot(io.Writer) error
 	Cleanup()
 
+	// QuotaUID is the user that the container's disk usage is counted
+	// against.
+	QuotaUID() uint32
+	InfoWithDiskStat(backend.ContainerDiskStat) (backend.ContainerInfo, error)
+
 	backend.Container
 }
 
@@ -29,6 +35,7 @@ type ContainerPool interface {
 
 type LinuxBackend struct {
 	containerPool ContainerPool
+	quotaManager  quota_manager.QuotaManager
 	snapshotsPath string
 
 	containers      map[string]Container
@@ -43,6 +50,10 @@ func (e UnknownHandleError) Error() string {
 	return "unknown handle: " + e.Handle
 }
 
+func (e UnknownHandleError) Code() backend.ErrorCode {
+	return backend.ErrorCodeUnknownHandle
+}
+
 type FailedToSnapshotError struct {
 	OriginalError error
 }
@@ -51,9 +62,14 @@ func (e FailedToSnapshotError) Error() string {
 	return fmt.Sprintf("failed to save snapshot: %s", e.OriginalError)
 }
 
-func New(containerPool ContainerPool, snapshotsPath string) *LinuxBackend {
+// bulkInfoConcurrency limits how many containers' info is gathered at once,
+// as each forks a few processes.
+const bulkInfoConcurrency = 16
+
+func New(containerPool ContainerPool, quotaManager quota_manager.QuotaManager, snapshotsPath string) *LinuxBackend {
 	return &LinuxBackend{
 		containerPool: containerPool,
+		quotaManager:  quotaManager,
 		snapshotsPath: snapshotsPath,
 
 		containers:      make(map[string]Container),
@@ -98,6 +114,13 @@ func (b *LinuxBackend) Start() error {
 }
 
 func (b *LinuxBackend) Create(spec backend.ContainerSpec) (backend.Container, error) {
+	if spec.Handle != "" {
+		_, err := b.Lookup(spec.Handle)
+		if err == nil {
+			return nil, backend.HandleExistsError{Handle: spec.Handle}
+		}
+	}
+
 	container, err := b.containerPool.Create(spec)
 	if err != nil {
 		return nil, err
@@ -160,6 +183,69 @@ func (b *LinuxBackend) Lookup(handle string) (backend.Container, error) {
 	return container, nil
 }
 
+// BulkInfo gathers the containers' info concurrently, reading all of their
+// disk usages with a single repquota. Containers whose usage it did not
+// report fall back to reading their own.
+func (b *LinuxBackend) BulkInfo(handles []string) map[string]backend.ContainerInfoEntry {
+	infos := make(map[string]backend.ContainerInfoEntry)
+	infosMutex := &sync.Mutex{}
+
+	containers := []Container{}
+	uids := []uint32{}
+
+	b.containersMutex.RLock()
+
+	for _, handle := range handles {
+		container, found := b.containers[handle]
+		if !found {
+			infos[handle] = backend.ContainerInfoEntry{Err: UnknownHandleError{handle}}
+			continue
+		}
+
+		containers = append(containers, container)
+		uids = append(uids, container.QuotaUID())
+	}
+
+	b.containersMutex.RUnlock()
+
+	usages, err := b.quotaManager.GetUsages(uids)
+	if err != nil {
+		log.Println("failed to get disk usages:", err)
+	}
+
+	wg := &sync.WaitGroup{}
+	limit := make(chan bool, bulkInfoConcurrency)
+
+	for _, container := range containers {
+		wg.Add(1)
+
+		go func(container Container) {
+			defer wg.Done()
+
+			limit <- true
+			defer func() { <-limit }()
+
+			var info backend.ContainerInfo
+			var err error
+
+			usage, found := usages[container.QuotaUID()]
+			if found {
+				info, err = container.InfoWithDiskStat(usage)
+			} else {
+				info, err = container.Info()
+			}
+
+			infosMutex.Lock()
+			infos[container.Handle()] = backend.ContainerInfoEntry{Info: info, Err: err}
+			infosMutex.Unlock()
+		}(container)
+	}
+
+	wg.Wait()
+
+	return infos
+}
+
 func (b *LinuxBackend) Stop() {
 	b.containersMutex.RLock()
 	defer b.containersMutex.RUnlock()
@@ -13,6 +13,7 @@ import (
 	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/container_pool/fake_container_pool"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager/fake_quota_manager"
 )
 
 var _ = Describe("Setup", func() {
@@ -21,7 +22,7 @@ var _ = Describe("Setup", func() {
 
 	BeforeEach(func() {
 		fakeContainerPool = fake_container_pool.New()
-		linuxBackend = linux_backend.New(fakeContainerPool, "")
+		linuxBackend = linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 	})
 
 	It("sets up the container pool", func() {
@@ -49,7 +50,7 @@ var _ = Describe("Start", func() {
 	It("creates the snapshots directory if it's not already there", func() {
 		snapshotsPath := path.Join(tmpdir, "snapshots")
 
-		linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+		linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 		err := linuxBackend.Start()
 		Expect(err).ToNot(HaveOccurred())
@@ -67,6 +68,7 @@ var _ = Describe("Start", func() {
 
 			linuxBackend := linux_backend.New(
 				fakeContainerPool,
+				fake_quota_manager.New(),
 				// weird scenario: /foo/X/snapshots with X being a file
 				path.Join(tmpfile.Name(), "snapshots"),
 			)
@@ -78,7 +80,7 @@ var _ = Describe("Start", func() {
 
 	Context("when no snapshots directory is given", func() {
 		It("successfully starts", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, "")
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 
 			err := linuxBackend.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -108,7 +110,7 @@ var _ = Describe("Start", func() {
 		})
 
 		It("restores them via the container pool", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 			Expect(fakeContainerPool.RestoredSnapshots).To(BeEmpty())
 
@@ -119,7 +121,7 @@ var _ = Describe("Start", func() {
 		})
 
 		It("removes the snapshots", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 			Expect(fakeContainerPool.RestoredSnapshots).To(BeEmpty())
 
@@ -134,7 +136,7 @@ var _ = Describe("Start", func() {
 		})
 
 		It("registers the containers", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 			err := linuxBackend.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -146,7 +148,7 @@ var _ = Describe("Start", func() {
 		})
 
 		It("keeps them when pruning the container pool", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 			err := linuxBackend.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -166,7 +168,7 @@ var _ = Describe("Start", func() {
 			})
 
 			It("returns the error", func() {
-				linuxBackend := linux_backend.New(fakeContainerPool, snapshotsPath)
+				linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), snapshotsPath)
 
 				err := linuxBackend.Start()
 				Expect(err).To(Equal(disaster))
@@ -175,7 +177,7 @@ var _ = Describe("Start", func() {
 	})
 
 	It("prunes the container pool", func() {
-		linuxBackend := linux_backend.New(fakeContainerPool, "")
+		linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 
 		err := linuxBackend.Start()
 		Expect(err).ToNot(HaveOccurred())
@@ -192,7 +194,7 @@ var _ = Describe("Start", func() {
 		})
 
 		It("returns the error", func() {
-			linuxBackend := linux_backend.New(fakeContainerPool, "")
+			linuxBackend := linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 
 			err := linuxBackend.Start()
 			Expect(err).To(Equal(disaster))
@@ -211,6 +213,7 @@ var _ = Describe("Stop", func() {
 		fakeContainerPool = fake_container_pool.New()
 		linuxBackend = linux_backend.New(
 			fakeContainerPool,
+			fake_quota_manager.New(),
 			path.Join(tmpdir, "snapshots"),
 		)
 	})
@@ -252,7 +255,7 @@ var _ = Describe("Create", func() {
 
 	BeforeEach(func() {
 		fakeContainerPool = fake_container_pool.New()
-		linuxBackend = linux_backend.New(fakeContainerPool, "")
+		linuxBackend = linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 	})
 
 	It("creates a container from the pool", func() {
@@ -280,6 +283,18 @@ var _ = Describe("Create", func() {
 		Expect(foundContainer).To(Equal(container))
 	})
 
+	Context("when a container with the handle already exists", func() {
+		It("returns an error without creating another", func() {
+			_, err := linuxBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = linuxBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).To(Equal(backend.HandleExistsError{Handle: "some-handle"}))
+
+			Expect(fakeContainerPool.CreatedContainers).To(HaveLen(1))
+		})
+	})
+
 	Context("when creating the container fails", func() {
 		disaster := errors.New("failed to create")
 
@@ -333,7 +348,7 @@ var _ = Describe("Destroy", func() {
 
 	BeforeEach(func() {
 		fakeContainerPool = fake_container_pool.New()
-		linuxBackend = linux_backend.New(fakeContainerPool, "")
+		linuxBackend = linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 
 		newContainer, err := linuxBackend.Create(backend.ContainerSpec{})
 		Expect(err).ToNot(HaveOccurred())
@@ -397,7 +412,7 @@ var _ = Describe("Lookup", func() {
 
 	BeforeEach(func() {
 		fakeContainerPool = fake_container_pool.New()
-		linuxBackend = linux_backend.New(fakeContainerPool, "")
+		linuxBackend = linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 	})
 
 	It("returns the container", func() {
@@ -427,7 +442,7 @@ var _ = Describe("Containers", func() {
 
 	BeforeEach(func() {
 		fakeContainerPool = fake_container_pool.New()
-		linuxBackend = linux_backend.New(fakeContainerPool, "")
+		linuxBackend = linux_backend.New(fakeContainerPool, fake_quota_manager.New(), "")
 	})
 
 	It("returns a list of all existing containers", func() {
@@ -444,3 +459,99 @@ var _ = Describe("Containers", func() {
 		Expect(containers).To(ContainElement(container2))
 	})
 })
+
+var _ = Describe("BulkInfo", func() {
+	var fakeContainerPool *fake_container_pool.FakeContainerPool
+	var fakeQuotaManager *fake_quota_manager.FakeQuotaManager
+	var linuxBackend *linux_backend.LinuxBackend
+
+	var container1 *fake_backend.FakeContainer
+	var container2 *fake_backend.FakeContainer
+
+	BeforeEach(func() {
+		fakeContainerPool = fake_container_pool.New()
+		fakeQuotaManager = fake_quota_manager.New()
+		linuxBackend = linux_backend.New(fakeContainerPool, fakeQuotaManager, "")
+
+		newContainer, err := linuxBackend.Create(backend.ContainerSpec{Handle: "handle-1"})
+		Expect(err).ToNot(HaveOccurred())
+
+		container1 = newContainer.(*fake_backend.FakeContainer)
+		container1.ReportedQuotaUID = 1
+		container1.ReportedInfo = backend.ContainerInfo{State: "active"}
+
+		newContainer, err = linuxBackend.Create(backend.ContainerSpec{Handle: "handle-2"})
+		Expect(err).ToNot(HaveOccurred())
+
+		container2 = newContainer.(*fake_backend.FakeContainer)
+		container2.ReportedQuotaUID = 2
+		container2.ReportedInfo = backend.ContainerInfo{State: "stopped"}
+
+		fakeQuotaManager.GetUsagesResult = map[uint32]backend.ContainerDiskStat{
+			1: {BytesUsed: 1, InodesUsed: 2},
+			2: {BytesUsed: 3, InodesUsed: 4},
+		}
+	})
+
+	It("returns the info of each container, with their disk usages", func() {
+		infos := linuxBackend.BulkInfo([]string{"handle-1", "handle-2"})
+
+		Expect(infos).To(Equal(map[string]backend.ContainerInfoEntry{
+			"handle-1": {
+				Info: backend.ContainerInfo{
+					State:    "active",
+					DiskStat: backend.ContainerDiskStat{BytesUsed: 1, InodesUsed: 2},
+				},
+			},
+			"handle-2": {
+				Info: backend.ContainerInfo{
+					State:    "stopped",
+					DiskStat: backend.ContainerDiskStat{BytesUsed: 3, InodesUsed: 4},
+				},
+			},
+		}))
+	})
+
+	Context("when a container's disk usage was not reported", func() {
+		BeforeEach(func() {
+			fakeQuotaManager.GetUsagesResult = map[uint32]backend.ContainerDiskStat{
+				1: {BytesUsed: 1, InodesUsed: 2},
+			}
+
+			fakeQuotaManager.GetUsagesError = errors.New("oh no!")
+
+			container2.ReportedInfo.DiskStat = backend.ContainerDiskStat{BytesUsed: 5, InodesUsed: 6}
+		})
+
+		It("gets the container's info on its own", func() {
+			infos := linuxBackend.BulkInfo([]string{"handle-1", "handle-2"})
+
+			Expect(infos["handle-1"].Info.DiskStat).To(Equal(backend.ContainerDiskStat{BytesUsed: 1, InodesUsed: 2}))
+			Expect(infos["handle-2"].Info.DiskStat).To(Equal(backend.ContainerDiskStat{BytesUsed: 5, InodesUsed: 6}))
+		})
+	})
+
+	Context("when getting a container's info fails", func() {
+		disaster := errors.New("oh no!")
+
+		BeforeEach(func() {
+			container2.InfoError = disaster
+		})
+
+		It("returns the error for that container only", func() {
+			infos := linuxBackend.BulkInfo([]string{"handle-1", "handle-2"})
+
+			Expect(infos["handle-1"].Err).ToNot(HaveOccurred())
+			Expect(infos["handle-2"].Err).To(Equal(disaster))
+		})
+	})
+
+	Context("when a handle is not found", func() {
+		It("returns UnknownHandleError for it", func() {
+			infos := linuxBackend.BulkInfo([]string{"handle-1", "bogus-handle"})
+
+			Expect(infos["handle-1"].Err).ToNot(HaveOccurred())
+			Expect(infos["bogus-handle"].Err).To(Equal(linux_backend.UnknownHandleError{"bogus-handle"}))
+		})
+	})
+})
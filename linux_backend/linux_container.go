@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
 	"path"
 	"strconv"
@@ -16,25 +17,45 @@ import (
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/bandwidth_manager"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/cgroups_manager"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/process_tracker"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager"
 )
 
+type CopyFailedError struct {
+	Err    error
+	Output string
+}
+
+func (e CopyFailedError) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("copy failed: %s", e.Err)
+	}
+
+	return fmt.Sprintf("copy failed: %s: %s", e.Err, strings.TrimSpace(e.Output))
+}
+
 type LinuxContainer struct {
 	id     string
 	handle string
 	path   string
 
+	rootFSPath string
+
 	graceTime time.Duration
 
+	properties backend.Properties
+
 	state      State
 	stateMutex sync.RWMutex
 
 	events      []string
 	eventsMutex sync.RWMutex
 
+	eventHub *event_hub.Hub
+
 	resources *Resources
 
 	portPool PortPool
@@ -67,6 +88,12 @@ type LinuxContainer struct {
 
 	netOuts      []NetOutSpec
 	netOutsMutex sync.RWMutex
+
+	supervisors      []*processSupervisor
+	supervisorsMutex sync.RWMutex
+
+	healthChecker      *healthChecker
+	healthCheckerMutex sync.RWMutex
 }
 
 type NetInSpec struct {
@@ -93,26 +120,39 @@ const (
 	StateStopped = State("stopped")
 )
 
+// MaxEvents is how many of its most recent events a container remembers,
+// so that one whose health flaps does not grow without bound.
+const MaxEvents = 100
+
 func NewLinuxContainer(
-	id, handle, path string,
+	id, handle, path, rootFSPath string,
 	graceTime time.Duration,
+	properties backend.Properties,
+	processOutputHistory uint64,
 	resources *Resources,
 	portPool PortPool,
 	runner command_runner.CommandRunner,
 	cgroupsManager cgroups_manager.CgroupsManager,
 	quotaManager quota_manager.QuotaManager,
 	bandwidthManager bandwidth_manager.BandwidthManager,
+	eventHub *event_hub.Hub,
 ) *LinuxContainer {
 	return &LinuxContainer{
 		id:     id,
 		handle: handle,
 		path:   path,
 
+		rootFSPath: rootFSPath,
+
 		graceTime: graceTime,
 
+		properties: properties,
+
 		state:  StateBorn,
 		events: []string{},
 
+		eventHub: eventHub,
+
 		resources: resources,
 
 		portPool: portPool,
@@ -123,7 +163,7 @@ func NewLinuxContainer(
 		quotaManager:     quotaManager,
 		bandwidthManager: bandwidthManager,
 
-		processTracker: process_tracker.New(path, runner),
+		processTracker: process_tracker.New(path, processOutputHistory, process_tracker.DefaultTimeoutGracePeriod, runner),
 	}
 }
 
@@ -135,10 +175,18 @@ func (c *LinuxContainer) Handle() string {
 	return c.handle
 }
 
+func (c *LinuxContainer) RootFSPath() string {
+	return c.rootFSPath
+}
+
 func (c *LinuxContainer) GraceTime() time.Duration {
 	return c.graceTime
 }
 
+func (c *LinuxContainer) Properties() backend.Properties {
+	return c.properties
+}
+
 func (c *LinuxContainer) State() State {
 	c.stateMutex.RLock()
 	defer c.stateMutex.RUnlock()
@@ -180,22 +228,54 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 	c.netOutsMutex.RLock()
 	defer c.netOutsMutex.RUnlock()
 
+	c.supervisorsMutex.RLock()
+	defer c.supervisorsMutex.RUnlock()
+
+	c.healthCheckerMutex.RLock()
+	defer c.healthCheckerMutex.RUnlock()
+
 	processSnapshots := []ProcessSnapshot{}
 
-	for _, process := range c.processTracker.ActiveProcesses() {
+	for _, process := range c.processTracker.Processes() {
 		processSnapshots = append(
 			processSnapshots,
-			ProcessSnapshot{ID: process.ID},
+			ProcessSnapshot{
+				ID:        process.ID,
+				Command:   process.Command,
+				StartedAt: process.StartedAt,
+				Timeout:   process.Timeout,
+
+				FinishedAt: process.FinishedAt,
+				ExitStatus: process.ExitStatus,
+				TimedOut:   process.TimedOut,
+			},
 		)
 	}
 
+	supervisorSnapshots := []SupervisorSnapshot{}
+
+	for _, supervisor := range c.supervisors {
+		supervisorSnapshots = append(supervisorSnapshots, supervisor.Snapshot())
+	}
+
+	var healthCheckSnapshot *HealthCheckSnapshot
+
+	if c.healthChecker != nil {
+		snapshot := c.healthChecker.Snapshot()
+		healthCheckSnapshot = &snapshot
+	}
+
 	return json.NewEncoder(out).Encode(
 		ContainerSnapshot{
 			ID:     c.id,
 			Handle: c.handle,
 
+			RootFSPath: c.rootFSPath,
+
 			GraceTime: c.graceTime,
 
+			Properties: c.properties,
+
 			State:  string(c.State()),
 			Events: c.Events(),
 
@@ -216,6 +296,10 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 			NetOuts: c.netOuts,
 
 			Processes: processSnapshots,
+
+			Supervisors: supervisorSnapshots,
+
+			HealthCheck: healthCheckSnapshot,
 		},
 	)
 }
@@ -223,9 +307,9 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 func (c *LinuxContainer) Restore(snapshot ContainerSnapshot) error {
 	c.setState(State(snapshot.State))
 
-	for _, ev := range snapshot.Events {
-		c.registerEvent(ev)
-	}
+	c.eventsMutex.Lock()
+	c.events = append(c.events, snapshot.Events...)
+	c.eventsMutex.Unlock()
 
 	if snapshot.Limits.Memory != nil {
 		err := c.LimitMemory(*snapshot.Limits.Memory)
@@ -235,7 +319,46 @@ func (c *LinuxContainer) Restore(snapshot ContainerSnapshot) error {
 	}
 
 	for _, process := range snapshot.Processes {
-		c.processTracker.Restore(process.ID)
+		c.processTracker.Restore(backend.ProcessInfo{
+			ID:        process.ID,
+			Command:   process.Command,
+			StartedAt: process.StartedAt,
+			Timeout:   process.Timeout,
+
+			FinishedAt: process.FinishedAt,
+			ExitStatus: process.ExitStatus,
+			TimedOut:   process.TimedOut,
+		})
+
+		if process.ExitStatus == nil {
+			go c.publishProcessExit(process.ID)
+		}
+	}
+
+	c.supervisorsMutex.Lock()
+
+	for _, snapshot := range snapshot.Supervisors {
+		supervisor := restoreProcessSupervisor(c, snapshot)
+
+		c.supervisors = append(c.supervisors, supervisor)
+
+		if !snapshot.Done && !snapshot.Stopped {
+			go supervisor.Supervise()
+		}
+	}
+
+	c.supervisorsMutex.Unlock()
+
+	if snapshot.HealthCheck != nil {
+		checker := restoreHealthChecker(c, *snapshot.HealthCheck)
+
+		c.healthCheckerMutex.Lock()
+		c.healthChecker = checker
+		c.healthCheckerMutex.Unlock()
+
+		if !snapshot.HealthCheck.Stopped {
+			go checker.Check()
+		}
 	}
 
 	net := &exec.Cmd{
@@ -284,12 +407,30 @@ func (c *LinuxContainer) Start() error {
 
 	c.setState(StateActive)
 
+	c.publishEvent(backend.EventTypeStarted, nil)
+
 	return nil
 }
 
 func (c *LinuxContainer) Stop(kill bool) error {
 	log.Println(c.id, "stopping")
 
+	c.supervisorsMutex.RLock()
+
+	for _, supervisor := range c.supervisors {
+		supervisor.Stop()
+	}
+
+	c.supervisorsMutex.RUnlock()
+
+	c.healthCheckerMutex.RLock()
+
+	if c.healthChecker != nil {
+		c.healthChecker.Stop()
+	}
+
+	c.healthCheckerMutex.RUnlock()
+
 	stop := &exec.Cmd{
 		Path: path.Join(c.path, "stop.sh"),
 	}
@@ -307,6 +448,10 @@ func (c *LinuxContainer) Stop(kill bool) error {
 
 	c.setState(StateStopped)
 
+	c.publishEvent(backend.EventTypeStopped, map[string]string{
+		"killed": strconv.FormatBool(kill),
+	})
+
 	return nil
 }
 
@@ -318,7 +463,29 @@ func (c *LinuxContainer) Cleanup() {
 	}
 }
 
+// ForgetProcesses drops the processes the container has run, along with
+// their output history, once it has been destroyed.
+func (c *LinuxContainer) ForgetProcesses() {
+	c.processTracker.ForgetAll()
+}
+
+func (c *LinuxContainer) QuotaUID() uint32 {
+	return c.resources.UID
+}
+
 func (c *LinuxContainer) Info() (backend.ContainerInfo, error) {
+	diskStat, err := c.quotaManager.GetUsage(c.resources.UID)
+	if err != nil {
+		return backend.ContainerInfo{}, err
+	}
+
+	return c.InfoWithDiskStat(diskStat)
+}
+
+// InfoWithDiskStat gathers the container's info like Info, but with its
+// disk usage already known, so that many containers' usages can be read at
+// once.
+func (c *LinuxContainer) InfoWithDiskStat(diskStat backend.ContainerDiskStat) (backend.ContainerInfo, error) {
 	log.Println(c.id, "info")
 
 	memoryStat, err := c.cgroupsManager.Get("memory", "memory.stat")
@@ -336,11 +503,6 @@ func (c *LinuxContainer) Info() (backend.ContainerInfo, error) {
 		return backend.ContainerInfo{}, err
 	}
 
-	diskStat, err := c.quotaManager.GetUsage(c.resources.UID)
-	if err != nil {
-		return backend.ContainerInfo{}, err
-	}
-
 	bandwidthStat, err := c.bandwidthManager.GetLimits()
 	if err != nil {
 		return backend.ContainerInfo{}, err
@@ -351,9 +513,30 @@ func (c *LinuxContainer) Info() (backend.ContainerInfo, error) {
 		processIDs = append(processIDs, process.ID)
 	}
 
+	c.supervisorsMutex.RLock()
+
+	supervisedProcesses := []backend.SupervisedProcessInfo{}
+	for _, supervisor := range c.supervisors {
+		supervisedProcesses = append(supervisedProcesses, supervisor.Info())
+	}
+
+	c.supervisorsMutex.RUnlock()
+
+	var healthCheck *backend.HealthCheckInfo
+
+	c.healthCheckerMutex.RLock()
+
+	if c.healthChecker != nil {
+		info := c.healthChecker.Info()
+		healthCheck = &info
+	}
+
+	c.healthCheckerMutex.RUnlock()
+
 	return backend.ContainerInfo{
 		State:         string(c.State()),
 		Events:        c.Events(),
+		Properties:    c.properties,
 		HostIP:        c.resources.Network.HostIP().String(),
 		ContainerIP:   c.resources.Network.ContainerIP().String(),
 		ContainerPath: c.path,
@@ -362,18 +545,34 @@ func (c *LinuxContainer) Info() (backend.ContainerInfo, error) {
 		CPUStat:       parseCPUStat(cpuUsage, cpuStat),
 		DiskStat:      diskStat,
 		BandwidthStat: bandwidthStat,
+
+		SupervisedProcesses: supervisedProcesses,
+		HealthCheck:         healthCheck,
 	}, nil
 }
 
-func (c *LinuxContainer) CopyIn(src, dst string) error {
+func (c *LinuxContainer) IsRootUser(user string) (bool, error) {
+	if user == "" {
+		user = "vcap"
+	}
+
+	return isRootUser(path.Join(c.path, "tmp", "rootfs", "etc", "passwd"), user)
+}
+
+func (c *LinuxContainer) CopyIn(src, dst, user string) error {
 	log.Println(c.id, "copying in from", src, "to", dst)
-	return c.rsync(src, "vcap@container:"+dst)
+	return c.rsync(src, "container:"+dst, user)
 }
 
-func (c *LinuxContainer) CopyOut(src, dst, owner string) error {
+func (c *LinuxContainer) CopyOut(src, dst, owner, user string) error {
 	log.Println(c.id, "copying out from", src, "to", dst)
 
-	err := c.rsync("vcap@container:"+src, dst)
+	err := backend.ValidateUser(owner)
+	if err != nil {
+		return err
+	}
+
+	err = c.rsync("container:"+src, dst, user)
 	if err != nil {
 		return err
 	}
@@ -523,30 +722,160 @@ func (c *LinuxContainer) CurrentCPULimits() (backend.CPULimits, error) {
 }
 
 func (c *LinuxContainer) Run(spec backend.ProcessSpec) (uint32, <-chan backend.ProcessStream, error) {
-	log.Println(c.id, "running process:", spec.Script)
+	processID, stream, err := c.runProcess(spec)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	go c.publishProcessExit(processID)
+
+	return processID, stream, nil
+}
+
+// runProcess runs a process without publishing an event when it exits, for
+// processes run on the container's own behalf.
+func (c *LinuxContainer) runProcess(spec backend.ProcessSpec) (uint32, <-chan backend.ProcessStream, error) {
+	if spec.Path != "" {
+		log.Println(c.id, "running process:", spec.Path, spec.Args)
+	} else {
+		log.Println(c.id, "running process:", spec.Script)
+	}
 
 	wshPath := path.Join(c.path, "bin", "wsh")
 	sockPath := path.Join(c.path, "run", "wshd.sock")
 
-	user := "vcap"
-	if spec.Privileged {
-		user = "root"
+	user := spec.User
+	if user == "" {
+		user = "vcap"
+
+		if spec.Privileged {
+			user = "root"
+		}
+	}
+
+	args := []string{"--socket", sockPath, "--user", user, "--pid-file", process_tracker.PIDFile}
+
+	if spec.TTY != nil {
+		args = append(args, "--tty", "--winsize-fifo", process_tracker.WindowSizeFifo)
+
+		if spec.TTY.WindowSize != nil {
+			args = append(
+				args,
+				"--columns", fmt.Sprintf("%d", spec.TTY.WindowSize.Columns),
+				"--rows", fmt.Sprintf("%d", spec.TTY.WindowSize.Rows),
+			)
+		}
+	}
+
+	for _, env := range spec.Env {
+		args = append(args, "--env", env)
+	}
+
+	if spec.Dir != "" {
+		args = append(args, "--dir", spec.Dir)
 	}
 
 	wsh := &exec.Cmd{
-		Path:  wshPath,
-		Args:  []string{"--socket", sockPath, "--user", user, "/bin/bash"},
-		Stdin: bytes.NewBufferString(spec.Script),
+		Path: wshPath,
+	}
+
+	if spec.Path != "" {
+		wsh.Args = append(append(args, spec.Path), spec.Args...)
+	} else {
+		wsh.Args = append(args, "/bin/bash")
+		wsh.Stdin = bytes.NewBufferString(spec.Script)
 	}
 
 	setRLimitsEnv(wsh, spec.Limits)
 
-	return c.processTracker.Run(wsh)
+	return c.processTracker.Run(wsh, spec)
+}
+
+func (c *LinuxContainer) Supervise(spec backend.ProcessSpec, policy backend.RestartPolicy) (uint32, error) {
+	log.Println(c.id, "supervising process")
+
+	// nothing would ever write to or close its stdin
+	spec.StreamStdin = false
+
+	processID, stream, err := c.Run(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	go backend.DrainProcessStream(stream)
+
+	supervisor := newProcessSupervisor(c, spec, policy, processID)
+
+	c.supervisorsMutex.Lock()
+	c.supervisors = append(c.supervisors, supervisor)
+	c.supervisorsMutex.Unlock()
+
+	go supervisor.Supervise()
+
+	return processID, nil
+}
+
+func (c *LinuxContainer) SetHealthCheck(spec backend.HealthCheckSpec) error {
+	log.Println(c.id, "setting health check")
+
+	checker := newHealthChecker(c, spec)
+
+	c.healthCheckerMutex.Lock()
+
+	if c.healthChecker != nil {
+		c.healthChecker.Stop()
+	}
+
+	c.healthChecker = checker
+
+	c.healthCheckerMutex.Unlock()
+
+	go checker.Check()
+
+	return nil
+}
+
+func (c *LinuxContainer) RemoveHealthCheck() error {
+	log.Println(c.id, "removing health check")
+
+	c.healthCheckerMutex.Lock()
+
+	if c.healthChecker != nil {
+		c.healthChecker.Stop()
+	}
+
+	c.healthChecker = nil
+
+	c.healthCheckerMutex.Unlock()
+
+	return nil
 }
 
-func (c *LinuxContainer) Attach(processID uint32) (<-chan backend.ProcessStream, error) {
+func (c *LinuxContainer) Attach(processID uint32, offsets backend.ProcessStreamOffsets) (<-chan backend.ProcessStream, error) {
 	log.Println(c.id, "attaching to process", processID)
-	return c.processTracker.Attach(processID)
+	return c.processTracker.Attach(processID, offsets)
+}
+
+func (c *LinuxContainer) Stdin(processID uint32) (io.WriteCloser, error) {
+	return c.processTracker.Stdin(processID)
+}
+
+func (c *LinuxContainer) SetWindowSize(processID uint32, size backend.WindowSize) error {
+	log.Println(c.id, "resizing tty of process", processID, "to", size.Columns, "x", size.Rows)
+	return c.processTracker.SetWindowSize(processID, size)
+}
+
+func (c *LinuxContainer) Signal(processID uint32, signal os.Signal) error {
+	log.Println(c.id, "sending", signal, "to process", processID)
+	return c.processTracker.Signal(processID, signal)
+}
+
+func (c *LinuxContainer) Processes() ([]backend.ProcessInfo, error) {
+	return c.processTracker.Processes(), nil
+}
+
+func (c *LinuxContainer) Wait(processID uint32, timeout time.Duration) (uint32, error) {
+	return c.processTracker.Wait(processID, timeout)
 }
 
 func (c *LinuxContainer) NetIn(hostPort uint32, containerPort uint32) (uint32, uint32, error) {
@@ -647,30 +976,78 @@ func (c *LinuxContainer) setState(state State) {
 	c.state = state
 }
 
-func (c *LinuxContainer) registerEvent(event string) {
+// registerEvent records an event in the container's info as well as
+// publishing it.
+func (c *LinuxContainer) registerEvent(eventType backend.EventType, details map[string]string) {
 	c.eventsMutex.Lock()
-	defer c.eventsMutex.Unlock()
+	c.events = append(c.events, string(eventType))
 
-	c.events = append(c.events, event)
+	if len(c.events) > MaxEvents {
+		c.events = c.events[len(c.events)-MaxEvents:]
+	}
+
+	c.eventsMutex.Unlock()
+
+	c.publishEvent(eventType, details)
+}
+
+func (c *LinuxContainer) publishEvent(eventType backend.EventType, details map[string]string) {
+	c.eventHub.Publish(backend.ContainerEvent{
+		Type:    eventType,
+		Time:    time.Now(),
+		Handle:  c.handle,
+		Details: details,
+	})
+}
+
+func (c *LinuxContainer) publishProcessExit(processID uint32) {
+	exitStatus, err := c.processTracker.Wait(processID, 0)
+	if err != nil {
+		return
+	}
+
+	c.publishEvent(backend.EventTypeProcessExited, map[string]string{
+		"process_id":  strconv.FormatUint(uint64(processID), 10),
+		"exit_status": strconv.FormatUint(uint64(exitStatus), 10),
+	})
 }
 
-func (c *LinuxContainer) rsync(src, dst string) error {
+func (c *LinuxContainer) rsync(src, dst, user string) error {
 	wshPath := path.Join(c.path, "bin", "wsh")
 	sockPath := path.Join(c.path, "run", "wshd.sock")
 
+	if user == "" {
+		user = "vcap"
+	}
+
+	// rsync splits the remote shell command on spaces and quotes, so only a
+	// plain name or id may go in it
+	err := backend.ValidateUser(user)
+	if err != nil {
+		return err
+	}
+
+	stderr := new(bytes.Buffer)
+
 	rsync := &exec.Cmd{
 		Path: "rsync",
 		Args: []string{
-			"-e", wshPath + " --socket " + sockPath + " --rsh",
+			"-e", wshPath + " --socket " + sockPath + " --user " + user + " --rsh",
 			"-r",
 			"-p",
 			"--links",
 			src,
 			dst,
 		},
+		Stderr: stderr,
 	}
 
-	return c.runner.Run(rsync)
+	err = c.runner.Run(rsync)
+	if err != nil {
+		return CopyFailedError{err, stderr.String()}
+	}
+
+	return nil
 }
 
 func (c *LinuxContainer) startOomNotifier() error {
@@ -711,7 +1088,7 @@ func (c *LinuxContainer) watchForOom(oom *exec.Cmd) {
 	err := c.runner.Wait(oom)
 	if err == nil {
 		log.Println(c.id, "out of memory")
-		c.registerEvent("out of memory")
+		c.registerEvent(backend.EventTypeOutOfMemory, nil)
 		c.Stop(false)
 	} else {
 		log.Println(c.id, "oom failed:", err)
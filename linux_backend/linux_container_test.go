@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"net"
 	"os"
 	"os/exec"
+	"path"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -17,6 +20,7 @@ import (
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner/fake_command_runner"
 	. "github.com/pivotal-cf-experimental/garden/command_runner/fake_command_runner/matchers"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/linux_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/cgroups_manager/fake_cgroups_manager"
@@ -32,6 +36,7 @@ var fakeRunner *fake_command_runner.FakeCommandRunner
 var containerResources *linux_backend.Resources
 var container *linux_backend.LinuxContainer
 var fakePortPool *fake_port_pool.FakePortPool
+var eventHub *event_hub.Hub
 
 var _ = Describe("Linux containers", func() {
 	BeforeEach(func() {
@@ -42,6 +47,8 @@ var _ = Describe("Linux containers", func() {
 		fakeQuotaManager = fake_quota_manager.New()
 		fakeBandwidthManager = fake_bandwidth_manager.New()
 
+		eventHub = event_hub.New()
+
 		_, ipNet, err := net.ParseCIDR("10.254.0.0/24")
 		Expect(err).ToNot(HaveOccurred())
 
@@ -62,13 +69,17 @@ var _ = Describe("Linux containers", func() {
 			"some-id",
 			"some-handle",
 			"/depot/some-id",
+			"/some/rootfs/path",
 			1*time.Second,
+			backend.Properties{"property-name": "property-value"},
+			0,
 			containerResources,
 			fakePortPool,
 			fakeRunner,
 			fakeCgroups,
 			fakeQuotaManager,
 			fakeBandwidthManager,
+			eventHub,
 		)
 	})
 
@@ -152,7 +163,9 @@ var _ = Describe("Linux containers", func() {
 
 			setupSuccessfulSpawn()
 
-			_, _, err = container.Run(backend.ProcessSpec{})
+			_, _, err = container.Run(backend.ProcessSpec{
+				Script: "/some/script",
+			})
 			Expect(err).ToNot(HaveOccurred())
 
 			out := new(bytes.Buffer)
@@ -167,9 +180,14 @@ var _ = Describe("Linux containers", func() {
 
 			Expect(snapshot.ID).To(Equal("some-id"))
 			Expect(snapshot.Handle).To(Equal("some-handle"))
+			Expect(snapshot.RootFSPath).To(Equal("/some/rootfs/path"))
 
 			Expect(snapshot.GraceTime).To(Equal(1 * time.Second))
 
+			Expect(snapshot.Properties).To(Equal(backend.Properties{
+				"property-name": "property-value",
+			}))
+
 			Expect(snapshot.State).To(Equal("stopped"))
 			Expect(snapshot.Events).To(Equal([]string{"out of memory"}))
 
@@ -216,11 +234,45 @@ var _ = Describe("Linux containers", func() {
 				},
 			))
 
-			Expect(snapshot.Processes).To(ContainElement(
-				linux_backend.ProcessSnapshot{
-					ID: 0,
-				},
-			))
+			Expect(snapshot.Processes).To(HaveLen(1))
+			Expect(snapshot.Processes[0].ID).To(Equal(uint32(0)))
+			Expect(snapshot.Processes[0].Command).To(Equal("/some/script"))
+			Expect(snapshot.Processes[0].StartedAt).ToNot(BeZero())
+		})
+
+		Context("with finished processes", func() {
+			It("saves their exit status", func(done Done) {
+				setupSuccessfulSpawn()
+
+				_, payloads, err := container.Run(backend.ProcessSpec{
+					Path: "/some/bin",
+					Args: []string{"some", "args"},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				for _ = range payloads {
+					// noop
+				}
+
+				Eventually(func() *uint32 {
+					out := new(bytes.Buffer)
+
+					err := container.Snapshot(out)
+					Expect(err).ToNot(HaveOccurred())
+
+					var snapshot linux_backend.ContainerSnapshot
+
+					err = json.NewDecoder(out).Decode(&snapshot)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(snapshot.Processes).To(HaveLen(1))
+					Expect(snapshot.Processes[0].Command).To(Equal("/some/bin some args"))
+
+					return snapshot.Processes[0].ExitStatus
+				}).ShouldNot(BeNil())
+
+				close(done)
+			}, 5.0)
 		})
 
 		Context("with no limits set", func() {
@@ -264,6 +316,44 @@ var _ = Describe("Linux containers", func() {
 			}))
 		})
 
+		It("restores finished processes", func() {
+			exitStatus := uint32(42)
+			startedAt := time.Unix(100, 0)
+			finishedAt := time.Unix(200, 0)
+
+			err := container.Restore(linux_backend.ContainerSnapshot{
+				State:  "active",
+				Events: []string{},
+
+				Processes: []linux_backend.ProcessSnapshot{
+					{
+						ID:         3,
+						Command:    "/some/script",
+						StartedAt:  startedAt,
+						FinishedAt: finishedAt,
+						ExitStatus: &exitStatus,
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			processes, err := container.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(Equal([]backend.ProcessInfo{
+				{
+					ID:         3,
+					Command:    "/some/script",
+					StartedAt:  startedAt,
+					FinishedAt: finishedAt,
+					ExitStatus: &exitStatus,
+				},
+			}))
+
+			status, err := container.Wait(3, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(uint32(42)))
+		})
+
 		It("restores process state", func(done Done) {
 			writeHello := make(chan bool)
 
@@ -297,7 +387,7 @@ var _ = Describe("Linux containers", func() {
 			linked := make(chan bool)
 
 			go func() {
-				payloads, err := container.Attach(0)
+				payloads, err := container.Attach(0, backend.ProcessStreamOffsets{})
 				Expect(err).NotTo(HaveOccurred())
 
 				writeHello <- true
@@ -527,6 +617,20 @@ var _ = Describe("Linux containers", func() {
 			))
 		})
 
+		It("publishes a started event", func() {
+			subscription := eventHub.Subscribe(nil, nil)
+
+			err := container.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			var event backend.ContainerEvent
+			Expect(subscription.Events()).To(Receive(&event))
+
+			Expect(event.Type).To(Equal(backend.EventTypeStarted))
+			Expect(event.Handle).To(Equal("some-handle"))
+			Expect(event.Time.IsZero()).To(BeFalse())
+		})
+
 		It("changes the container's state to active", func() {
 			Expect(container.State()).To(Equal(linux_backend.StateBorn))
 
@@ -577,6 +681,20 @@ var _ = Describe("Linux containers", func() {
 			))
 		})
 
+		It("publishes a stopped event", func() {
+			subscription := eventHub.Subscribe(nil, nil)
+
+			err := container.Stop(true)
+			Expect(err).ToNot(HaveOccurred())
+
+			var event backend.ContainerEvent
+			Expect(subscription.Events()).To(Receive(&event))
+
+			Expect(event.Type).To(Equal(backend.EventTypeStopped))
+			Expect(event.Handle).To(Equal("some-handle"))
+			Expect(event.Details).To(Equal(map[string]string{"killed": "true"}))
+		})
+
 		It("sets the container's state to stopped", func() {
 			Expect(container.State()).To(Equal(linux_backend.StateBorn))
 
@@ -722,9 +840,83 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Resolving users", func() {
+		var containerPath string
+		var userContainer *linux_backend.LinuxContainer
+
+		BeforeEach(func() {
+			var err error
+
+			containerPath, err = ioutil.TempDir("", "linux-container-users")
+			Expect(err).ToNot(HaveOccurred())
+
+			etcPath := path.Join(containerPath, "tmp", "rootfs", "etc")
+
+			err = os.MkdirAll(etcPath, 0755)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = ioutil.WriteFile(path.Join(etcPath, "passwd"), []byte(
+				"root:x:0:0:root:/root:/bin/bash\n"+
+					"toor:x:0:1000:root alias:/root:/bin/bash\n"+
+					"wheely:x:1001:0:root group:/home/wheely:/bin/bash\n"+
+					"vcap:x:1000:1000::/home/vcap:/bin/bash\n",
+			), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			userContainer = linux_backend.NewLinuxContainer(
+				"some-id",
+				"some-handle",
+				containerPath,
+				"/some/rootfs/path",
+				1*time.Second,
+				nil,
+				0,
+				containerResources,
+				fakePortPool,
+				fakeRunner,
+				fakeCgroups,
+				fakeQuotaManager,
+				fakeBandwidthManager,
+				eventHub,
+			)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(containerPath)
+		})
+
+		It("reports users with uid or gid 0 in the container's passwd as root", func() {
+			for _, user := range []string{"root", "toor", "wheely", "0", "000", "1001", "0:1000", "1000:0"} {
+				root, err := userContainer.IsRootUser(user)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(root).To(BeTrue(), user)
+			}
+		})
+
+		It("reports other users as not root", func() {
+			for _, user := range []string{"", "vcap", "1000", "1001:1000", "2000", "nobody"} {
+				root, err := userContainer.IsRootUser(user)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(root).To(BeFalse(), user)
+			}
+		})
+
+		Context("when the container has no passwd file", func() {
+			BeforeEach(func() {
+				err := os.Remove(path.Join(containerPath, "tmp", "rootfs", "etc", "passwd"))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				_, err := userContainer.IsRootUser("vcap")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("Copying in", func() {
 		It("executes rsync from src into dst via wsh --rsh", func() {
-			err := container.CopyIn("/src", "/dst")
+			err := container.CopyIn("/src", "/dst", "")
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(fakeRunner).To(HaveExecutedSerially(
@@ -732,17 +924,39 @@ var _ = Describe("Linux containers", func() {
 					Path: "rsync",
 					Args: []string{
 						"-e",
-						"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --rsh",
+						"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --user vcap --rsh",
 						"-r",
 						"-p",
 						"--links",
 						"/src",
-						"vcap@container:/dst",
+						"container:/dst",
 					},
 				},
 			))
 		})
 
+		Context("when a user is given", func() {
+			It("runs rsync in the container as that user", func() {
+				err := container.CopyIn("/src", "/dst", "1000:1000")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "rsync",
+						Args: []string{
+							"-e",
+							"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --user 1000:1000 --rsh",
+							"-r",
+							"-p",
+							"--links",
+							"/src",
+							"container:/dst",
+						},
+					},
+				))
+			})
+		})
+
 		Context("when rsync fails", func() {
 			nastyError := errors.New("oh no!")
 
@@ -757,15 +971,54 @@ var _ = Describe("Linux containers", func() {
 			})
 
 			It("returns the error", func() {
-				err := container.CopyIn("/src", "/dst")
-				Expect(err).To(Equal(nastyError))
+				err := container.CopyIn("/src", "/dst", "")
+				Expect(err).To(Equal(linux_backend.CopyFailedError{nastyError, ""}))
+			})
+
+		})
+
+		Context("when rsync fails with output", func() {
+			BeforeEach(func() {
+				fakeRunner.WhenRunning(
+					fake_command_runner.CommandSpec{
+						Path: "rsync",
+					}, func(cmd *exec.Cmd) error {
+						cmd.Stderr.Write([]byte("wsh: unknown user: bob\n"))
+						return errors.New("exit status 255")
+					},
+				)
+			})
+
+			It("includes the output in the error", func() {
+				err := container.CopyIn("/src", "/dst", "bob")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("copy failed: exit status 255: wsh: unknown user: bob"))
+			})
+		})
+
+		Context("when the user is not a plain name or id", func() {
+			It("returns an error without running rsync", func() {
+				for _, user := range []string{
+					"root --socket /depot/some-other-id/run/wshd.sock",
+					"--socket=/depot/some-other-id/run/wshd.sock",
+					"vcap'",
+				} {
+					err := container.CopyIn("/src", "/dst", user)
+					Expect(err).To(Equal(backend.InvalidUserError{User: user}))
+				}
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "rsync",
+					},
+				))
 			})
 		})
 	})
 
 	Describe("Copying out", func() {
-		It("rsyncs from vcap@container:/src to /dst", func() {
-			err := container.CopyOut("/src", "/dst", "")
+		It("rsyncs from /src in the container to /dst as vcap", func() {
+			err := container.CopyOut("/src", "/dst", "", "")
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(fakeRunner).To(HaveExecutedSerially(
@@ -773,20 +1026,42 @@ var _ = Describe("Linux containers", func() {
 					Path: "rsync",
 					Args: []string{
 						"-e",
-						"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --rsh",
+						"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --user vcap --rsh",
 						"-r",
 						"-p",
 						"--links",
-						"vcap@container:/src",
+						"container:/src",
 						"/dst",
 					},
 				},
 			))
 		})
 
+		Context("when a user is given", func() {
+			It("runs rsync in the container as that user", func() {
+				err := container.CopyOut("/src", "/dst", "", "some-service")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "rsync",
+						Args: []string{
+							"-e",
+							"/depot/some-id/bin/wsh --socket /depot/some-id/run/wshd.sock --user some-service --rsh",
+							"-r",
+							"-p",
+							"--links",
+							"container:/src",
+							"/dst",
+						},
+					},
+				))
+			})
+		})
+
 		Context("when an owner is given", func() {
 			It("chowns the files after rsyncing", func() {
-				err := container.CopyOut("/src", "/dst", "some-user")
+				err := container.CopyOut("/src", "/dst", "some-user", "")
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeRunner).To(HaveExecutedSerially(
@@ -801,6 +1076,19 @@ var _ = Describe("Linux containers", func() {
 			})
 		})
 
+		Context("when the owner is not a plain name or id", func() {
+			It("returns an error without copying", func() {
+				err := container.CopyOut("/src", "/dst", "--reference=/etc/shadow", "")
+				Expect(err).To(Equal(backend.InvalidUserError{User: "--reference=/etc/shadow"}))
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "rsync",
+					},
+				))
+			})
+		})
+
 		Context("when rsync fails", func() {
 			nastyError := errors.New("oh no!")
 
@@ -815,8 +1103,8 @@ var _ = Describe("Linux containers", func() {
 			})
 
 			It("returns the error", func() {
-				err := container.CopyOut("/src", "/dst", "")
-				Expect(err).To(Equal(nastyError))
+				err := container.CopyOut("/src", "/dst", "", "")
+				Expect(err).To(Equal(linux_backend.CopyFailedError{nastyError, ""}))
 			})
 		})
 
@@ -834,7 +1122,7 @@ var _ = Describe("Linux containers", func() {
 			})
 
 			It("returns the error", func() {
-				err := container.CopyOut("/src", "/dst", "some-user")
+				err := container.CopyOut("/src", "/dst", "some-user", "")
 				Expect(err).To(Equal(nastyError))
 			})
 		})
@@ -875,6 +1163,7 @@ var _ = Describe("Linux containers", func() {
 						"/depot/some-id/bin/wsh",
 						"--socket", "/depot/some-id/run/wshd.sock",
 						"--user", "vcap",
+						"--pid-file", "pid",
 						"/bin/bash",
 					},
 					Stdin: "/some/script",
@@ -899,6 +1188,105 @@ var _ = Describe("Linux containers", func() {
 			))
 		})
 
+		Context("with a user", func() {
+			It("runs wsh as that user, regardless of privilege", func() {
+				setupSuccessfulSpawn()
+
+				processID, _, err := container.Run(backend.ProcessSpec{
+					Script:     "/some/script",
+					User:       "1000:1000",
+					Privileged: true,
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(fakeRunner).Should(HaveStartedExecuting(
+					fake_command_runner.CommandSpec{
+						Path: "/depot/some-id/bin/iomux-spawn",
+						Args: []string{
+							fmt.Sprintf("/depot/some-id/processes/%d", processID),
+							"/depot/some-id/bin/wsh",
+							"--socket", "/depot/some-id/run/wshd.sock",
+							"--user", "1000:1000",
+							"--pid-file", "pid",
+							"/bin/bash",
+						},
+						Stdin: "/some/script",
+					},
+				))
+			})
+		})
+
+		Context("with a path and arguments", func() {
+			It("runs the executable directly via wsh with the given environment and working directory", func() {
+				setupSuccessfulSpawn()
+
+				processID, _, err := container.Run(backend.ProcessSpec{
+					Path: "/some/executable",
+					Args: []string{"arg1", "--arg2", "arg 3"},
+					Env:  []string{"FOO=bar", "BAZ=a b"},
+					Dir:  "/some/dir",
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(fakeRunner).Should(HaveStartedExecuting(
+					fake_command_runner.CommandSpec{
+						Path: "/depot/some-id/bin/iomux-spawn",
+						Args: []string{
+							fmt.Sprintf("/depot/some-id/processes/%d", processID),
+							"/depot/some-id/bin/wsh",
+							"--socket", "/depot/some-id/run/wshd.sock",
+							"--user", "vcap",
+							"--pid-file", "pid",
+							"--env", "FOO=bar",
+							"--env", "BAZ=a b",
+							"--dir", "/some/dir",
+							"/some/executable",
+							"arg1", "--arg2", "arg 3",
+						},
+					},
+				))
+			})
+		})
+
+		Context("with a tty", func() {
+			It("runs wsh with a tty of the given window size and a fifo for resizing it", func() {
+				setupSuccessfulSpawn()
+
+				processID, _, err := container.Run(backend.ProcessSpec{
+					Script: "/some/script",
+					TTY: &backend.TTYSpec{
+						WindowSize: &backend.WindowSize{
+							Columns: 80,
+							Rows:    24,
+						},
+					},
+				})
+
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(fakeRunner).Should(HaveStartedExecuting(
+					fake_command_runner.CommandSpec{
+						Path: "/depot/some-id/bin/iomux-spawn",
+						Args: []string{
+							fmt.Sprintf("/depot/some-id/processes/%d", processID),
+							"/depot/some-id/bin/wsh",
+							"--socket", "/depot/some-id/run/wshd.sock",
+							"--user", "vcap",
+							"--pid-file", "pid",
+							"--tty",
+							"--winsize-fifo", "winsize",
+							"--columns", "80",
+							"--rows", "24",
+							"/bin/bash",
+						},
+						Stdin: "/some/script",
+					},
+				))
+			})
+		})
+
 		Describe("streaming", func() {
 			BeforeEach(func() {
 				fakeRunner.WhenRunning(
@@ -951,6 +1339,26 @@ var _ = Describe("Linux containers", func() {
 
 				close(done)
 			}, 5.0)
+
+			It("publishes a process exited event", func(done Done) {
+				setupSuccessfulSpawn()
+
+				subscription := eventHub.Subscribe(nil, []backend.EventType{backend.EventTypeProcessExited})
+
+				processID, _, err := container.Run(backend.ProcessSpec{
+					Script: "/some/script",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				event := <-subscription.Events()
+				Expect(event.Handle).To(Equal("some-handle"))
+				Expect(event.Details).To(Equal(map[string]string{
+					"process_id":  fmt.Sprintf("%d", processID),
+					"exit_status": "42",
+				}))
+
+				close(done)
+			}, 5.0)
 		})
 
 		Context("when not all rlimits are set", func() {
@@ -981,6 +1389,7 @@ var _ = Describe("Linux containers", func() {
 							"/depot/some-id/bin/wsh",
 							"--socket", "/depot/some-id/run/wshd.sock",
 							"--user", "vcap",
+							"--pid-file", "pid",
 							"/bin/bash",
 						},
 						Stdin: "/some/script",
@@ -1034,6 +1443,7 @@ var _ = Describe("Linux containers", func() {
 							"/depot/some-id/bin/wsh",
 							"--socket", "/depot/some-id/run/wshd.sock",
 							"--user", "root",
+							"--pid-file", "pid",
 							"/bin/bash",
 						},
 						Stdin: "/some/script",
@@ -1101,7 +1511,7 @@ var _ = Describe("Linux containers", func() {
 				})
 				Expect(err).ToNot(HaveOccurred())
 
-				attachStreamChannel, err := container.Attach(processID)
+				attachStreamChannel, err := container.Attach(processID, backend.ProcessStreamOffsets{})
 				Expect(err).ToNot(HaveOccurred())
 
 				runChunk := <-runningStreamChannel
@@ -1131,7 +1541,7 @@ var _ = Describe("Linux containers", func() {
 		})
 
 		Context("a process that has already completed", func() {
-			It("returns an error", func(done Done) {
+			It("yields its exit status and closes the channel", func(done Done) {
 				processID, payloads, err := container.Run(backend.ProcessSpec{
 					Script: "/some/script",
 				})
@@ -1141,8 +1551,15 @@ var _ = Describe("Linux containers", func() {
 					// noop
 				}
 
-				_, err = container.Attach(processID)
-				Expect(err).To(HaveOccurred())
+				attachPayloads, err := container.Attach(processID, backend.ProcessStreamOffsets{})
+				Expect(err).ToNot(HaveOccurred())
+
+				chunk := <-attachPayloads
+				Expect(chunk.ExitStatus).ToNot(BeNil())
+				Expect(*chunk.ExitStatus).To(Equal(uint32(255)))
+
+				_, ok := <-attachPayloads
+				Expect(ok).To(BeFalse(), "channel is not closed")
 
 				close(done)
 			}, 1.0)
@@ -1150,27 +1567,564 @@ var _ = Describe("Linux containers", func() {
 
 		Context("an unknown process", func() {
 			It("returns an error", func() {
-				_, err := container.Attach(42)
+				_, err := container.Attach(42, backend.ProcessStreamOffsets{})
 				Expect(err).To(HaveOccurred())
 			})
 		})
 	})
 
-	Describe("Limiting bandwidth", func() {
-		limits := backend.BandwidthLimits{
-			RateInBytesPerSecond:      128,
-			BurstRateInBytesPerSecond: 256,
-		}
+	Describe("Supervising processes", func() {
+		var exitStatuses chan int
 
-		It("sets the limit via the bandwidth manager with the new limits", func() {
-			err := container.LimitBandwidth(limits)
-			Expect(err).ToNot(HaveOccurred())
+		BeforeEach(func() {
+			statuses := make(chan int, 10)
+			exitStatuses = statuses
 
-			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(limits))
-		})
+			setupSuccessfulSpawn()
 
-		Context("when setting the limit fails", func() {
-			disaster := errors.New("oh no!")
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: "/depot/some-id/bin/iomux-link",
+				},
+				func(cmd *exec.Cmd) error {
+					exitStatus := <-statuses
+
+					dummyCmd := exec.Command("/bin/bash", "-c", fmt.Sprintf("exit %d", exitStatus))
+					dummyCmd.Run()
+
+					cmd.ProcessState = dummyCmd.ProcessState
+
+					return nil
+				},
+			)
+		})
+
+		AfterEach(func() {
+			// stop supervisors from outliving the test
+			container.Stop(false)
+		})
+
+		supervisedProcess := func() backend.SupervisedProcessInfo {
+			info, err := container.Info()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.SupervisedProcesses).To(HaveLen(1))
+
+			return info.SupervisedProcesses[0]
+		}
+
+		restarts := func() int {
+			return supervisedProcess().Restarts
+		}
+
+		It("runs the process and reports it in the container's info", func() {
+			policy := backend.RestartPolicy{Mode: backend.RestartNever}
+
+			processID, err := container.Supervise(backend.ProcessSpec{
+				Script: "/some/script",
+			}, policy)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(supervisedProcess()).To(Equal(backend.SupervisedProcessInfo{
+				ID:        processID,
+				Policy:    policy,
+				ProcessID: processID,
+				Running:   true,
+			}))
+
+			exitStatuses <- 0
+		})
+
+		Context("with the on-failure policy", func() {
+			It("restarts the process until it succeeds", func() {
+				exitStatuses <- 1
+				exitStatuses <- 2
+				exitStatuses <- 0
+
+				_, err := container.Supervise(backend.ProcessSpec{
+					Script: "/some/script",
+				}, backend.RestartPolicy{
+					Mode:    backend.RestartOnFailure,
+					Backoff: 10 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(restarts).Should(Equal(2))
+
+				Eventually(func() bool {
+					return supervisedProcess().Running
+				}).Should(BeFalse())
+
+				info := supervisedProcess()
+				Expect(info.ProcessID).To(Equal(uint32(2)))
+				Expect(*info.LastExitStatus).To(Equal(uint32(0)))
+
+				Consistently(restarts, 0.1).Should(Equal(2))
+			})
+
+			It("gives up after the maximum number of restarts", func() {
+				for i := 0; i < 5; i++ {
+					exitStatuses <- 1
+				}
+
+				_, err := container.Supervise(backend.ProcessSpec{
+					Script: "/some/script",
+				}, backend.RestartPolicy{
+					Mode:        backend.RestartOnFailure,
+					MaxRestarts: 2,
+					Backoff:     10 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(restarts).Should(Equal(2))
+				Consistently(restarts, 0.1).Should(Equal(2))
+			})
+		})
+
+		Context("with the never policy", func() {
+			It("does not restart the process", func() {
+				exitStatuses <- 1
+
+				_, err := container.Supervise(backend.ProcessSpec{
+					Script: "/some/script",
+				}, backend.RestartPolicy{
+					Mode:    backend.RestartNever,
+					Backoff: 10 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(func() *uint32 {
+					return supervisedProcess().LastExitStatus
+				}).ShouldNot(BeNil())
+
+				Consistently(restarts, 0.1).Should(Equal(0))
+			})
+		})
+
+		Context("when the container is stopped", func() {
+			It("stops restarting the process", func() {
+				exitStatuses <- 0
+
+				_, err := container.Supervise(backend.ProcessSpec{
+					Script: "/some/script",
+				}, backend.RestartPolicy{
+					Mode:    backend.RestartAlways,
+					Backoff: 200 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.Stop(false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(restarts, 0.4).Should(Equal(0))
+			})
+		})
+
+		It("saves the supervision state in the snapshot, and resumes it when restored", func() {
+			exitStatuses <- 1
+
+			_, err := container.Supervise(backend.ProcessSpec{
+				Script: "/some/script",
+			}, backend.RestartPolicy{
+				Mode:    backend.RestartNever,
+				Backoff: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				return supervisedProcess().Running
+			}).Should(BeFalse())
+
+			out := new(bytes.Buffer)
+
+			err = container.Snapshot(out)
+			Expect(err).ToNot(HaveOccurred())
+
+			var snapshot linux_backend.ContainerSnapshot
+
+			err = json.NewDecoder(out).Decode(&snapshot)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(snapshot.Supervisors).To(HaveLen(1))
+
+			supervisorSnapshot := snapshot.Supervisors[0]
+			Expect(supervisorSnapshot.Spec.Script).To(Equal("/some/script"))
+			Expect(supervisorSnapshot.Done).To(BeTrue())
+			Expect(*supervisorSnapshot.LastExitStatus).To(Equal(uint32(1)))
+
+			// pretend the server went away while the supervisor was backing off
+			supervisorSnapshot.Done = false
+			supervisorSnapshot.Policy.Mode = backend.RestartOnFailure
+
+			exitStatuses <- 0
+
+			restoredContainer := linux_backend.NewLinuxContainer(
+				"some-id",
+				"some-handle",
+				"/depot/some-id",
+				"/some/rootfs/path",
+				1*time.Second,
+				nil,
+				0,
+				containerResources,
+				fakePortPool,
+				fakeRunner,
+				fakeCgroups,
+				fakeQuotaManager,
+				fakeBandwidthManager,
+				eventHub,
+			)
+
+			err = restoredContainer.Restore(linux_backend.ContainerSnapshot{
+				State:       "active",
+				Events:      []string{},
+				Supervisors: []linux_backend.SupervisorSnapshot{supervisorSnapshot},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				info, err := restoredContainer.Info()
+				Expect(err).ToNot(HaveOccurred())
+
+				return info.SupervisedProcesses[0].Restarts
+			}).Should(Equal(1))
+		})
+	})
+
+	Describe("Health checks", func() {
+		var setCheckExitStatus func(int)
+
+		BeforeEach(func() {
+			checkExitStatus := 0
+			checkExitStatusMutex := &sync.Mutex{}
+
+			setCheckExitStatus = func(exitStatus int) {
+				checkExitStatusMutex.Lock()
+				checkExitStatus = exitStatus
+				checkExitStatusMutex.Unlock()
+			}
+
+			setupSuccessfulSpawn()
+
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: "/depot/some-id/bin/iomux-link",
+				},
+				func(cmd *exec.Cmd) error {
+					checkExitStatusMutex.Lock()
+					exitStatus := checkExitStatus
+					checkExitStatusMutex.Unlock()
+
+					dummyCmd := exec.Command("/bin/bash", "-c", fmt.Sprintf("exit %d", exitStatus))
+					dummyCmd.Run()
+
+					cmd.ProcessState = dummyCmd.ProcessState
+
+					return nil
+				},
+			)
+		})
+
+		AfterEach(func() {
+			// stop health checks from outliving the test
+			container.Stop(false)
+		})
+
+		healthCheck := func() backend.HealthCheckInfo {
+			info, err := container.Info()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.HealthCheck).ToNot(BeNil())
+
+			return *info.HealthCheck
+		}
+
+		healthStatus := func() backend.HealthStatus {
+			return healthCheck().Status
+		}
+
+		It("reports an unknown status until the first check has run", func() {
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: time.Second,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			info := healthCheck()
+			Expect(info.Status).To(Equal(backend.HealthStatusUnknown))
+			Expect(info.Spec.Process.Script).To(Equal("/some/check"))
+			Expect(info.LastCheckedAt.IsZero()).To(BeTrue())
+		})
+
+		It("periodically runs the check in the container", func() {
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() int {
+				count := 0
+
+				for _, cmd := range fakeRunner.StartedCommands() {
+					if cmd.Path == "/depot/some-id/bin/iomux-spawn" {
+						count++
+					}
+				}
+
+				return count
+			}).Should(BeNumerically(">=", 2))
+		})
+
+		It("becomes healthy when a check passes, registering an event", func() {
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+			Expect(healthCheck().LastCheckedAt.IsZero()).To(BeFalse())
+
+			Expect(container.Events()).To(Equal([]string{"healthy"}))
+		})
+
+		It("becomes unhealthy once the failure threshold is reached, and recovers", func() {
+			setCheckExitStatus(1)
+
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval:         10 * time.Millisecond,
+				FailureThreshold: 3,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusUnhealthy))
+			Expect(healthCheck().ConsecutiveFailures).To(BeNumerically(">=", 3))
+
+			setCheckExitStatus(0)
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+			Expect(healthCheck().ConsecutiveFailures).To(Equal(0))
+
+			Expect(container.Events()).To(Equal([]string{"unhealthy", "healthy"}))
+		})
+
+		It("does not keep the checks among the container's processes", func() {
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+
+			finishedProcesses := func() int {
+				processes, err := container.Processes()
+				Expect(err).ToNot(HaveOccurred())
+
+				finished := 0
+
+				for _, process := range processes {
+					if process.ExitStatus != nil {
+						finished++
+					}
+				}
+
+				return finished
+			}
+
+			Consistently(finishedProcesses, 0.3).Should(Equal(0))
+		})
+
+		It("remembers only the most recent events", func() {
+			events := []string{}
+			for i := 0; i < linux_backend.MaxEvents; i++ {
+				events = append(events, "unhealthy")
+			}
+
+			err := container.Restore(linux_backend.ContainerSnapshot{
+				State:  "active",
+				Events: events,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+
+			Expect(container.Events()).To(HaveLen(linux_backend.MaxEvents))
+			Expect(container.Events()[linux_backend.MaxEvents-1]).To(Equal("healthy"))
+		})
+
+		Context("when the check is set again", func() {
+			It("replaces the check instead of running both", func() {
+				err := container.SetHealthCheck(backend.HealthCheckSpec{
+					Process: backend.ProcessSpec{
+						Script: "/some/check",
+					},
+					Interval: 10 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+
+				err = container.SetHealthCheck(backend.HealthCheckSpec{
+					Process: backend.ProcessSpec{
+						Script: "/other/check",
+					},
+					Interval: time.Hour,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				setCheckExitStatus(1)
+
+				Expect(healthCheck().Spec.Process.Script).To(Equal("/other/check"))
+				Consistently(healthStatus, 0.3).Should(Equal(backend.HealthStatusUnknown))
+				Expect(container.Events()).To(Equal([]string{"healthy"}))
+			})
+		})
+
+		Context("when the check is removed", func() {
+			It("stops running it and no longer reports it", func() {
+				err := container.SetHealthCheck(backend.HealthCheckSpec{
+					Process: backend.ProcessSpec{
+						Script: "/some/check",
+					},
+					Interval: 10 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+
+				err = container.RemoveHealthCheck()
+				Expect(err).ToNot(HaveOccurred())
+
+				setCheckExitStatus(1)
+
+				info, err := container.Info()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.HealthCheck).To(BeNil())
+
+				Consistently(container.Events, 0.3).Should(Equal([]string{"healthy"}))
+
+				out := new(bytes.Buffer)
+
+				err = container.Snapshot(out)
+				Expect(err).ToNot(HaveOccurred())
+
+				var snapshot linux_backend.ContainerSnapshot
+
+				err = json.NewDecoder(out).Decode(&snapshot)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(snapshot.HealthCheck).To(BeNil())
+			})
+		})
+
+		Context("when the container is stopped", func() {
+			It("stops running the check", func() {
+				err := container.SetHealthCheck(backend.HealthCheckSpec{
+					Process: backend.ProcessSpec{
+						Script: "/some/check",
+					},
+					Interval: 100 * time.Millisecond,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.Stop(false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(healthStatus, 0.3).Should(Equal(backend.HealthStatusUnknown))
+			})
+		})
+
+		It("saves the health check in the snapshot, and resumes it when restored", func() {
+			err := container.SetHealthCheck(backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script: "/some/check",
+				},
+				Interval: 10 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(healthStatus).Should(Equal(backend.HealthStatusHealthy))
+
+			out := new(bytes.Buffer)
+
+			err = container.Snapshot(out)
+			Expect(err).ToNot(HaveOccurred())
+
+			var snapshot linux_backend.ContainerSnapshot
+
+			err = json.NewDecoder(out).Decode(&snapshot)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(snapshot.HealthCheck).ToNot(BeNil())
+			Expect(snapshot.HealthCheck.Spec.Process.Script).To(Equal("/some/check"))
+			Expect(snapshot.HealthCheck.Status).To(Equal(backend.HealthStatusHealthy))
+
+			setCheckExitStatus(1)
+
+			restoredContainer := linux_backend.NewLinuxContainer(
+				"some-id",
+				"some-handle",
+				"/depot/some-id",
+				"/some/rootfs/path",
+				1*time.Second,
+				nil,
+				0,
+				containerResources,
+				fakePortPool,
+				fakeRunner,
+				fakeCgroups,
+				fakeQuotaManager,
+				fakeBandwidthManager,
+				eventHub,
+			)
+
+			err = restoredContainer.Restore(linux_backend.ContainerSnapshot{
+				State:       "active",
+				Events:      []string{"healthy"},
+				HealthCheck: snapshot.HealthCheck,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			defer restoredContainer.Stop(false)
+
+			Eventually(restoredContainer.Events).Should(Equal([]string{"healthy", "unhealthy"}))
+		})
+	})
+
+	Describe("Limiting bandwidth", func() {
+		limits := backend.BandwidthLimits{
+			RateInBytesPerSecond:      128,
+			BurstRateInBytesPerSecond: 256,
+		}
+
+		It("sets the limit via the bandwidth manager with the new limits", func() {
+			err := container.LimitBandwidth(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(limits))
+		})
+
+		Context("when setting the limit fails", func() {
+			disaster := errors.New("oh no!")
 
 			BeforeEach(func() {
 				fakeBandwidthManager.SetLimitsError = disaster
@@ -1331,6 +2285,23 @@ var _ = Describe("Linux containers", func() {
 					return container.Events()
 				}).Should(ContainElement("out of memory"))
 			})
+
+			It("publishes an 'out of memory' event", func() {
+				subscription := eventHub.Subscribe(nil, nil)
+
+				limits := backend.MemoryLimits{
+					LimitInBytes: 102400,
+				}
+
+				err := container.LimitMemory(limits)
+				Expect(err).ToNot(HaveOccurred())
+
+				var event backend.ContainerEvent
+				Eventually(subscription.Events()).Should(Receive(&event))
+
+				Expect(event.Type).To(Equal(backend.EventTypeOutOfMemory))
+				Expect(event.Handle).To(Equal("some-handle"))
+			})
 		})
 
 		Context("when setting memory.memsw.limit_in_bytes fails", func() {
@@ -1788,6 +2759,15 @@ var _ = Describe("Linux containers", func() {
 			Expect(info.Events).To(Equal([]string{}))
 		})
 
+		It("returns the container's properties", func() {
+			info, err := container.Info()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(info.Properties).To(Equal(backend.Properties{
+				"property-name": "property-value",
+			}))
+		})
+
 		It("returns the container's network info", func() {
 			info, err := container.Info()
 			Expect(err).ToNot(HaveOccurred())
@@ -2003,6 +2983,25 @@ system 2
 					Expect(err).To(Equal(disaster))
 				})
 			})
+
+			Context("when the disk usage is already known", func() {
+				BeforeEach(func() {
+					fakeQuotaManager.GetUsageError = errors.New("should not be read")
+				})
+
+				It("is returned in the response", func() {
+					info, err := container.InfoWithDiskStat(backend.ContainerDiskStat{
+						BytesUsed:  3,
+						InodesUsed: 4,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(info.DiskStat).To(Equal(backend.ContainerDiskStat{
+						BytesUsed:  3,
+						InodesUsed: 4,
+					}))
+				})
+			})
 		})
 
 		Describe("bandwidth info", func() {
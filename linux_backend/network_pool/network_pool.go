@@ -5,6 +5,7 @@ import (
 	"net"
 	"sync"
 
+	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/network"
 )
 
@@ -28,6 +29,10 @@ func (e PoolExhaustedError) Error() string {
 	return "network pool is exhausted"
 }
 
+func (e PoolExhaustedError) Code() backend.ErrorCode {
+	return backend.ErrorCodeCapacityExhausted
+}
+
 type NetworkTakenError struct {
 	Network *network.Network
 }
@@ -36,6 +41,10 @@ func (e NetworkTakenError) Error() string {
 	return fmt.Sprintf("network already acquired: %s", e.Network.String())
 }
 
+func (e NetworkTakenError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
 func New(ipNet *net.IPNet) *RealNetworkPool {
 	pool := []*network.Network{}
 
@@ -3,6 +3,8 @@ package port_pool
 import (
 	"fmt"
 	"sync"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
 )
 
 type PortPool struct {
@@ -19,6 +21,10 @@ func (e PoolExhaustedError) Error() string {
 	return "port pool is exhausted"
 }
 
+func (e PoolExhaustedError) Code() backend.ErrorCode {
+	return backend.ErrorCodeCapacityExhausted
+}
+
 type PortTakenError struct {
 	Port uint32
 }
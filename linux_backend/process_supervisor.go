@@ -0,0 +1,240 @@
+package linux_backend
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+const defaultRestartBackoff = time.Second
+const maxRestartBackoff = time.Minute
+
+// processSupervisor runs a process again, as its restart policy says, each
+// time it exits.
+type processSupervisor struct {
+	id     uint32
+	spec   backend.ProcessSpec
+	policy backend.RestartPolicy
+
+	container *LinuxContainer
+
+	processID      uint32
+	running        bool
+	restarts       int
+	lastExitStatus *uint32
+
+	// done is set once the supervisor will not restart the process again;
+	// stopped is set if that is because it was told to stop
+	done    bool
+	stopped bool
+
+	stopping chan struct{}
+	stopOnce *sync.Once
+
+	mutex *sync.RWMutex
+}
+
+func newProcessSupervisor(
+	container *LinuxContainer,
+	spec backend.ProcessSpec,
+	policy backend.RestartPolicy,
+	processID uint32,
+) *processSupervisor {
+	return &processSupervisor{
+		id:     processID,
+		spec:   spec,
+		policy: policy,
+
+		container: container,
+
+		processID: processID,
+		running:   true,
+
+		stopping: make(chan struct{}),
+		stopOnce: &sync.Once{},
+
+		mutex: &sync.RWMutex{},
+	}
+}
+
+func restoreProcessSupervisor(container *LinuxContainer, snapshot SupervisorSnapshot) *processSupervisor {
+	supervisor := newProcessSupervisor(container, snapshot.Spec, snapshot.Policy, snapshot.ID)
+
+	supervisor.processID = snapshot.ProcessID
+	supervisor.running = snapshot.Running
+	supervisor.restarts = snapshot.Restarts
+	supervisor.lastExitStatus = snapshot.LastExitStatus
+	supervisor.done = snapshot.Done
+	supervisor.stopped = snapshot.Stopped
+
+	return supervisor
+}
+
+func (s *processSupervisor) Supervise() {
+	for {
+		succeeded := s.waitForExit()
+
+		if !s.shouldRestart(succeeded) {
+			s.mutex.Lock()
+			s.done = true
+			s.mutex.Unlock()
+
+			return
+		}
+
+		if !s.backOff() {
+			return
+		}
+
+		s.restart()
+	}
+}
+
+func (s *processSupervisor) Stop() {
+	s.stopOnce.Do(func() {
+		s.mutex.Lock()
+		s.stopped = true
+		s.mutex.Unlock()
+
+		close(s.stopping)
+	})
+}
+
+func (s *processSupervisor) Info() backend.SupervisedProcessInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return backend.SupervisedProcessInfo{
+		ID:     s.id,
+		Policy: s.policy,
+
+		ProcessID: s.processID,
+		Running:   s.running,
+
+		Restarts:       s.restarts,
+		LastExitStatus: s.lastExitStatus,
+	}
+}
+
+func (s *processSupervisor) Snapshot() SupervisorSnapshot {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return SupervisorSnapshot{
+		ID:     s.id,
+		Spec:   s.spec,
+		Policy: s.policy,
+
+		ProcessID:      s.processID,
+		Running:        s.running,
+		Restarts:       s.restarts,
+		LastExitStatus: s.lastExitStatus,
+
+		Done:    s.done,
+		Stopped: s.stopped,
+	}
+}
+
+func (s *processSupervisor) waitForExit() bool {
+	s.mutex.RLock()
+	processID := s.processID
+	running := s.running
+	s.mutex.RUnlock()
+
+	if running {
+		exitStatus, err := s.container.processTracker.Wait(processID, 0)
+
+		s.mutex.Lock()
+
+		s.running = false
+
+		if err == nil {
+			s.lastExitStatus = &exitStatus
+		} else {
+			log.Println(s.container.id, "lost track of supervised process", processID, "-", err)
+			s.lastExitStatus = nil
+		}
+
+		s.mutex.Unlock()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.lastExitStatus != nil && *s.lastExitStatus == 0
+}
+
+func (s *processSupervisor) shouldRestart(succeeded bool) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.stopped {
+		return false
+	}
+
+	if s.policy.MaxRestarts > 0 && s.restarts >= s.policy.MaxRestarts {
+		return false
+	}
+
+	switch s.policy.Mode {
+	case backend.RestartOnFailure:
+		return !succeeded
+	case backend.RestartAlways:
+		return true
+	default:
+		return false
+	}
+}
+
+// backOff waits before the next restart, returning false if the supervisor
+// was stopped meanwhile.
+func (s *processSupervisor) backOff() bool {
+	s.mutex.RLock()
+	restarts := s.restarts
+	s.mutex.RUnlock()
+
+	delay := s.policy.Backoff
+	if delay == 0 {
+		delay = defaultRestartBackoff
+	}
+
+	for i := 0; i < restarts && delay < maxRestartBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRestartBackoff {
+		delay = maxRestartBackoff
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopping:
+		return false
+	}
+}
+
+func (s *processSupervisor) restart() {
+	processID, stream, err := s.container.Run(s.spec)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.restarts++
+
+	if err != nil {
+		log.Println(s.container.id, "failed to restart supervised process", s.id, "-", err)
+		s.lastExitStatus = nil
+		return
+	}
+
+	go backend.DrainProcessStream(stream)
+
+	s.processID = processID
+	s.running = true
+}
@@ -19,10 +19,7 @@ func newNamedStream(process *Process, source backend.ProcessStreamSource) *named
 func (s *namedStream) Write(data []byte) (int, error) {
 	myBytes := make([]byte, len(data))
 	copy(myBytes, data)
-	s.process.sendToStreams(backend.ProcessStream{
-		Source: s.source,
-		Data:   myBytes,
-	})
+	s.process.emit(s.source, myBytes)
 
 	return len(data), nil
 }
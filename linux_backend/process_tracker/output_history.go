@@ -0,0 +1,310 @@
+package process_tracker
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+// outputHistory keeps the most recent output a process wrote to one of its
+// streams, so that clients attaching later can resume from an offset.
+//
+// The history is a file starting with the offset of its first byte (8 bytes,
+// big-endian) followed by the output itself. Once it holds twice its limit it
+// is rewritten to hold only the last limit bytes; the rewrite replaces the
+// file atomically, so readers that already opened it are unaffected.
+//
+// outputHistory is not safe for concurrent use; Process guards it with its
+// streams lock.
+type outputHistory struct {
+	path  string
+	limit uint64
+
+	// start and end are the offsets of the first byte retained and of the
+	// next byte to be written
+	start uint64
+	end   uint64
+
+	file *os.File
+}
+
+const outputHistoryHeaderSize = 8
+
+func newOutputHistory(path string, limit uint64) *outputHistory {
+	h := &outputHistory{
+		path:  path,
+		limit: limit,
+	}
+
+	if limit == 0 {
+		return h
+	}
+
+	// pick up where a previous server left off
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() < outputHistoryHeaderSize {
+		return h
+	}
+
+	err = binary.Read(file, binary.BigEndian, &h.start)
+	if err != nil {
+		return h
+	}
+
+	h.end = h.start + uint64(info.Size()-outputHistoryHeaderSize)
+
+	return h
+}
+
+// Append records data as the next output, returning its offset.
+func (h *outputHistory) Append(data []byte) uint64 {
+	offset := h.end
+
+	h.end += uint64(len(data))
+
+	if h.limit == 0 {
+		h.start = h.end
+		return offset
+	}
+
+	err := h.write(offset, data)
+	if err != nil {
+		// the history no longer matches the offsets; start afresh rather
+		// than replay the wrong bytes
+		h.discard()
+		return offset
+	}
+
+	if h.end-h.start >= 2*h.limit {
+		err := h.compact()
+		if err != nil {
+			h.discard()
+		}
+	}
+
+	return offset
+}
+
+// Replay returns a reader of the history from the given offset up to what
+// has been written so far, along with the offset it actually starts from,
+// which is later than requested if that output is no longer retained.
+func (h *outputHistory) Replay(from uint64) (io.ReadCloser, uint64, error) {
+	if from < h.start {
+		from = h.start
+	}
+
+	if from >= h.end {
+		return nil, h.end, nil
+	}
+
+	file, err := os.Open(h.path)
+	if err != nil {
+		return nil, h.end, err
+	}
+
+	_, err = file.Seek(int64(outputHistoryHeaderSize+from-h.start), 0)
+	if err != nil {
+		file.Close()
+		return nil, h.end, err
+	}
+
+	return readCloser{io.LimitReader(file, int64(h.end-from)), file}, from, nil
+}
+
+func (h *outputHistory) Close() error {
+	if h.file == nil {
+		return nil
+	}
+
+	err := h.file.Close()
+	h.file = nil
+
+	return err
+}
+
+func (h *outputHistory) write(offset uint64, data []byte) error {
+	if h.file == nil {
+		file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+
+		h.file = file
+
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		if info.Size() == 0 {
+			h.start = offset
+
+			err := binary.Write(file, binary.BigEndian, h.start)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := h.file.Write(data)
+
+	return err
+}
+
+func (h *outputHistory) compact() error {
+	h.Close()
+
+	current, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+
+	defer current.Close()
+
+	start := h.end - h.limit
+
+	_, err = current.Seek(int64(outputHistoryHeaderSize+start-h.start), 0)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := h.path + ".tmp"
+
+	compacted, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer compacted.Close()
+
+	err = binary.Write(compacted, binary.BigEndian, start)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(compacted, current, int64(h.limit))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, h.path)
+	if err != nil {
+		return err
+	}
+
+	h.start = start
+
+	return nil
+}
+
+func (h *outputHistory) discard() {
+	h.Close()
+	os.Remove(h.path)
+	h.start = h.end
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// historyReplay picks up one of a process's outputs from an offset, first
+// from its history and then from the live stream.
+type historyReplay struct {
+	source backend.ProcessStreamSource
+
+	// reader replays the history starting at offset; nil if there is none
+	reader io.ReadCloser
+	offset uint64
+
+	// next is the offset of the next byte to pass on; live output before it
+	// was either replayed already or not asked for
+	next uint64
+}
+
+const historyReplayChunkSize = 4096
+
+func streamReplays(replays []*historyReplay, live <-chan backend.ProcessStream, out chan<- backend.ProcessStream) {
+	defer close(out)
+
+	for _, replay := range replays {
+		replay.replayTo(out)
+	}
+
+	for chunk := range live {
+		if chunk.ExitStatus == nil {
+			for _, replay := range replays {
+				if replay.source == chunk.Source {
+					chunk = replay.trim(chunk)
+				}
+			}
+
+			if len(chunk.Data) == 0 {
+				continue
+			}
+		}
+
+		out <- chunk
+	}
+}
+
+func (r *historyReplay) replayTo(out chan<- backend.ProcessStream) {
+	if r.reader == nil {
+		return
+	}
+
+	defer r.reader.Close()
+
+	offset := r.offset
+
+	for {
+		buf := make([]byte, historyReplayChunkSize)
+
+		n, err := io.ReadFull(r.reader, buf)
+		if n > 0 {
+			out <- backend.ProcessStream{
+				Source: r.source,
+				Data:   buf[:n],
+				Offset: offset,
+			}
+
+			offset += uint64(n)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	// if the history was cut short the gap shows in the offsets
+	if offset < r.next {
+		r.next = offset
+	}
+}
+
+func (r *historyReplay) trim(chunk backend.ProcessStream) backend.ProcessStream {
+	end := chunk.Offset + uint64(len(chunk.Data))
+
+	if end <= r.next {
+		chunk.Data = nil
+		return chunk
+	}
+
+	if chunk.Offset < r.next {
+		chunk.Data = chunk.Data[r.next-chunk.Offset:]
+		chunk.Offset = r.next
+	}
+
+	r.next = end
+
+	return chunk
+}
@@ -3,19 +3,49 @@ package process_tracker
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner"
 )
 
+// WindowSizeFifo is the fifo, relative to a process's working directory,
+// through which a process running with a tty (i.e. wsh --tty) is told of
+// window size changes.
+const WindowSizeFifo = "winsize"
+
+// StdoutHistory and StderrHistory are the files, relative to a process's
+// working directory, that retain its recent output for clients attaching
+// from an offset.
+const (
+	StdoutHistory = "stdout.history"
+	StderrHistory = "stderr.history"
+)
+
+// DefaultTimeoutGracePeriod is how long a process that has run past its
+// timeout is usually given to exit after being sent TERM before it is sent
+// KILL.
+const DefaultTimeoutGracePeriod = 10 * time.Second
+
+// PIDFile is the file, relative to a process's working directory, in which
+// wsh --pid-file records the pid of the process it spawned in the container.
+const PIDFile = "pid"
+
 type Process struct {
 	ID uint32
 
+	command   string
+	startedAt time.Time
+	timeout   time.Duration
+
+	timeoutGracePeriod time.Duration
+
 	containerPath string
 	runner        command_runner.CommandRunner
 
@@ -28,7 +58,19 @@ type Process struct {
 	streams     []chan backend.ProcessStream
 	streamsLock *sync.RWMutex
 
-	completed bool
+	// guarded by streamsLock
+	stdoutHistory *outputHistory
+	stderrHistory *outputHistory
+
+	completed  bool
+	finishedAt time.Time
+	timedOut   bool
+	done       chan struct{}
+
+	stdin io.WriteCloser
+
+	// guarded by the tracker's processesMutex
+	forgotten bool
 
 	exitStatus uint32
 	stdout     *namedStream
@@ -38,11 +80,15 @@ type Process struct {
 func NewProcess(
 	id uint32,
 	containerPath string,
+	outputHistory uint64,
+	timeoutGracePeriod time.Duration,
 	runner command_runner.CommandRunner,
 ) *Process {
 	p := &Process{
 		ID: id,
 
+		timeoutGracePeriod: timeoutGracePeriod,
+
 		containerPath: containerPath,
 		runner:        runner,
 
@@ -51,11 +97,16 @@ func NewProcess(
 		waitingLinks:   &sync.Mutex{},
 		runningLink:    &sync.Once{},
 		completionLock: &sync.Mutex{},
+
+		done: make(chan struct{}),
 	}
 
 	p.stdout = newNamedStream(p, backend.ProcessStreamSourceStdout)
 	p.stderr = newNamedStream(p, backend.ProcessStreamSourceStderr)
 
+	p.stdoutHistory = newOutputHistory(path.Join(p.dir(), StdoutHistory), outputHistory)
+	p.stderrHistory = newOutputHistory(path.Join(p.dir(), StderrHistory), outputHistory)
+
 	return p
 }
 
@@ -64,7 +115,7 @@ func (p *Process) Spawn(cmd *exec.Cmd) (ready, active chan error) {
 	active = make(chan error, 1)
 
 	spawnPath := path.Join(p.containerPath, "bin", "iomux-spawn")
-	processDir := path.Join(p.containerPath, "processes", fmt.Sprintf("%d", p.ID))
+	processDir := p.dir()
 
 	mkdir := &exec.Cmd{
 		Path: "mkdir",
@@ -79,6 +130,7 @@ func (p *Process) Spawn(cmd *exec.Cmd) (ready, active chan error) {
 
 	spawn := &exec.Cmd{
 		Path:  spawnPath,
+		Dir:   processDir,
 		Stdin: cmd.Stdin,
 	}
 
@@ -146,13 +198,125 @@ func (p *Process) Unlink() error {
 	return nil
 }
 
-func (p *Process) Stream() chan backend.ProcessStream {
-	return p.registerStream()
+func (p *Process) SetWindowSize(size backend.WindowSize) error {
+	fifoPath := path.Join(p.dir(), WindowSizeFifo)
+
+	fifo, err := os.OpenFile(fifoPath, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NoTTYError{p.ID}
+		}
+
+		return err
+	}
+
+	defer fifo.Close()
+
+	_, err = fmt.Fprintf(fifo, "%d %d\n", size.Columns, size.Rows)
+
+	return err
+}
+
+func (p *Process) Signal(signal os.Signal) error {
+	sig, ok := signal.(syscall.Signal)
+	if !ok {
+		return UnsupportedSignalError{signal}
+	}
+
+	kill := &exec.Cmd{
+		Path: path.Join(p.containerPath, "bin", "wsh"),
+		Args: []string{
+			"--socket", path.Join(p.containerPath, "run", "wshd.sock"),
+			"--signal", fmt.Sprintf("%d", sig),
+			"--pid-file", path.Join(p.dir(), PIDFile),
+		},
+	}
+
+	return p.runner.Run(kill)
+}
+
+func (p *Process) Info() backend.ProcessInfo {
+	p.streamsLock.RLock()
+	defer p.streamsLock.RUnlock()
+
+	info := backend.ProcessInfo{
+		ID:        p.ID,
+		Command:   p.command,
+		StartedAt: p.startedAt,
+		Timeout:   p.timeout,
+	}
+
+	if p.completed {
+		exitStatus := p.exitStatus
+
+		info.FinishedAt = p.finishedAt
+		info.ExitStatus = &exitStatus
+		info.TimedOut = p.timedOut
+	}
+
+	return info
+}
+
+func (p *Process) Wait(timeout time.Duration) (uint32, error) {
+	var timedOut <-chan time.Time
+
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		timedOut = timer.C
+	}
+
+	select {
+	case <-p.done:
+		return p.exitStatus, nil
+	case <-timedOut:
+		return 0, WaitTimedOutError{p.ID}
+	}
+}
+
+// EnforceTimeout terminates the process if it is still running once its
+// timeout has passed since it started: first with TERM, then, if it has not
+// exited after its grace period, with KILL.
+func (p *Process) EnforceTimeout() {
+	if p.timeout == 0 {
+		return
+	}
+
+	timer := time.NewTimer(p.startedAt.Add(p.timeout).Sub(time.Now()))
+	defer timer.Stop()
+
+	select {
+	case <-p.done:
+		return
+	case <-timer.C:
+	}
+
+	p.streamsLock.Lock()
+	p.timedOut = true
+	p.streamsLock.Unlock()
+
+	p.Signal(syscall.SIGTERM)
+
+	grace := time.NewTimer(p.timeoutGracePeriod)
+	defer grace.Stop()
+
+	select {
+	case <-p.done:
+		return
+	case <-grace.C:
+	}
+
+	p.Signal(syscall.SIGKILL)
+}
+
+func (p *Process) Stream(offsets backend.ProcessStreamOffsets) chan backend.ProcessStream {
+	return p.registerStream(offsets)
 }
 
 func (p *Process) runLinker() {
 	linkPath := path.Join(p.containerPath, "bin", "iomux-link")
-	processDir := path.Join(p.containerPath, "processes", fmt.Sprintf("%d", p.ID))
+	processDir := p.dir()
 
 	p.link = &exec.Cmd{
 		Path:   linkPath,
@@ -175,47 +339,193 @@ func (p *Process) runLinker() {
 		exitStatus = uint32(p.link.ProcessState.Sys().(syscall.WaitStatus).ExitStatus())
 	}
 
+	p.streamsLock.Lock()
 	p.exitStatus = exitStatus
+	p.finishedAt = time.Now()
+	p.streamsLock.Unlock()
+
+	p.closeStdin()
 
 	p.closeStreams()
+
+	p.closeHistory()
+
+	close(p.done)
 }
 
-func (p *Process) registerStream() chan backend.ProcessStream {
+// restoreExit marks a process as having already exited, as recorded by a
+// previous server.
+func (p *Process) restoreExit(exitStatus uint32, finishedAt time.Time, timedOut bool) {
+	// there is nothing left to link to
+	p.runningLink.Do(func() {})
+
+	p.exitStatus = exitStatus
+	p.finishedAt = finishedAt
+	p.timedOut = timedOut
+	p.completed = true
+
+	close(p.done)
+}
+
+func (p *Process) closeStdin() {
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+}
+
+// removeDir removes the process's directory, and with it its output
+// history.
+func (p *Process) removeDir() {
+	p.closeHistory()
+	os.RemoveAll(p.dir())
+}
+
+func (p *Process) dir() string {
+	return path.Join(p.containerPath, "processes", fmt.Sprintf("%d", p.ID))
+}
+
+func (p *Process) registerStream(offsets backend.ProcessStreamOffsets) chan backend.ProcessStream {
 	p.streamsLock.Lock()
 	defer p.streamsLock.Unlock()
 
 	stream := make(chan backend.ProcessStream, 1000)
 
-	p.streams = append(p.streams, stream)
-
 	if p.completed {
-		defer p.closeStreams()
+		stream <- p.exitChunk()
+		close(stream)
+	} else {
+		p.streams = append(p.streams, stream)
 	}
 
-	return stream
+	if offsets.Stdout == nil && offsets.Stderr == nil {
+		return stream
+	}
+
+	stdout := p.replay(p.stdoutHistory, backend.ProcessStreamSourceStdout, offsets.Stdout)
+	stderr := p.replay(p.stderrHistory, backend.ProcessStreamSourceStderr, offsets.Stderr)
+
+	replayed := make(chan backend.ProcessStream, 1000)
+
+	go streamReplays([]*historyReplay{stdout, stderr}, stream, replayed)
+
+	return replayed
 }
 
-func (p *Process) sendToStreams(chunk backend.ProcessStream) {
-	p.streamsLock.RLock()
-	defer p.streamsLock.RUnlock()
+func (p *Process) replay(
+	history *outputHistory,
+	source backend.ProcessStreamSource,
+	from *uint64,
+) *historyReplay {
+	replay := &historyReplay{
+		source: source,
+		next:   history.end,
+	}
 
-	for _, stream := range p.streams {
-		select {
-		case stream <- chunk:
-		default:
-		}
+	if from == nil {
+		return replay
+	}
+
+	reader, start, err := history.Replay(*from)
+	if err == nil {
+		replay.reader = reader
+		replay.offset = start
+	}
+
+	if *from > replay.next {
+		replay.next = *from
 	}
+
+	return replay
 }
 
-func (p *Process) closeStreams() {
-	p.streamsLock.RLock()
-	defer p.streamsLock.RUnlock()
+func (p *Process) emit(source backend.ProcessStreamSource, data []byte) {
+	p.streamsLock.Lock()
 
-	for _, stream := range p.streams {
-		stream <- backend.ProcessStream{ExitStatus: &(p.exitStatus)}
-		close(stream)
+	var offset uint64
+
+	switch source {
+	case backend.ProcessStreamSourceStdout:
+		offset = p.stdoutHistory.Append(data)
+	case backend.ProcessStreamSourceStderr:
+		offset = p.stderrHistory.Append(data)
 	}
 
+	streams := make([]chan backend.ProcessStream, len(p.streams))
+	copy(streams, p.streams)
+
+	p.streamsLock.Unlock()
+
+	p.sendToStreams(streams, backend.ProcessStream{
+		Source: source,
+		Data:   data,
+		Offset: offset,
+	})
+}
+
+func (p *Process) sendToStreams(streams []chan backend.ProcessStream, chunk backend.ProcessStream) {
+	// block until every stream has taken the chunk rather than dropping it;
+	// this holds up iomux-link, which in turn holds up iomux-spawn from
+	// reading more of the process's output until its clients catch up
+	for _, stream := range streams {
+		stream <- chunk
+	}
+}
+
+func (p *Process) closeStreams() {
+	p.streamsLock.Lock()
+
+	streams := p.streams
+	exit := p.exitChunk()
+
 	p.streams = nil
 	p.completed = true
+
+	p.streamsLock.Unlock()
+
+	// as with output, wait for slow clients without holding the lock, so
+	// that others can still attach in the meantime
+	for _, stream := range streams {
+		stream <- exit
+		close(stream)
+	}
+}
+
+// unregisterStream stops sending to a stream that nothing will read.
+func (p *Process) unregisterStream(stream chan backend.ProcessStream) {
+	p.streamsLock.Lock()
+
+	for i, registered := range p.streams {
+		if registered == stream {
+			p.streams = append(p.streams[:i], p.streams[i+1:]...)
+			break
+		}
+	}
+
+	p.streamsLock.Unlock()
+
+	// output already on its way may still be sent to it
+	go func() {
+		for {
+			select {
+			case <-stream:
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+func (p *Process) exitChunk() backend.ProcessStream {
+	return backend.ProcessStream{
+		ExitStatus: &(p.exitStatus),
+		TimedOut:   p.timedOut,
+	}
+}
+
+func (p *Process) closeHistory() {
+	p.streamsLock.Lock()
+	defer p.streamsLock.Unlock()
+
+	p.stdoutHistory.Close()
+	p.stderrHistory.Close()
 }
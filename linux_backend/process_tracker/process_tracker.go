@@ -2,20 +2,34 @@ package process_tracker
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner"
 )
 
+// MaxFinishedProcesses is how many finished processes a tracker remembers,
+// so that clients can still learn how they exited.
+const MaxFinishedProcesses = 100
+
 type ProcessTracker struct {
-	containerPath string
-	runner        command_runner.CommandRunner
+	containerPath      string
+	outputHistory      uint64
+	timeoutGracePeriod time.Duration
+	runner             command_runner.CommandRunner
 
 	processes      map[uint32]*Process
 	nextProcessID  uint32
 	processesMutex *sync.RWMutex
+
+	// finished processes, oldest first; guarded by processesMutex
+	finished []*Process
 }
 
 type UnknownProcessError struct {
@@ -26,23 +40,100 @@ func (e UnknownProcessError) Error() string {
 	return fmt.Sprintf("unknown process: %d", e.ProcessID)
 }
 
-func New(containerPath string, runner command_runner.CommandRunner) *ProcessTracker {
+func (e UnknownProcessError) Code() backend.ErrorCode {
+	return backend.ErrorCodeUnknownProcess
+}
+
+type StdinNotStreamedError struct {
+	ProcessID uint32
+}
+
+func (e StdinNotStreamedError) Error() string {
+	return fmt.Sprintf("stdin is not being streamed to process: %d", e.ProcessID)
+}
+
+func (e StdinNotStreamedError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
+type NoTTYError struct {
+	ProcessID uint32
+}
+
+func (e NoTTYError) Error() string {
+	return fmt.Sprintf("process has no tty: %d", e.ProcessID)
+}
+
+func (e NoTTYError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
+type WaitTimedOutError struct {
+	ProcessID uint32
+}
+
+func (e WaitTimedOutError) Error() string {
+	return fmt.Sprintf("timed out waiting for process: %d", e.ProcessID)
+}
+
+func (e WaitTimedOutError) Code() backend.ErrorCode {
+	return backend.ErrorCodeTimedOut
+}
+
+type UnsupportedSignalError struct {
+	Signal os.Signal
+}
+
+func (e UnsupportedSignalError) Error() string {
+	return fmt.Sprintf("unsupported signal: %s", e.Signal)
+}
+
+func (e UnsupportedSignalError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
+// New returns a tracker for processes in the container at containerPath,
+// retaining up to outputHistory bytes of each of their outputs (and at most
+// twice that on disk) for clients attaching from an offset. Processes that
+// run past their timeout are given timeoutGracePeriod to exit after TERM
+// before they are sent KILL.
+func New(containerPath string, outputHistory uint64, timeoutGracePeriod time.Duration, runner command_runner.CommandRunner) *ProcessTracker {
 	return &ProcessTracker{
-		containerPath: containerPath,
-		runner:        runner,
+		containerPath:      containerPath,
+		outputHistory:      outputHistory,
+		timeoutGracePeriod: timeoutGracePeriod,
+		runner:             runner,
 
 		processes:      make(map[uint32]*Process),
 		processesMutex: new(sync.RWMutex),
 	}
 }
 
-func (t *ProcessTracker) Run(cmd *exec.Cmd) (uint32, chan backend.ProcessStream, error) {
+func (t *ProcessTracker) Run(cmd *exec.Cmd, spec backend.ProcessSpec) (uint32, chan backend.ProcessStream, error) {
 	t.processesMutex.Lock()
 
 	processID := t.nextProcessID
 	t.nextProcessID++
 
-	process := NewProcess(processID, t.containerPath, t.runner)
+	process := NewProcess(processID, t.containerPath, t.outputHistory, t.timeoutGracePeriod, t.runner)
+
+	process.command = describeCommand(spec)
+	process.startedAt = time.Now()
+	process.timeout = spec.Timeout
+
+	if spec.StreamStdin {
+		// keep the process's stdin open after the command's own input (i.e. its
+		// script) so that clients can write to it until they send EOF
+		stdinR, stdinW := io.Pipe()
+
+		if cmd.Stdin != nil {
+			cmd.Stdin = io.MultiReader(cmd.Stdin, stdinR)
+		} else {
+			cmd.Stdin = stdinR
+		}
+
+		process.stdin = stdinW
+	}
 
 	t.processes[processID] = process
 
@@ -52,22 +143,40 @@ func (t *ProcessTracker) Run(cmd *exec.Cmd) (uint32, chan backend.ProcessStream,
 
 	err := <-ready
 	if err != nil {
+		process.closeStdin()
 		return 0, nil, err
 	}
 
-	processStream := process.Stream()
+	processStream := process.Stream(backend.ProcessStreamOffsets{})
 
 	go t.link(processID)
 
+	go process.EnforceTimeout()
+
 	err = <-active
 	if err != nil {
+		process.unregisterStream(processStream)
 		return 0, nil, err
 	}
 
 	return processID, processStream, nil
 }
 
-func (t *ProcessTracker) Attach(processID uint32) (chan backend.ProcessStream, error) {
+func (t *ProcessTracker) Attach(processID uint32, offsets backend.ProcessStreamOffsets) (chan backend.ProcessStream, error) {
+	process, ok := t.lookup(processID)
+
+	if !ok {
+		return nil, UnknownProcessError{processID}
+	}
+
+	processStream := process.Stream(offsets)
+
+	go t.link(processID)
+
+	return processStream, nil
+}
+
+func (t *ProcessTracker) Stdin(processID uint32) (io.WriteCloser, error) {
 	t.processesMutex.RLock()
 	process, ok := t.processes[processID]
 	t.processesMutex.RUnlock()
@@ -76,22 +185,76 @@ func (t *ProcessTracker) Attach(processID uint32) (chan backend.ProcessStream, e
 		return nil, UnknownProcessError{processID}
 	}
 
-	processStream := process.Stream()
+	if process.stdin == nil {
+		return nil, StdinNotStreamedError{processID}
+	}
+
+	return process.stdin, nil
+}
+
+func (t *ProcessTracker) SetWindowSize(processID uint32, size backend.WindowSize) error {
+	t.processesMutex.RLock()
+	process, ok := t.processes[processID]
+	t.processesMutex.RUnlock()
+
+	if !ok {
+		return UnknownProcessError{processID}
+	}
+
+	return process.SetWindowSize(size)
+}
+
+func (t *ProcessTracker) Signal(processID uint32, signal os.Signal) error {
+	t.processesMutex.RLock()
+	process, ok := t.processes[processID]
+	t.processesMutex.RUnlock()
+
+	if !ok {
+		return UnknownProcessError{processID}
+	}
+
+	return process.Signal(signal)
+}
+
+func (t *ProcessTracker) Wait(processID uint32, timeout time.Duration) (uint32, error) {
+	process, ok := t.lookup(processID)
+
+	if !ok {
+		return 0, UnknownProcessError{processID}
+	}
 
+	// a restored process may not be linked to yet, in which case nothing
+	// would ever notice it exit
 	go t.link(processID)
 
-	return processStream, nil
+	return process.Wait(timeout)
 }
 
-func (t *ProcessTracker) Restore(processID uint32) {
+func (t *ProcessTracker) Restore(info backend.ProcessInfo) {
 	t.processesMutex.Lock()
 
-	process := NewProcess(processID, t.containerPath, t.runner)
+	process := NewProcess(info.ID, t.containerPath, t.outputHistory, t.timeoutGracePeriod, t.runner)
 
-	t.processes[processID] = process
+	process.command = info.Command
+	process.startedAt = info.StartedAt
+	process.timeout = info.Timeout
 
-	if processID >= t.nextProcessID {
-		t.nextProcessID = processID + 1
+	if info.ExitStatus != nil {
+		process.restoreExit(*info.ExitStatus, info.FinishedAt, info.TimedOut)
+		t.remember(process)
+	} else {
+		t.processes[info.ID] = process
+
+		if process.timeout != 0 {
+			// the timeout still applies, and noticing the process exit
+			// requires linking to it
+			go t.link(info.ID)
+			go process.EnforceTimeout()
+		}
+	}
+
+	if info.ID >= t.nextProcessID {
+		t.nextProcessID = info.ID + 1
 	}
 
 	t.processesMutex.Unlock()
@@ -107,9 +270,54 @@ func (t *ProcessTracker) ActiveProcesses() []*Process {
 		processes = append(processes, process)
 	}
 
+	sort.Sort(byID(processes))
+
 	return processes
 }
 
+// Processes describes the active processes and those that finished most
+// recently, ordered by ID.
+func (t *ProcessTracker) Processes() []backend.ProcessInfo {
+	t.processesMutex.RLock()
+
+	processes := make([]*Process, 0, len(t.processes)+len(t.finished))
+
+	for _, process := range t.processes {
+		processes = append(processes, process)
+	}
+
+	processes = append(processes, t.finished...)
+
+	t.processesMutex.RUnlock()
+
+	sort.Sort(byID(processes))
+
+	infos := make([]backend.ProcessInfo, len(processes))
+	for i, process := range processes {
+		infos[i] = process.Info()
+	}
+
+	return infos
+}
+
+func (t *ProcessTracker) lookup(processID uint32) (*Process, bool) {
+	t.processesMutex.RLock()
+	defer t.processesMutex.RUnlock()
+
+	process, ok := t.processes[processID]
+	if ok {
+		return process, true
+	}
+
+	for _, process := range t.finished {
+		if process.ID == processID {
+			return process, true
+		}
+	}
+
+	return nil, false
+}
+
 func (t *ProcessTracker) link(processID uint32) {
 	t.processesMutex.RLock()
 	process, ok := t.processes[processID]
@@ -119,16 +327,96 @@ func (t *ProcessTracker) link(processID uint32) {
 		return
 	}
 
-	defer t.unregister(processID)
+	defer t.retire(processID)
 
 	process.Link()
 
 	return
 }
 
-func (t *ProcessTracker) unregister(processID uint32) {
+func (t *ProcessTracker) retire(processID uint32) {
 	t.processesMutex.Lock()
 	defer t.processesMutex.Unlock()
 
+	process, ok := t.processes[processID]
+	if !ok {
+		return
+	}
+
 	delete(t.processes, processID)
+
+	if process.forgotten {
+		process.removeDir()
+		return
+	}
+
+	t.remember(process)
+}
+
+// Forget drops the process from the tracker once it finishes, removing its
+// directory and output history rather than remembering how it exited. It
+// is for processes run on the server's own behalf, which no client will ask
+// after.
+func (t *ProcessTracker) Forget(processID uint32) {
+	t.processesMutex.Lock()
+	defer t.processesMutex.Unlock()
+
+	process, ok := t.processes[processID]
+	if ok {
+		process.forgotten = true
+		return
+	}
+
+	for i, process := range t.finished {
+		if process.ID == processID {
+			t.finished = append(t.finished[:i], t.finished[i+1:]...)
+			process.removeDir()
+			return
+		}
+	}
+}
+
+// ForgetAll forgets every process as Forget does, for once the container
+// has been destroyed.
+func (t *ProcessTracker) ForgetAll() {
+	t.processesMutex.Lock()
+	defer t.processesMutex.Unlock()
+
+	for _, process := range t.processes {
+		process.forgotten = true
+	}
+
+	for _, process := range t.finished {
+		process.removeDir()
+	}
+
+	t.finished = nil
+}
+
+// remember keeps a finished process around, forgetting the oldest one if
+// there are too many. processesMutex must be held.
+func (t *ProcessTracker) remember(process *Process) {
+	t.finished = append(t.finished, process)
+
+	if len(t.finished) > MaxFinishedProcesses {
+		for _, forgotten := range t.finished[:len(t.finished)-MaxFinishedProcesses] {
+			forgotten.removeDir()
+		}
+
+		t.finished = t.finished[len(t.finished)-MaxFinishedProcesses:]
+	}
 }
+
+func describeCommand(spec backend.ProcessSpec) string {
+	if spec.Path != "" {
+		return strings.Join(append([]string{spec.Path}, spec.Args...), " ")
+	}
+
+	return spec.Script
+}
+
+type byID []*Process
+
+func (ps byID) Len() int           { return len(ps) }
+func (ps byID) Less(i, j int) bool { return ps[i].ID < ps[j].ID }
+func (ps byID) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
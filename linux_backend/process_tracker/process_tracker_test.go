@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"path"
+	"syscall"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -40,7 +43,7 @@ func setupSuccessfulSpawn() {
 var _ = Describe("Running processes", func() {
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
-		processTracker = process_tracker.New("/depot/some-id", fakeRunner)
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
 	})
 
 	It("runs the command asynchronously via iomux-spawn", func() {
@@ -50,7 +53,7 @@ var _ = Describe("Running processes", func() {
 
 		setupSuccessfulSpawn()
 
-		processID, _, err := processTracker.Run(cmd)
+		processID, _, err := processTracker.Run(cmd, backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
 
 		Eventually(fakeRunner).Should(HaveStartedExecuting(
@@ -96,15 +99,15 @@ var _ = Describe("Running processes", func() {
 			},
 		)
 
-		processTracker.Run(exec.Command("xxx"))
+		processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 	}, 10.0)
 
 	It("returns a unique process ID", func() {
 		setupSuccessfulSpawn()
 
-		processID1, _, err := processTracker.Run(exec.Command("xxx"))
+		processID1, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
-		processID2, _, err := processTracker.Run(exec.Command("xxx"))
+		processID2, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(processID1).ToNot(Equal(processID2))
@@ -113,7 +116,7 @@ var _ = Describe("Running processes", func() {
 	It("creates the process's working directory", func() {
 		setupSuccessfulSpawn()
 
-		processID, _, err := processTracker.Run(exec.Command("xxx"))
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeRunner).To(HaveExecutedSerially(
@@ -154,7 +157,7 @@ var _ = Describe("Running processes", func() {
 			},
 		)
 
-		_, processStreamChannel, err := processTracker.Run(exec.Command("xxx"))
+		_, processStreamChannel, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
 
 		chunk1 := <-processStreamChannel
@@ -170,6 +173,133 @@ var _ = Describe("Running processes", func() {
 		close(done)
 	}, 5.0)
 
+	It("does not drop output when the stream is read slowly", func(done Done) {
+		setupSuccessfulSpawn()
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				for i := 0; i < 2000; i++ {
+					cmd.Stdout.Write([]byte(fmt.Sprintf("%d\n", i)))
+				}
+
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 0")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+
+		_, processStreamChannel, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).NotTo(HaveOccurred())
+
+		time.Sleep(100 * time.Millisecond)
+
+		for i := 0; i < 2000; i++ {
+			chunk := <-processStreamChannel
+			Expect(string(chunk.Data)).To(Equal(fmt.Sprintf("%d\n", i)))
+		}
+
+		chunk := <-processStreamChannel
+		Expect(chunk.ExitStatus).ToNot(BeNil())
+
+		close(done)
+	}, 5.0)
+
+	It("does not hold up other clients while one is slow to take the exit status", func(done Done) {
+		setupSuccessfulSpawn()
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				// just enough to fill the unread stream
+				for i := 0; i < 1000; i++ {
+					cmd.Stdout.Write([]byte(fmt.Sprintf("%d\n", i)))
+				}
+
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 42")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() *uint32 {
+			return processTracker.Processes()[0].ExitStatus
+		}).ShouldNot(BeNil())
+
+		stream, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{})
+		Expect(err).ToNot(HaveOccurred())
+
+		chunk := <-stream
+		Expect(chunk.ExitStatus).ToNot(BeNil())
+		Expect(*chunk.ExitStatus).To(Equal(uint32(42)))
+
+		close(done)
+	}, 5.0)
+
+	Context("when the process fails to become active", func() {
+		BeforeEach(func() {
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: binPath("iomux-spawn"),
+				},
+				func(cmd *exec.Cmd) error {
+					cmd.Stdout.Write([]byte("ready\n"))
+
+					// exit before becoming active
+					cmd.Stdout.(*os.File).Close()
+
+					return nil
+				},
+			)
+
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: binPath("iomux-link"),
+				},
+				func(cmd *exec.Cmd) error {
+					// more than the abandoned stream could buffer
+					for i := 0; i < 2000; i++ {
+						cmd.Stdout.Write([]byte(fmt.Sprintf("%d\n", i)))
+					}
+
+					dummyCmd := exec.Command("/bin/bash", "-c", "exit 42")
+					dummyCmd.Run()
+
+					cmd.ProcessState = dummyCmd.ProcessState
+
+					return nil
+				},
+			)
+		})
+
+		It("returns the error and does not hold up the process's output", func(done Done) {
+			_, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).To(HaveOccurred())
+
+			processes := processTracker.ActiveProcesses()
+			Expect(processes).To(HaveLen(1))
+
+			exitStatus, err := processTracker.Wait(processes[0].ID, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exitStatus).To(Equal(uint32(42)))
+
+			close(done)
+		}, 5.0)
+	})
+
 	Context("when spawning fails", func() {
 		disaster := errors.New("oh no!")
 
@@ -184,7 +314,7 @@ var _ = Describe("Running processes", func() {
 		})
 
 		It("returns the error", func() {
-			_, _, err := processTracker.Run(exec.Command("xxx"))
+			_, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 			Expect(err).To(Equal(disaster))
 		})
 	})
@@ -193,35 +323,35 @@ var _ = Describe("Running processes", func() {
 var _ = Describe("Restoring processes", func() {
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
-		processTracker = process_tracker.New("/depot/some-id", fakeRunner)
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
 	})
 
 	It("makes the next process ID be higher than the highest restored ID", func() {
 		setupSuccessfulSpawn()
 
-		processTracker.Restore(0)
+		processTracker.Restore(backend.ProcessInfo{ID: 0})
 
 		cmd := &exec.Cmd{Path: "/bin/bash"}
 
 		cmd.Stdin = bytes.NewBufferString("echo hi")
 
-		processID, _, err := processTracker.Run(cmd)
+		processID, _, err := processTracker.Run(cmd, backend.ProcessSpec{})
 		Expect(err).ToNot(HaveOccurred())
 		Expect(processID).To(Equal(uint32(1)))
 
-		processTracker.Restore(5)
+		processTracker.Restore(backend.ProcessInfo{ID: 5})
 
 		cmd = &exec.Cmd{Path: "/bin/bash"}
 
 		cmd.Stdin = bytes.NewBufferString("echo hi")
 
-		processID, _, err = processTracker.Run(cmd)
+		processID, _, err = processTracker.Run(cmd, backend.ProcessSpec{})
 		Expect(err).ToNot(HaveOccurred())
 		Expect(processID).To(Equal(uint32(6)))
 	})
 
 	It("tracks the restored process", func() {
-		processTracker.Restore(2)
+		processTracker.Restore(backend.ProcessInfo{ID: 2})
 
 		activeProcesses := processTracker.ActiveProcesses()
 
@@ -233,7 +363,7 @@ var _ = Describe("Restoring processes", func() {
 var _ = Describe("Attaching to running processes", func() {
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
-		processTracker = process_tracker.New("/depot/some-id", fakeRunner)
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
 
 		fakeRunner.WhenRunning(
 			fake_command_runner.CommandSpec{
@@ -263,10 +393,10 @@ var _ = Describe("Attaching to running processes", func() {
 	It("streams their stdout and stderr into the channel", func(done Done) {
 		setupSuccessfulSpawn()
 
-		processID, _, err := processTracker.Run(exec.Command("xxx"))
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).NotTo(HaveOccurred())
 
-		processStreamChannel, err := processTracker.Attach(processID)
+		processStreamChannel, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{})
 		Expect(err).ToNot(HaveOccurred())
 
 		chunk1 := <-processStreamChannel
@@ -286,7 +416,7 @@ var _ = Describe("Attaching to running processes", func() {
 		It("runs iomux-link", func() {
 			setupSuccessfulSpawn()
 
-			processTracker.Restore(0)
+			processTracker.Restore(backend.ProcessInfo{ID: 0})
 
 			Expect(fakeRunner).ToNot(HaveExecutedSerially(
 				fake_command_runner.CommandSpec{
@@ -294,7 +424,7 @@ var _ = Describe("Attaching to running processes", func() {
 				},
 			))
 
-			_, err := processTracker.Attach(0)
+			_, err := processTracker.Attach(0, backend.ProcessStreamOffsets{})
 			Expect(err).ToNot(HaveOccurred())
 
 			Eventually(fakeRunner).Should(HaveExecutedSerially(
@@ -309,10 +439,10 @@ var _ = Describe("Attaching to running processes", func() {
 		It("yields the exit status and closes the channel", func(done Done) {
 			setupSuccessfulSpawn()
 
-			processID, _, err := processTracker.Run(exec.Command("xxx"))
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 			Expect(err).NotTo(HaveOccurred())
 
-			processStreamChannel, err := processTracker.Attach(processID)
+			processStreamChannel, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{})
 			Expect(err).ToNot(HaveOccurred())
 
 			<-processStreamChannel
@@ -332,10 +462,864 @@ var _ = Describe("Attaching to running processes", func() {
 	})
 })
 
+var _ = Describe("Attaching to processes from an offset", func() {
+	var containerPath string
+	var linkOutput chan []byte
+
+	BeforeEach(func() {
+		var err error
+
+		containerPath, err = ioutil.TempDir("", "process-tracker-history")
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New(containerPath, 8, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+
+		linkOutput = make(chan []byte)
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: path.Join(containerPath, "bin", "iomux-spawn"),
+			},
+			func(cmd *exec.Cmd) error {
+				err := os.MkdirAll(cmd.Dir, 0755)
+				Expect(err).ToNot(HaveOccurred())
+
+				cmd.Stdout.Write([]byte("ready\n"))
+				cmd.Stdout.Write([]byte("active\n"))
+				return nil
+			},
+		)
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: path.Join(containerPath, "bin", "iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				for data := range linkOutput {
+					cmd.Stdout.Write(data)
+				}
+
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 42")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(containerPath)
+	})
+
+	runAndWrite := func(chunks ...string) (uint32, <-chan backend.ProcessStream) {
+		processID, stream, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, chunk := range chunks {
+			linkOutput <- []byte(chunk)
+			<-stream
+		}
+
+		return processID, stream
+	}
+
+	It("tags each chunk with its offset in its source", func(done Done) {
+		processID, stream, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).ToNot(HaveOccurred())
+
+		linkOutput <- []byte("hi out\n")
+		linkOutput <- []byte("more\n")
+
+		chunk := <-stream
+		Expect(string(chunk.Data)).To(Equal("hi out\n"))
+		Expect(chunk.Offset).To(Equal(uint64(0)))
+
+		chunk = <-stream
+		Expect(string(chunk.Data)).To(Equal("more\n"))
+		Expect(chunk.Offset).To(Equal(uint64(7)))
+
+		close(linkOutput)
+
+		Expect(processID).To(Equal(uint32(0)))
+
+		close(done)
+	}, 5.0)
+
+	It("replays retained output from the offset and then streams live output", func(done Done) {
+		processID, _ := runAndWrite("0123456789")
+
+		offset := uint64(3)
+
+		stream, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{
+			Stdout: &offset,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		chunk := <-stream
+		Expect(chunk.Source).To(Equal(backend.ProcessStreamSourceStdout))
+		Expect(string(chunk.Data)).To(Equal("3456789"))
+		Expect(chunk.Offset).To(Equal(uint64(3)))
+
+		linkOutput <- []byte("abc")
+
+		chunk = <-stream
+		Expect(string(chunk.Data)).To(Equal("abc"))
+		Expect(chunk.Offset).To(Equal(uint64(10)))
+
+		close(linkOutput)
+
+		chunk = <-stream
+		Expect(chunk.ExitStatus).ToNot(BeNil())
+		Expect(*chunk.ExitStatus).To(Equal(uint32(42)))
+
+		_, ok := <-stream
+		Expect(ok).To(BeFalse(), "channel is not closed")
+
+		close(done)
+	}, 5.0)
+
+	It("does not replay output of sources without an offset", func(done Done) {
+		processID, _ := runAndWrite("0123456789")
+
+		stream, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{})
+		Expect(err).ToNot(HaveOccurred())
+
+		linkOutput <- []byte("abc")
+
+		chunk := <-stream
+		Expect(string(chunk.Data)).To(Equal("abc"))
+		Expect(chunk.Offset).To(Equal(uint64(10)))
+
+		close(linkOutput)
+
+		close(done)
+	}, 5.0)
+
+	Context("when the offset is no longer retained", func() {
+		It("replays from the oldest retained output", func(done Done) {
+			processID, _ := runAndWrite("0123456789", "abcdefghij")
+
+			offset := uint64(0)
+
+			stream, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{
+				Stdout: &offset,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			chunk := <-stream
+			Expect(string(chunk.Data)).To(Equal("cdefghij"))
+			Expect(chunk.Offset).To(Equal(uint64(12)))
+
+			close(linkOutput)
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("when the process is restored by another tracker", func() {
+		It("continues from the retained offsets", func(done Done) {
+			processID, stream := runAndWrite("0123456789")
+
+			// let the first server's link go, as though it had shut down
+			close(linkOutput)
+
+			for _ = range stream {
+			}
+
+			linkOutput = make(chan []byte)
+
+			processTracker = process_tracker.New(containerPath, 8, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+			processTracker.Restore(backend.ProcessInfo{ID: processID})
+
+			offset := uint64(5)
+
+			stream, err := processTracker.Attach(processID, backend.ProcessStreamOffsets{
+				Stdout: &offset,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			chunk := <-stream
+			Expect(string(chunk.Data)).To(Equal("56789"))
+			Expect(chunk.Offset).To(Equal(uint64(5)))
+
+			linkOutput <- []byte("abc")
+
+			chunk = <-stream
+			Expect(string(chunk.Data)).To(Equal("abc"))
+			Expect(chunk.Offset).To(Equal(uint64(10)))
+
+			close(linkOutput)
+
+			close(done)
+		}, 5.0)
+	})
+
+	Describe("forgetting a process", func() {
+		It("drops it once it finishes and removes its history", func(done Done) {
+			processID, stream := runAndWrite("0123456789")
+
+			processDir := path.Join(containerPath, "processes", fmt.Sprintf("%d", processID))
+			_, err := os.Stat(path.Join(processDir, process_tracker.StdoutHistory))
+			Expect(err).ToNot(HaveOccurred())
+
+			processTracker.Forget(processID)
+
+			close(linkOutput)
+
+			for _ = range stream {
+			}
+
+			Eventually(processTracker.Processes).Should(BeEmpty())
+
+			_, err = os.Stat(processDir)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			close(done)
+		}, 5.0)
+
+		Context("when it has already finished", func() {
+			It("drops it and removes its history", func(done Done) {
+				processID, stream := runAndWrite("0123456789")
+
+				close(linkOutput)
+
+				for _ = range stream {
+				}
+
+				Eventually(processTracker.ActiveProcesses).Should(BeEmpty())
+				Expect(processTracker.Processes()).To(HaveLen(1))
+
+				processTracker.Forget(processID)
+
+				Expect(processTracker.Processes()).To(BeEmpty())
+
+				_, err := os.Stat(path.Join(containerPath, "processes", fmt.Sprintf("%d", processID)))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				close(done)
+			}, 5.0)
+		})
+	})
+
+	Describe("forgetting every process", func() {
+		It("removes the history of finished and active processes", func(done Done) {
+			finishedID, finishedStream := runAndWrite("0123456789")
+
+			close(linkOutput)
+
+			for _ = range finishedStream {
+			}
+
+			Eventually(processTracker.ActiveProcesses).Should(BeEmpty())
+
+			linkOutput = make(chan []byte)
+
+			activeID, activeStream := runAndWrite("abc")
+
+			processTracker.ForgetAll()
+
+			Expect(processTracker.Processes()).To(HaveLen(1))
+
+			close(linkOutput)
+
+			for _ = range activeStream {
+			}
+
+			Eventually(processTracker.Processes).Should(BeEmpty())
+
+			for _, processID := range []uint32{finishedID, activeID} {
+				_, err := os.Stat(path.Join(containerPath, "processes", fmt.Sprintf("%d", processID)))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			}
+
+			close(done)
+		}, 5.0)
+	})
+
+	It("removes the history of the oldest finished processes beyond the limit", func() {
+		exitStatus := uint32(0)
+
+		oldestDir := path.Join(containerPath, "processes", "0")
+
+		err := os.MkdirAll(oldestDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ioutil.WriteFile(path.Join(oldestDir, process_tracker.StdoutHistory), []byte("some output"), 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < process_tracker.MaxFinishedProcesses; i++ {
+			processTracker.Restore(backend.ProcessInfo{ID: uint32(i), ExitStatus: &exitStatus})
+		}
+
+		_, err = os.Stat(oldestDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		processTracker.Restore(backend.ProcessInfo{ID: process_tracker.MaxFinishedProcesses, ExitStatus: &exitStatus})
+
+		_, err = os.Stat(oldestDir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Streaming stdin to processes", func() {
+	var linked chan bool
+
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+
+		// the linker keeps its own channel, as it may still be running once
+		// the next spec has made another
+		unlink := make(chan bool)
+		linked = unlink
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				<-unlink
+				return nil
+			},
+		)
+	})
+
+	AfterEach(func() {
+		close(linked)
+	})
+
+	It("writes to the process's stdin after its initial input", func(done Done) {
+		received := make(chan string, 1)
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-spawn"),
+			},
+			func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte("ready\n"))
+				cmd.Stdout.Write([]byte("active\n"))
+
+				go func() {
+					in, _ := ioutil.ReadAll(cmd.Stdin)
+					received <- string(in)
+				}()
+
+				return nil
+			},
+		)
+
+		cmd := exec.Command("xxx")
+		cmd.Stdin = bytes.NewBufferString("echo hi\n")
+
+		processID, _, err := processTracker.Run(cmd, backend.ProcessSpec{StreamStdin: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		stdin, err := processTracker.Stdin(processID)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = stdin.Write([]byte("echo bye\n"))
+		Expect(err).ToNot(HaveOccurred())
+
+		err = stdin.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(<-received).To(Equal("echo hi\necho bye\n"))
+
+		close(done)
+	}, 5.0)
+
+	Context("when the process was not run with stdin streaming", func() {
+		It("returns a StdinNotStreamedError", func() {
+			setupSuccessfulSpawn()
+
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = processTracker.Stdin(processID)
+			Expect(err).To(Equal(process_tracker.StdinNotStreamedError{processID}))
+		})
+	})
+
+	Context("when the process is not known", func() {
+		It("returns an UnknownProcessError", func() {
+			_, err := processTracker.Stdin(42)
+			Expect(err).To(Equal(process_tracker.UnknownProcessError{42}))
+		})
+	})
+})
+
+var _ = Describe("Resizing a process's tty", func() {
+	var containerPath string
+
+	BeforeEach(func() {
+		var err error
+
+		containerPath, err = ioutil.TempDir("", "process-tracker-tty")
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New(containerPath, 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: path.Join(containerPath, "bin", "iomux-spawn"),
+			},
+			func(cmd *exec.Cmd) error {
+				cmd.Stdout.Write([]byte("ready\n"))
+				cmd.Stdout.Write([]byte("active\n"))
+				return nil
+			},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(containerPath)
+	})
+
+	It("writes the window size to the process's window size fifo", func() {
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).ToNot(HaveOccurred())
+
+		processDir := path.Join(containerPath, "processes", fmt.Sprintf("%d", processID))
+
+		err = os.MkdirAll(processDir, 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		fifoPath := path.Join(processDir, process_tracker.WindowSizeFifo)
+
+		err = syscall.Mkfifo(fifoPath, 0600)
+		Expect(err).ToNot(HaveOccurred())
+
+		fifo, err := os.OpenFile(fifoPath, os.O_RDWR, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		defer fifo.Close()
+
+		err = processTracker.SetWindowSize(processID, backend.WindowSize{
+			Columns: 80,
+			Rows:    24,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		buf := make([]byte, 64)
+
+		n, err := fifo.Read(buf)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(buf[:n])).To(Equal("80 24\n"))
+	})
+
+	Context("when the process has no tty", func() {
+		It("returns a NoTTYError", func() {
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = processTracker.SetWindowSize(processID, backend.WindowSize{})
+			Expect(err).To(Equal(process_tracker.NoTTYError{processID}))
+		})
+	})
+
+	Context("when the process is not known", func() {
+		It("returns an UnknownProcessError", func() {
+			err := processTracker.SetWindowSize(42, backend.WindowSize{})
+			Expect(err).To(Equal(process_tracker.UnknownProcessError{42}))
+		})
+	})
+})
+
+var _ = Describe("Waiting for a process", func() {
+	var exit chan bool
+
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+
+		// the linker keeps its own channel, as it may still be running once
+		// the next spec has made another
+		exited := make(chan bool)
+		exit = exited
+
+		setupSuccessfulSpawn()
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				<-exited
+
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 42")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+	})
+
+	It("returns the exit status once the process exits", func(done Done) {
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).ToNot(HaveOccurred())
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			close(exit)
+		}()
+
+		exitStatus, err := processTracker.Wait(processID, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitStatus).To(Equal(uint32(42)))
+
+		close(done)
+	}, 5.0)
+
+	Context("when the process is restored and not yet linked to", func() {
+		It("links to it", func(done Done) {
+			processTracker.Restore(backend.ProcessInfo{ID: 0})
+
+			close(exit)
+
+			exitStatus, err := processTracker.Wait(0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exitStatus).To(Equal(uint32(42)))
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("when the timeout passes first", func() {
+		It("returns a WaitTimedOutError", func(done Done) {
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = processTracker.Wait(processID, 100*time.Millisecond)
+			Expect(err).To(Equal(process_tracker.WaitTimedOutError{processID}))
+
+			close(exit)
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("when the process is not known", func() {
+		It("returns an UnknownProcessError", func() {
+			_, err := processTracker.Wait(42, 0)
+			Expect(err).To(Equal(process_tracker.UnknownProcessError{42}))
+		})
+	})
+})
+
+var _ = Describe("Listing processes", func() {
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+
+		setupSuccessfulSpawn()
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 42")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+	})
+
+	runToCompletion := func(spec backend.ProcessSpec) uint32 {
+		processID, stream, err := processTracker.Run(exec.Command("xxx"), spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _ = range stream {
+		}
+
+		// the process is retired just after its streams close
+		Eventually(func() []*process_tracker.Process {
+			return processTracker.ActiveProcesses()
+		}).Should(BeEmpty())
+
+		return processID
+	}
+
+	It("includes finished processes with their exit status", func(done Done) {
+		before := time.Now()
+
+		processID := runToCompletion(backend.ProcessSpec{
+			Path: "/some/bin",
+			Args: []string{"some", "args"},
+		})
+
+		processes := processTracker.Processes()
+		Expect(processes).To(HaveLen(1))
+
+		info := processes[0]
+		Expect(info.ID).To(Equal(processID))
+		Expect(info.Command).To(Equal("/some/bin some args"))
+		Expect(info.StartedAt.Before(before)).To(BeFalse())
+		Expect(info.FinishedAt.Before(info.StartedAt)).To(BeFalse())
+		Expect(info.ExitStatus).ToNot(BeNil())
+		Expect(*info.ExitStatus).To(Equal(uint32(42)))
+
+		close(done)
+	}, 5.0)
+
+	It("still lets clients wait for finished processes", func(done Done) {
+		processID := runToCompletion(backend.ProcessSpec{Script: "exit 42"})
+
+		exitStatus, err := processTracker.Wait(processID, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitStatus).To(Equal(uint32(42)))
+
+		close(done)
+	}, 5.0)
+
+	It("forgets the oldest finished processes beyond the limit", func(done Done) {
+		for i := 0; i < process_tracker.MaxFinishedProcesses+1; i++ {
+			runToCompletion(backend.ProcessSpec{Script: "exit 42"})
+		}
+
+		processes := processTracker.Processes()
+		Expect(processes).To(HaveLen(process_tracker.MaxFinishedProcesses))
+		Expect(processes[0].ID).To(Equal(uint32(1)))
+
+		_, err := processTracker.Wait(0, 0)
+		Expect(err).To(Equal(process_tracker.UnknownProcessError{0}))
+
+		close(done)
+	}, 30.0)
+
+	Context("when a finished process is restored", func() {
+		It("reports it as finished without linking to it", func(done Done) {
+			exitStatus := uint32(3)
+			finishedAt := time.Now()
+
+			processTracker.Restore(backend.ProcessInfo{
+				ID:         7,
+				Command:    "some-command",
+				FinishedAt: finishedAt,
+				ExitStatus: &exitStatus,
+			})
+
+			processes := processTracker.Processes()
+			Expect(processes).To(HaveLen(1))
+			Expect(processes[0].ID).To(Equal(uint32(7)))
+			Expect(processes[0].Command).To(Equal("some-command"))
+			Expect(*processes[0].ExitStatus).To(Equal(uint32(3)))
+
+			status, err := processTracker.Wait(7, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal(uint32(3)))
+
+			Expect(fakeRunner).ToNot(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{
+					Path: binPath("iomux-link"),
+				},
+			))
+
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processID).To(Equal(uint32(8)))
+
+			close(done)
+		}, 5.0)
+	})
+})
+
+var _ = Describe("Timing out processes", func() {
+	var signalled chan string
+	var exit chan bool
+
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New("/depot/some-id", 0, 100*time.Millisecond, fakeRunner)
+
+		// the closures below keep their own channels, as processes from
+		// earlier specs may still be linked to or signalled after this
+		// spec's channels have been made
+		signals := make(chan string, 2)
+		exited := make(chan bool)
+
+		signalled = signals
+		exit = exited
+
+		setupSuccessfulSpawn()
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("iomux-link"),
+			},
+			func(cmd *exec.Cmd) error {
+				<-exited
+
+				dummyCmd := exec.Command("/bin/bash", "-c", "exit 143")
+				dummyCmd.Run()
+
+				cmd.ProcessState = dummyCmd.ProcessState
+
+				return nil
+			},
+		)
+
+		fakeRunner.WhenRunning(
+			fake_command_runner.CommandSpec{
+				Path: binPath("wsh"),
+			},
+			func(cmd *exec.Cmd) error {
+				signals <- cmd.Args[3]
+				return nil
+			},
+		)
+	})
+
+	It("terminates the process once the timeout passes and reports that it timed out", func(done Done) {
+		processID, stream, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{
+			Timeout: 100 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(<-signalled).To(Equal("15"))
+
+		close(exit)
+
+		chunk := <-stream
+		Expect(*chunk.ExitStatus).To(Equal(uint32(143)))
+		Expect(chunk.TimedOut).To(BeTrue())
+
+		Eventually(func() bool {
+			info := processTracker.Processes()[0]
+			return info.ID == processID && info.TimedOut
+		}).Should(BeTrue())
+
+		Consistently(signalled, 0.2).ShouldNot(Receive())
+
+		close(done)
+	}, 5.0)
+
+	Context("when the process does not exit after being terminated", func() {
+		It("kills it after the grace period", func(done Done) {
+			_, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{
+				Timeout: 100 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(<-signalled).To(Equal("15"))
+			Expect(<-signalled).To(Equal("9"))
+
+			close(exit)
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("when a process is restored after its timeout passed", func() {
+		It("terminates it", func(done Done) {
+			processTracker.Restore(backend.ProcessInfo{
+				ID:        0,
+				StartedAt: time.Now().Add(-time.Hour),
+				Timeout:   time.Minute,
+			})
+
+			Expect(<-signalled).To(Equal("15"))
+
+			close(exit)
+
+			exitStatus, err := processTracker.Wait(0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exitStatus).To(Equal(uint32(143)))
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("when the process exits before the timeout", func() {
+		It("does not signal it and does not report it as timed out", func(done Done) {
+			_, stream, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{
+				Timeout: 200 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			close(exit)
+
+			chunk := <-stream
+			Expect(chunk.TimedOut).To(BeFalse())
+
+			Consistently(signalled, 0.4).ShouldNot(Receive())
+
+			close(done)
+		}, 5.0)
+	})
+})
+
+var _ = Describe("Signalling a process", func() {
+	BeforeEach(func() {
+		fakeRunner = fake_command_runner.New()
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
+	})
+
+	It("asks wshd to signal the process group of the process recorded in its pid file", func() {
+		setupSuccessfulSpawn()
+
+		processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = processTracker.Signal(processID, syscall.SIGTERM)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeRunner).To(HaveExecutedSerially(
+			fake_command_runner.CommandSpec{
+				Path: binPath("wsh"),
+				Args: []string{
+					"--socket", "/depot/some-id/run/wshd.sock",
+					"--signal", "15",
+					"--pid-file", fmt.Sprintf("/depot/some-id/processes/%d/pid", processID),
+				},
+			},
+		))
+	})
+
+	Context("when signalling fails", func() {
+		disaster := errors.New("oh no!")
+
+		It("returns the error", func() {
+			setupSuccessfulSpawn()
+
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: binPath("wsh"),
+				}, func(*exec.Cmd) error {
+					return disaster
+				},
+			)
+
+			processID, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = processTracker.Signal(processID, syscall.SIGKILL)
+			Expect(err).To(Equal(disaster))
+		})
+	})
+
+	Context("when the process is not known", func() {
+		It("returns an UnknownProcessError", func() {
+			err := processTracker.Signal(42, syscall.SIGTERM)
+			Expect(err).To(Equal(process_tracker.UnknownProcessError{42}))
+		})
+	})
+})
+
 var _ = Describe("Listing active processes", func() {
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
-		processTracker = process_tracker.New("/depot/some-id", fakeRunner)
+		processTracker = process_tracker.New("/depot/some-id", 0, process_tracker.DefaultTimeoutGracePeriod, fakeRunner)
 	})
 
 	It("includes running process IDs", func() {
@@ -353,10 +1337,10 @@ var _ = Describe("Listing active processes", func() {
 			},
 		)
 
-		processID1, _, err := processTracker.Run(exec.Command("xxx"))
+		processID1, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).ToNot(HaveOccurred())
 
-		processID2, _, err := processTracker.Run(exec.Command("xxx"))
+		processID2, _, err := processTracker.Run(exec.Command("xxx"), backend.ProcessSpec{})
 		Expect(err).ToNot(HaveOccurred())
 
 		totalRunning := append(<-running, <-running...)
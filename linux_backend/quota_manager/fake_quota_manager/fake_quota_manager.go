@@ -14,6 +14,9 @@ type FakeQuotaManager struct {
 	GetLimitsResult backend.DiskLimits
 	GetUsageResult  backend.ContainerDiskStat
 
+	GetUsagesError  error
+	GetUsagesResult map[uint32]backend.ContainerDiskStat
+
 	MountPointResult string
 
 	Limited map[uint32]backend.DiskLimits
@@ -66,6 +69,22 @@ func (m *FakeQuotaManager) GetUsage(uid uint32) (backend.ContainerDiskStat, erro
 	return m.GetUsageResult, nil
 }
 
+func (m *FakeQuotaManager) GetUsages(uids []uint32) (map[uint32]backend.ContainerDiskStat, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	usages := make(map[uint32]backend.ContainerDiskStat)
+
+	for _, uid := range uids {
+		usage, found := m.GetUsagesResult[uid]
+		if found {
+			usages[uid] = usage
+		}
+	}
+
+	return usages, m.GetUsagesError
+}
+
 func (m *FakeQuotaManager) MountPoint() string {
 	return m.MountPointResult
 }
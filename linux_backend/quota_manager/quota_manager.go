@@ -1,6 +1,7 @@
 package quota_manager
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"os"
@@ -16,6 +17,7 @@ type QuotaManager interface {
 	SetLimits(uid uint32, limits backend.DiskLimits) error
 	GetLimits(uid uint32) (backend.DiskLimits, error)
 	GetUsage(uid uint32) (backend.ContainerDiskStat, error)
+	GetUsages(uids []uint32) (map[uint32]backend.ContainerDiskStat, error)
 	MountPoint() string
 	Disable()
 	IsEnabled() bool
@@ -186,6 +188,74 @@ func (m *LinuxQuotaManager) GetUsage(uid uint32) (backend.ContainerDiskStat, err
 	return usage, err
 }
 
+// GetUsages gets the usage of many users with a single repquota. If it
+// fails part-way, the usages reported up to then are returned with the
+// error.
+func (m *LinuxQuotaManager) GetUsages(uids []uint32) (map[uint32]backend.ContainerDiskStat, error) {
+	usages := make(map[uint32]backend.ContainerDiskStat)
+
+	if !m.enabled {
+		for _, uid := range uids {
+			usages[uid] = backend.ContainerDiskStat{}
+		}
+
+		return usages, nil
+	}
+
+	if len(uids) == 0 {
+		return usages, nil
+	}
+
+	args := []string{m.mountPoint}
+	for _, uid := range uids {
+		args = append(args, fmt.Sprintf("%d", uid))
+	}
+
+	out := new(bytes.Buffer)
+
+	repquota := &exec.Cmd{
+		Path:   path.Join(m.binPath, "repquota"),
+		Args:   args,
+		Stdout: out,
+	}
+
+	runErr := m.runner.Run(repquota)
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var uid uint32
+		var skip uint64
+
+		usage := backend.ContainerDiskStat{}
+
+		_, err := fmt.Sscanf(
+			scanner.Text(),
+			"%d %d %d %d %d %d %d %d %d",
+			&uid,
+			&usage.BytesUsed,
+			&skip,
+			&skip,
+			&skip,
+			&usage.InodesUsed,
+			&skip,
+			&skip,
+			&skip,
+		)
+
+		if err != nil {
+			if runErr != nil {
+				return usages, runErr
+			}
+
+			return usages, err
+		}
+
+		usages[uid] = usage
+	}
+
+	return usages, runErr
+}
+
 func (m *LinuxQuotaManager) MountPoint() string {
 	return m.mountPoint
 }
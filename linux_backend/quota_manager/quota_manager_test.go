@@ -330,6 +330,94 @@ var _ = Describe("Linux Quota manager", func() {
 		})
 	})
 
+	Describe("getting the usage of many users", func() {
+		It("executes repquota once for all of them", func() {
+			fakeRunner.WhenRunning(
+				fake_command_runner.CommandSpec{
+					Path: "/root/path/repquota",
+					Args: []string{"/some/mount/point", "1234", "5678"},
+				}, func(cmd *exec.Cmd) error {
+					cmd.Stdout.Write([]byte("1234 111 222 333 444 555 666 777 888\n"))
+					cmd.Stdout.Write([]byte("5678 11 22 33 44 55 66 77 88\n"))
+
+					return nil
+				},
+			)
+
+			usages, err := quotaManager.GetUsages([]uint32{1234, 5678})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(usages).To(Equal(map[uint32]backend.ContainerDiskStat{
+				1234: {BytesUsed: 111, InodesUsed: 555},
+				5678: {BytesUsed: 11, InodesUsed: 55},
+			}))
+		})
+
+		Context("when repquota fails part-way", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeRunner.WhenRunning(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/repquota",
+						Args: []string{"/some/mount/point", "1234", "5678"},
+					}, func(cmd *exec.Cmd) error {
+						cmd.Stdout.Write([]byte("1234 111 222 333 444 555 666 777 888\n"))
+						return disaster
+					},
+				)
+			})
+
+			It("returns the usages reported before it failed, and the error", func() {
+				usages, err := quotaManager.GetUsages([]uint32{1234, 5678})
+				Expect(err).To(Equal(disaster))
+
+				Expect(usages).To(Equal(map[uint32]backend.ContainerDiskStat{
+					1234: {BytesUsed: 111, InodesUsed: 555},
+				}))
+			})
+		})
+
+		Context("when the output of repquota is malformed", func() {
+			It("returns an error", func() {
+				fakeRunner.WhenRunning(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/repquota",
+						Args: []string{"/some/mount/point", "1234"},
+					}, func(cmd *exec.Cmd) error {
+						cmd.Stdout.Write([]byte("abc\n"))
+
+						return nil
+					},
+				)
+
+				_, err := quotaManager.GetUsages([]uint32{1234})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when quotas are disabled", func() {
+			BeforeEach(func() {
+				quotaManager.Disable()
+			})
+
+			It("reports zero usage without running anything", func() {
+				usages, err := quotaManager.GetUsages([]uint32{1234})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(usages).To(Equal(map[uint32]backend.ContainerDiskStat{
+					1234: {},
+				}))
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/repquota",
+					},
+				))
+			})
+		})
+	})
+
 	Describe("getting the mount point", func() {
 		It("returns the mount point of the container depot", func() {
 			Expect(quotaManager.MountPoint()).To(Equal("/some/mount/point"))
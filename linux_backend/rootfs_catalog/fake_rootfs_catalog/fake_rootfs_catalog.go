@@ -0,0 +1,34 @@
+package fake_rootfs_catalog
+
+import (
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog"
+)
+
+type FakeRootFSCatalog struct {
+	RootFSes map[string]string
+
+	ResolveError error
+
+	Resolved []string
+}
+
+func New() *FakeRootFSCatalog {
+	return &FakeRootFSCatalog{
+		RootFSes: make(map[string]string),
+	}
+}
+
+func (c *FakeRootFSCatalog) Resolve(rootfs string) (string, error) {
+	if c.ResolveError != nil {
+		return "", c.ResolveError
+	}
+
+	c.Resolved = append(c.Resolved, rootfs)
+
+	rootFSPath, found := c.RootFSes[rootfs]
+	if !found {
+		return "", rootfs_catalog.UnknownRootFSError{RootFS: rootfs}
+	}
+
+	return rootFSPath, nil
+}
@@ -0,0 +1,68 @@
+package rootfs_catalog
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+type RootFSCatalog interface {
+	Resolve(rootfs string) (string, error)
+}
+
+type DirectoryRootFSCatalog struct {
+	catalogPath string
+	allowPaths  bool
+}
+
+type UnknownRootFSError struct {
+	RootFS string
+}
+
+func (e UnknownRootFSError) Error() string {
+	return "unknown rootfs: " + e.RootFS
+}
+
+func (e UnknownRootFSError) Code() backend.ErrorCode {
+	return backend.ErrorCodeInvalidRequest
+}
+
+// New returns a catalog of the directories in catalogPath. Absolute paths
+// to any directory on the host are only resolved if allowPaths is set, as
+// they let a container be created over whatever files they lead to.
+func New(catalogPath string, allowPaths bool) *DirectoryRootFSCatalog {
+	return &DirectoryRootFSCatalog{
+		catalogPath: catalogPath,
+		allowPaths:  allowPaths,
+	}
+}
+
+// Resolve maps a requested rootfs to a directory on the host. Absolute paths
+// are used as-is, if they are allowed; anything else is treated as the name
+// of a directory in the catalog.
+func (c *DirectoryRootFSCatalog) Resolve(rootfs string) (string, error) {
+	var rootFSPath string
+
+	if path.IsAbs(rootfs) {
+		if !c.allowPaths {
+			return "", UnknownRootFSError{rootfs}
+		}
+
+		rootFSPath = path.Clean(rootfs)
+	} else {
+		if c.catalogPath == "" || rootfs == "" || strings.Contains(rootfs, "/") {
+			return "", UnknownRootFSError{rootfs}
+		}
+
+		rootFSPath = path.Join(c.catalogPath, rootfs)
+	}
+
+	info, err := os.Stat(rootFSPath)
+	if err != nil || !info.IsDir() {
+		return "", UnknownRootFSError{rootfs}
+	}
+
+	return rootFSPath, nil
+}
@@ -0,0 +1,13 @@
+package rootfs_catalog_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRootfs_catalog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rootfs_catalog Suite")
+}
@@ -0,0 +1,116 @@
+package rootfs_catalog_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog"
+)
+
+var _ = Describe("Directory rootfs catalog", func() {
+	var catalogPath string
+	var catalog *rootfs_catalog.DirectoryRootFSCatalog
+
+	BeforeEach(func() {
+		var err error
+
+		catalogPath, err = ioutil.TempDir("", "rootfs-catalog")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = os.Mkdir(path.Join(catalogPath, "lucid64"), 0755)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ioutil.WriteFile(path.Join(catalogPath, "not-a-dir"), []byte{}, 0644)
+		Expect(err).ToNot(HaveOccurred())
+
+		catalog = rootfs_catalog.New(catalogPath, false)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(catalogPath)
+	})
+
+	Describe("resolving", func() {
+		Context("with a name in the catalog", func() {
+			It("returns the rootfs's directory", func() {
+				rootFSPath, err := catalog.Resolve("lucid64")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rootFSPath).To(Equal(path.Join(catalogPath, "lucid64")))
+			})
+		})
+
+		Context("with a name that is not in the catalog", func() {
+			It("returns an UnknownRootFSError", func() {
+				_, err := catalog.Resolve("precise64")
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"precise64"}))
+			})
+		})
+
+		Context("with a name that is not a directory", func() {
+			It("returns an UnknownRootFSError", func() {
+				_, err := catalog.Resolve("not-a-dir")
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"not-a-dir"}))
+			})
+		})
+
+		Context("with a relative path", func() {
+			It("returns an UnknownRootFSError", func() {
+				_, err := catalog.Resolve("../lucid64")
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"../lucid64"}))
+			})
+		})
+
+		Context("with an absolute path", func() {
+			It("returns an UnknownRootFSError", func() {
+				_, err := catalog.Resolve("/")
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"/"}))
+
+				_, err = catalog.Resolve(path.Join(catalogPath, "lucid64"))
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{path.Join(catalogPath, "lucid64")}))
+			})
+
+			Context("when paths are allowed", func() {
+				BeforeEach(func() {
+					catalog = rootfs_catalog.New(catalogPath, true)
+				})
+
+				It("returns the path", func() {
+					rootFSPath, err := catalog.Resolve(path.Join(catalogPath, "lucid64") + "/")
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(rootFSPath).To(Equal(path.Join(catalogPath, "lucid64")))
+				})
+
+				Context("and the path does not exist", func() {
+					It("returns an UnknownRootFSError", func() {
+						_, err := catalog.Resolve("/does/not/exist")
+						Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"/does/not/exist"}))
+					})
+				})
+			})
+		})
+
+		Context("when no catalog directory is configured", func() {
+			BeforeEach(func() {
+				catalog = rootfs_catalog.New("", true)
+			})
+
+			It("returns an UnknownRootFSError for names", func() {
+				_, err := catalog.Resolve("lucid64")
+				Expect(err).To(Equal(rootfs_catalog.UnknownRootFSError{"lucid64"}))
+			})
+
+			It("still resolves absolute paths if they are allowed", func() {
+				rootFSPath, err := catalog.Resolve(path.Join(catalogPath, "lucid64"))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rootFSPath).To(Equal(path.Join(catalogPath, "lucid64")))
+			})
+		})
+	})
+})
@@ -11,8 +11,12 @@ type ContainerSnapshot struct {
 	ID     string
 	Handle string
 
+	RootFSPath string
+
 	GraceTime time.Duration
 
+	Properties backend.Properties
+
 	State  string
 	Events []string
 
@@ -22,6 +26,10 @@ type ContainerSnapshot struct {
 
 	Processes []ProcessSnapshot
 
+	Supervisors []SupervisorSnapshot
+
+	HealthCheck *HealthCheckSnapshot
+
 	NetIns  []NetInSpec
 	NetOuts []NetOutSpec
 }
@@ -40,5 +48,37 @@ type ResourcesSnapshot struct {
 }
 
 type ProcessSnapshot struct {
-	ID uint32
+	ID        uint32
+	Command   string
+	StartedAt time.Time
+	Timeout   time.Duration
+
+	// set once the process has finished
+	FinishedAt time.Time
+	ExitStatus *uint32
+	TimedOut   bool
+}
+
+type SupervisorSnapshot struct {
+	ID     uint32
+	Spec   backend.ProcessSpec
+	Policy backend.RestartPolicy
+
+	ProcessID      uint32
+	Running        bool
+	Restarts       int
+	LastExitStatus *uint32
+
+	Done    bool
+	Stopped bool
+}
+
+type HealthCheckSnapshot struct {
+	Spec backend.HealthCheckSpec
+
+	Status              backend.HealthStatus
+	ConsecutiveFailures int
+	LastCheckedAt       time.Time
+
+	Stopped bool
 }
@@ -155,6 +155,19 @@ int msg_user_export(msg__user_t *u, struct passwd *pw) {
   return 0;
 }
 
+int msg_dir_import(msg__dir_t *d, const char *path) {
+  int rv;
+
+  if (path != NULL) {
+    rv = snprintf(d->path, sizeof(d->path), "%s", path);
+    if (rv >= sizeof(d->path)) {
+      return -1;
+    }
+  }
+
+  return 0;
+}
+
 void msg_request_init(msg_request_t *req) {
   assert(sizeof(msg_request_t) <= MSG_MAX_SIZE);
   memset(req, 0, sizeof(*req));
@@ -1,8 +1,8 @@
 #ifndef MSG_H
 #define MSG_H 1
 
-#define MSG_VERSION 1
-#define MSG_MAX_SIZE (16 * 1024)
+#define MSG_VERSION 2
+#define MSG_MAX_SIZE (32 * 1024)
 
 #include <sys/time.h>
 #include <sys/resource.h>
@@ -12,6 +12,7 @@
 typedef struct msg__array_s msg__array_t;
 typedef struct msg__rlimit_s msg__rlimit_t;
 typedef struct msg__user_s msg__user_t;
+typedef struct msg__dir_s msg__dir_t;
 typedef struct msg_request_s msg_request_t;
 typedef struct msg_response_s msg_response_t;
 
@@ -32,16 +33,32 @@ struct msg__user_s {
   char name[32];
 };
 
+struct msg__dir_s {
+  char path[1024];
+};
+
 struct msg_request_s {
   int version;
   int tty;
   msg__array_t arg;
   msg__rlimit_t rlim;
   msg__user_t user;
+  msg__array_t env;
+  msg__dir_t dir;
+
+  /* Signal to send to process group of pid, instead of spawning a process */
+  int signal;
+  int pid;
 };
 
 struct msg_response_s {
   int version;
+
+  /* Pid of the spawned process, which leads its own process group */
+  int pid;
+
+  /* Errno of a failed request */
+  int error;
 };
 
 int msg_array_import(msg__array_t * a, int count, const char ** ptr);
@@ -53,6 +70,8 @@ int msg_rlimit_export(msg__rlimit_t *);
 int msg_user_import(msg__user_t *u, const char *name);
 int msg_user_export(msg__user_t *u, struct passwd *pw);
 
+int msg_dir_import(msg__dir_t *d, const char *path);
+
 void msg_request_init(msg_request_t *req);
 void msg_response_init(msg_response_t *res);
 
@@ -1,3 +1,4 @@
+#include <stdint.h>
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
@@ -24,11 +25,8 @@
       *(y) = '\0';                            \
   } while(0);
 
-/* Instead of using getpwnam from glibc, the following custom version is used
- * because we need to bypass dynamically loading the nsswitch libraries.
- * The version of glibc inside a container may be different than the version
- * that wshd is compiled for, leading to undefined behavior. */
-struct passwd *getpwnam(const char *name) {
+/* Find the entry matching name, or uid if name is NULL. */
+static struct passwd *getpw__find(const char *name, uint32_t uid) {
   static struct passwd passwd;
   static char buf[1024];
   struct passwd *_passwd = NULL;
@@ -47,7 +45,7 @@ struct passwd *getpwnam(const char *name) {
     /* Username */
     _GETPWNAM_NEXT(p, q);
 
-    if (strcmp(p, name) != 0) {
+    if (name != NULL && strcmp(p, name) != 0) {
       continue;
     }
 
@@ -59,11 +57,15 @@ struct passwd *getpwnam(const char *name) {
 
     /* User ID */
     _GETPWNAM_NEXT(p, q);
-    passwd.pw_uid = atoi(p);
+    passwd.pw_uid = strtoul(p, NULL, 10);
+
+    if (name == NULL && passwd.pw_uid != uid) {
+      continue;
+    }
 
     /* Group ID */
     _GETPWNAM_NEXT(p, q);
-    passwd.pw_gid = atoi(p);
+    passwd.pw_gid = strtoul(p, NULL, 10);
 
     /* User information */
     _GETPWNAM_NEXT(p, q);
@@ -90,4 +92,60 @@ done:
   return _passwd;
 }
 
+/* Instead of using getpwnam from glibc, the following custom version is used
+ * because we need to bypass dynamically loading the nsswitch libraries.
+ * The version of glibc inside a container may be different than the version
+ * that wshd is compiled for, leading to undefined behavior. */
+struct passwd *getpwnam(const char *name) {
+  return getpw__find(name, 0);
+}
+
+struct passwd *pwd_lookup(const char *user) {
+  static struct passwd passwd;
+  static char name[32];
+  struct passwd *pw;
+  unsigned long uid, gid;
+  char *end;
+
+  if (user[0] < '0' || user[0] > '9') {
+    return getpwnam(user);
+  }
+
+  uid = strtoul(user, &end, 10);
+
+  if (*end == ':' && end[1] >= '0' && end[1] <= '9') {
+    gid = strtoul(end + 1, &end, 10);
+  } else {
+    gid = uid;
+  }
+
+  /* Not numeric after all; names may start with a digit */
+  if (*end != '\0') {
+    return getpwnam(user);
+  }
+
+  pw = getpw__find(NULL, uid);
+  if (pw != NULL) {
+    /* Use the entry's name and home, but the group that was asked for */
+    if (strchr(user, ':') != NULL) {
+      pw->pw_gid = gid;
+    }
+
+    return pw;
+  }
+
+  /* Users without a passwd entry get a bare environment */
+  snprintf(name, sizeof(name), "%lu", uid);
+
+  passwd.pw_name = name;
+  passwd.pw_passwd = "";
+  passwd.pw_uid = uid;
+  passwd.pw_gid = gid;
+  passwd.pw_gecos = "";
+  passwd.pw_dir = "/";
+  passwd.pw_shell = "";
+
+  return &passwd;
+}
+
 #undef _GETPWNAM_NEXT
@@ -130,7 +130,18 @@ int un_recv_fds(int fd, char *data, int datalen, int *fds, int fdslen) {
 
   if (fds != NULL) {
     cmh = CMSG_FIRSTHDR(&mh);
-    assert(cmh != NULL);
+
+    /* Failed requests are answered without descriptors */
+    if (cmh == NULL) {
+      int i;
+
+      for (i = 0; i < fdslen; i++) {
+        fds[i] = -1;
+      }
+
+      goto done;
+    }
+
     assert(cmh->cmsg_level == SOL_SOCKET);
     assert(cmh->cmsg_type == SCM_RIGHTS);
     assert(cmh->cmsg_len == CMSG_LEN(sizeof(int) * fdslen));
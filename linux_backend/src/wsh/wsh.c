@@ -6,7 +6,9 @@
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
+#include <fcntl.h>
 #include <sys/ioctl.h>
+#include <sys/stat.h>
 #include <termios.h>
 #include <unistd.h>
 
@@ -25,6 +27,29 @@ struct wsh_s {
 
   /* User to change to */
   const char *user;
+
+  /* Allocate a pty even if stdin is not a terminal */
+  int tty;
+
+  /* Initial window size of the pty */
+  unsigned short columns;
+  unsigned short rows;
+
+  /* Path to fifo to read window size changes from */
+  const char *winsize_fifo;
+
+  /* Environment variables to set, as KEY=VALUE */
+  int envc;
+  const char **envv;
+
+  /* Working directory */
+  const char *dir;
+
+  /* Path to file holding the pid of the spawned process */
+  const char *pid_file;
+
+  /* Signal to send to the process in pid_file, instead of spawning one */
+  int signal;
 };
 
 int wsh__usage(wsh_t *w) {
@@ -39,6 +64,38 @@ int wsh__usage(wsh_t *w) {
     "User to change to"
     "\n");
 
+  fprintf(stderr, "  --env KEY=VALUE "
+    "Environment variable to set (may be repeated)"
+    "\n");
+
+  fprintf(stderr, "  --dir PATH    "
+    "Working directory"
+    "\n");
+
+  fprintf(stderr, "  --tty         "
+    "Allocate a pty even if stdin is not a terminal"
+    "\n");
+
+  fprintf(stderr, "  --columns N   "
+    "Initial number of columns of the pty"
+    "\n");
+
+  fprintf(stderr, "  --rows N      "
+    "Initial number of rows of the pty"
+    "\n");
+
+  fprintf(stderr, "  --winsize-fifo PATH "
+    "Create fifo to read \"COLUMNS ROWS\" window size changes from"
+    "\n");
+
+  fprintf(stderr, "  --pid-file PATH "
+    "Write pid of spawned process to PATH"
+    "\n");
+
+  fprintf(stderr, "  --signal N    "
+    "Send signal N to process group of the process in --pid-file"
+    "\n");
+
   fprintf(stderr, "  --rsh         "
     "RSH compatibility mode"
     "\n");
@@ -65,6 +122,40 @@ int wsh__getopt(wsh_t *w) {
       w->user = strdup(w->argv[i+1]);
       i += 2;
       j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--env") == 0) {
+      w->envv = realloc(w->envv, sizeof(w->envv[0]) * (w->envc + 1));
+      assert(w->envv != NULL);
+      w->envv[w->envc++] = strdup(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--dir") == 0) {
+      w->dir = strdup(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--pid-file") == 0) {
+      w->pid_file = strdup(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--signal") == 0) {
+      w->signal = atoi(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 1 && strcmp(w->argv[i], "--tty") == 0) {
+      w->tty = 1;
+      i += 1;
+      j -= 1;
+    } else if (j >= 2 && strcmp(w->argv[i], "--columns") == 0) {
+      w->columns = atoi(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--rows") == 0) {
+      w->rows = atoi(w->argv[i+1]);
+      i += 2;
+      j -= 2;
+    } else if (j >= 2 && strcmp(w->argv[i], "--winsize-fifo") == 0) {
+      w->winsize_fifo = strdup(w->argv[i+1]);
+      i += 2;
+      j -= 2;
     } else if (j >= 1 && strcmp(w->argv[i], "--rsh") == 0) {
       i += 1;
       j -= 1;
@@ -116,7 +207,9 @@ invalid:
   return -1;
 }
 
-void pump_loop(pump_t *p, int exit_status_fd, pump_pair_t *pp, int pplen) {
+void tty_read_winsz(int fd);
+
+void pump_loop(pump_t *p, int exit_status_fd, int winsize_fd, pump_pair_t *pp, int pplen) {
   int i, rv;
 
   for (;;) {
@@ -130,6 +223,10 @@ void pump_loop(pump_t *p, int exit_status_fd, pump_pair_t *pp, int pplen) {
       pump_add_fd(p, exit_status_fd, PUMP_READ | PUMP_EXCEPT);
     }
 
+    if (winsize_fd >= 0) {
+      pump_add_fd(p, winsize_fd, PUMP_READ);
+    }
+
     do {
       rv = pump_select(p);
     } while (rv == -1 && errno == EINTR);
@@ -143,6 +240,10 @@ void pump_loop(pump_t *p, int exit_status_fd, pump_pair_t *pp, int pplen) {
       pump_pair_copy(&pp[i]);
     }
 
+    if (winsize_fd >= 0 && pump_ready(p, winsize_fd, PUMP_READ)) {
+      tty_read_winsz(winsize_fd);
+    }
+
     if (pump_ready(p, exit_status_fd, PUMP_READ | PUMP_EXCEPT)) {
       int status;
 
@@ -215,6 +316,65 @@ void tty__sigwinch(int sig) {
   tty_swinsz();
 }
 
+static const char *winsize_fifo_path;
+
+void tty__unlink_winsize_fifo(void) {
+  unlink(winsize_fifo_path);
+}
+
+int tty_open_winsize_fifo(const char *path) {
+  int rv;
+  int fd;
+
+  unlink(path);
+
+  rv = mkfifo(path, 0600);
+  if (rv == -1) {
+    perror("mkfifo");
+    exit(255);
+  }
+
+  winsize_fifo_path = path;
+
+  rv = atexit(tty__unlink_winsize_fifo);
+  assert(rv != -1);
+
+  /* Open read-write so the fifo never reports EOF between writers */
+  fd = open(path, O_RDWR | O_NONBLOCK);
+  if (fd == -1) {
+    perror("open");
+    exit(255);
+  }
+
+  return fd;
+}
+
+void tty_read_winsz(int fd) {
+  char buf[256];
+  char *line, *next;
+  unsigned short columns, rows;
+  int rv;
+
+  rv = read(fd, buf, sizeof(buf) - 1);
+  if (rv <= 0) {
+    return;
+  }
+
+  buf[rv] = '\0';
+
+  /* Only the most recent complete size matters */
+  for (line = buf; (next = strchr(line, '\n')) != NULL; line = next + 1) {
+    *next = '\0';
+
+    if (sscanf(line, "%hu %hu", &columns, &rows) == 2) {
+      wsz.ws_col = columns;
+      wsz.ws_row = rows;
+    }
+  }
+
+  tty_swinsz();
+}
+
 void tty_winsz(void) {
   sighandler_t s;
 
@@ -227,12 +387,74 @@ void tty_winsz(void) {
   tty_swinsz();
 }
 
-void loop_interactive(int fd) {
+void check_response(wsh_t *w, msg_response_t *res) {
+  if (res->error == 0) {
+    return;
+  }
+
+  if (res->error == ENOENT) {
+    fprintf(stderr, "wsh: unknown user: %s\n", w->user != NULL ? w->user : "root");
+  } else {
+    fprintf(stderr, "wsh: %s\n", strerror(res->error));
+  }
+
+  exit(255);
+}
+
+static const char *pid_file_path;
+
+void pid_file__unlink(void) {
+  unlink(pid_file_path);
+}
+
+void pid_file_write(const char *path, int pid) {
+  FILE *f;
+  int rv;
+
+  f = fopen(path, "w");
+  if (f == NULL) {
+    perror("fopen");
+    exit(255);
+  }
+
+  fprintf(f, "%d\n", pid);
+  fclose(f);
+
+  pid_file_path = path;
+
+  rv = atexit(pid_file__unlink);
+  assert(rv != -1);
+}
+
+int pid_file_read(const char *path) {
+  FILE *f;
+  int pid;
+  int rv;
+
+  f = fopen(path, "r");
+  if (f == NULL) {
+    perror("fopen");
+    exit(255);
+  }
+
+  rv = fscanf(f, "%d", &pid);
+  fclose(f);
+
+  if (rv != 1) {
+    fprintf(stderr, "%s: no pid\n", path);
+    exit(255);
+  }
+
+  return pid;
+}
+
+void loop_interactive(wsh_t *w, int fd) {
   msg_response_t res;
   char buf[MSG_MAX_SIZE];
   size_t buflen = sizeof(buf);
   int fds[2];
   size_t fdslen = sizeof(fds)/sizeof(fds[0]);
+  int winsize_fd = -1;
   int rv;
 
   rv = un_recv_fds(fd, buf, buflen, fds, fdslen);
@@ -244,11 +466,27 @@ void loop_interactive(int fd) {
   assert(rv == sizeof(res));
   memcpy(&res, buf, sizeof(res));
 
+  check_response(w, &res);
+
+  if (w->pid_file != NULL) {
+    pid_file_write(w->pid_file, res.pid);
+  }
+
   pty_remote_fd = fds[0];
   pty_local_fd = STDIN_FILENO;
 
-  tty_raw();
-  tty_winsz();
+  if (isatty(pty_local_fd)) {
+    tty_raw();
+    tty_winsz();
+  } else if (w->columns > 0 && w->rows > 0) {
+    wsz.ws_col = w->columns;
+    wsz.ws_row = w->rows;
+    tty_swinsz();
+  }
+
+  if (w->winsize_fifo != NULL) {
+    winsize_fd = tty_open_winsize_fifo(w->winsize_fifo);
+  }
 
   pump_t p;
   pump_pair_t pp[2];
@@ -257,10 +495,10 @@ void loop_interactive(int fd) {
   pump_pair_init(&pp[0], &p, STDIN_FILENO, dup(fds[0]));
   pump_pair_init(&pp[1], &p, dup(fds[0]), STDOUT_FILENO);
 
-  pump_loop(&p, fds[1], pp, 2);
+  pump_loop(&p, fds[1], winsize_fd, pp, 2);
 }
 
-void loop_noninteractive(int fd) {
+void loop_noninteractive(wsh_t *w, int fd) {
   msg_response_t res;
   char buf[MSG_MAX_SIZE];
   size_t buflen = sizeof(buf);
@@ -277,6 +515,12 @@ void loop_noninteractive(int fd) {
   assert(rv == sizeof(res));
   memcpy(&res, buf, sizeof(res));
 
+  check_response(w, &res);
+
+  if (w->pid_file != NULL) {
+    pid_file_write(w->pid_file, res.pid);
+  }
+
   pump_t p;
   pump_pair_t pp[3];
 
@@ -284,7 +528,45 @@ void loop_noninteractive(int fd) {
   pump_pair_init(&pp[1], &p, fds[1], STDOUT_FILENO);
   pump_pair_init(&pp[2], &p, fds[2], STDERR_FILENO);
 
-  pump_loop(&p, fds[3], pp, 3);
+  pump_loop(&p, fds[3], -1, pp, 3);
+}
+
+void signal_process(wsh_t *w, int fd, msg_request_t *req) {
+  msg_response_t res;
+  char buf[MSG_MAX_SIZE];
+  size_t buflen = sizeof(buf);
+  int rv;
+
+  if (w->pid_file == NULL) {
+    fprintf(stderr, "--signal requires --pid-file\n");
+    exit(255);
+  }
+
+  req->signal = w->signal;
+  req->pid = pid_file_read(w->pid_file);
+
+  rv = un_send_fds(fd, (char *)req, sizeof(*req), NULL, 0);
+  if (rv <= 0) {
+    perror("sendmsg");
+    exit(255);
+  }
+
+  rv = un_recv_fds(fd, buf, buflen, NULL, 0);
+  if (rv <= 0) {
+    perror("recvmsg");
+    exit(255);
+  }
+
+  assert(rv == sizeof(res));
+  memcpy(&res, buf, sizeof(res));
+
+  if (res.error) {
+    errno = res.error;
+    perror("kill");
+    exit(1);
+  }
+
+  exit(0);
 }
 
 int main(int argc, char **argv) {
@@ -318,7 +600,11 @@ int main(int argc, char **argv) {
 
   msg_request_init(&req);
 
-  if (isatty(STDIN_FILENO)) {
+  if (w->signal) {
+    signal_process(w, fd, &req);
+  }
+
+  if (w->tty || isatty(STDIN_FILENO)) {
     req.tty = 1;
   } else {
     req.tty = 0;
@@ -342,6 +628,18 @@ int main(int argc, char **argv) {
     exit(255);
   }
 
+  rv = msg_array_import(&req.env, w->envc, w->envv);
+  if (rv == -1) {
+    fprintf(stderr, "msg_import_array: Too much data\n");
+    exit(255);
+  }
+
+  rv = msg_dir_import(&req.dir, w->dir);
+  if (rv == -1) {
+    fprintf(stderr, "msg_dir_import: Path too long\n");
+    exit(255);
+  }
+
   rv = un_send_fds(fd, (char *)&req, sizeof(req), NULL, 0);
   if (rv <= 0) {
     perror("sendmsg");
@@ -349,9 +647,9 @@ int main(int argc, char **argv) {
   }
 
   if (req.tty) {
-    loop_interactive(fd);
+    loop_interactive(w, fd);
   } else {
-    loop_noninteractive(fd);
+    loop_noninteractive(w, fd);
   }
 
   perror("unreachable");
@@ -174,6 +174,19 @@ void child_pid_to_fd_add(wshd_t *w, pid_t pid, int fd) {
   w->pid_to_fd_len++;
 }
 
+int child_pid_to_fd_contains(wshd_t *w, pid_t pid) {
+  int i;
+  int len = w->pid_to_fd_len;
+
+  for (i = 0; i < len; i++) {
+    if (w->pid_to_fd[i].pid == pid) {
+      return 1;
+    }
+  }
+
+  return 0;
+}
+
 int child_pid_to_fd_remove(wshd_t *w, pid_t pid) {
   int i;
   int len = w->pid_to_fd_len;
@@ -233,6 +246,40 @@ char **env__add(char **envp, const char *key, const char *value) {
   return envp;
 }
 
+char **env__set(char **envp, const char *keyvalue) {
+  const char *eq;
+  size_t keylen;
+  size_t i;
+  char *key;
+
+  eq = strchr(keyvalue, '=');
+  if (eq == NULL) {
+    /* Ignore malformed variables */
+    return envp;
+  }
+
+  keylen = eq - keyvalue;
+
+  /* Replace existing variable with the same key */
+  for (i = 0; envp != NULL && envp[i] != NULL; i++) {
+    if (strncmp(envp[i], keyvalue, keylen) == 0 && envp[i][keylen] == '=') {
+      free(envp[i]);
+      envp[i] = strdup(keyvalue);
+      assert(envp[i] != NULL);
+      return envp;
+    }
+  }
+
+  key = strndup(keyvalue, keylen);
+  assert(key != NULL);
+
+  envp = env__add(envp, key, eq + 1);
+
+  free(key);
+
+  return envp;
+}
+
 char **child_setup_environment(struct passwd *pw) {
   int rv;
   char **envp = NULL;
@@ -255,6 +302,29 @@ char **child_setup_environment(struct passwd *pw) {
   return envp;
 }
 
+const char *child_user(msg_request_t *req) {
+  if (!strlen(req->user.name)) {
+    return "root";
+  }
+
+  return req->user.name;
+}
+
+/* Answer a request that cannot be served without spawning anything */
+int child_reject(int fd, int error) {
+  msg_response_t res;
+
+  msg_response_init(&res);
+
+  res.error = error;
+
+  un_send_fds(fd, (char *)&res, sizeof(res), NULL, 0);
+
+  close(fd);
+
+  return 0;
+}
+
 int child_fork(msg_request_t *req, int in, int out, int err) {
   int rv;
 
@@ -271,6 +341,7 @@ int child_fork(msg_request_t *req, int in, int out, int err) {
     char *default_envp[] = { NULL };
     char **argv = default_argv;
     char **envp = default_envp;
+    int i;
 
     rv = dup2(in, STDIN_FILENO);
     assert(rv != -1);
@@ -284,14 +355,11 @@ int child_fork(msg_request_t *req, int in, int out, int err) {
     rv = setsid();
     assert(rv != -1);
 
-    user = req->user.name;
-    if (!strlen(user)) {
-      user = "root";
-    }
+    user = child_user(req);
 
-    pw = getpwnam(user);
+    pw = pwd_lookup(user);
     if (pw == NULL) {
-      perror("getpwnam");
+      fprintf(stderr, "unknown user: %s\n", user);
       goto error;
     }
 
@@ -326,6 +394,24 @@ int child_fork(msg_request_t *req, int in, int out, int err) {
     envp = child_setup_environment(pw);
     assert(envp != NULL);
 
+    /* Use environment and working directory from request if needed */
+    if (req->env.count) {
+      const char **env = msg_array_export(&req->env);
+      assert(env != NULL);
+
+      for (i = 0; env[i] != NULL; i++) {
+        envp = env__set(envp, env[i]);
+      }
+    }
+
+    if (strlen(req->dir.path)) {
+      rv = chdir(req->dir.path);
+      if (rv == -1) {
+        perror("chdir");
+        goto error;
+      }
+    }
+
     execvpe(argv[0], argv, envp);
     perror("execvpe");
 
@@ -374,16 +460,18 @@ int child_handle_interactive(int fd, wshd_t *w, msg_request_t *req) {
   p_[0] = p[0][0];
   p_[1] = p[1][0];
 
-  rv = un_send_fds(fd, (char *)&res, sizeof(res), p_, 2);
-  if (rv == -1) {
-    goto err;
-  }
-
   rv = child_fork(req, p[0][1], p[0][1], p[0][1]);
   assert(rv > 0);
 
   child_pid_to_fd_add(w, rv, p[1][1]);
 
+  res.pid = rv;
+
+  rv = un_send_fds(fd, (char *)&res, sizeof(res), p_, 2);
+  if (rv == -1) {
+    goto err;
+  }
+
 err:
   for (i = 0; i < 2; i++) {
     for (j = 0; j < 2; j++) {
@@ -435,16 +523,18 @@ int child_handle_noninteractive(int fd, wshd_t *w, msg_request_t *req) {
   p_[2] = p[2][0];
   p_[3] = p[3][0];
 
-  rv = un_send_fds(fd, (char *)&res, sizeof(res), p_, 4);
-  if (rv == -1) {
-    goto err;
-  }
-
   rv = child_fork(req, p[0][0], p[1][1], p[2][1]);
   assert(rv > 0);
 
   child_pid_to_fd_add(w, rv, p[3][1]);
 
+  res.pid = rv;
+
+  rv = un_send_fds(fd, (char *)&res, sizeof(res), p_, 4);
+  if (rv == -1) {
+    goto err;
+  }
+
 err:
   for (i = 0; i < 4; i++) {
     for (j = 0; j < 2; j++) {
@@ -463,6 +553,32 @@ err:
   return 0;
 }
 
+int child_handle_signal(int fd, wshd_t *w, msg_request_t *req) {
+  int rv;
+  msg_response_t res;
+
+  msg_response_init(&res);
+
+  res.pid = req->pid;
+
+  /* Only signal processes spawned by wshd that have yet to be reaped */
+  if (req->pid <= 0 || !child_pid_to_fd_contains(w, req->pid)) {
+    res.error = ESRCH;
+  } else {
+    /* Spawned processes lead their own process group (see child_fork) */
+    rv = kill(-req->pid, req->signal);
+    if (rv == -1) {
+      res.error = errno;
+    }
+  }
+
+  un_send_fds(fd, (char *)&res, sizeof(res), NULL, 0);
+
+  close(fd);
+
+  return 0;
+}
+
 int child_accept(wshd_t *w) {
   int rv, fd;
   char buf[MSG_MAX_SIZE];
@@ -493,6 +609,14 @@ int child_accept(wshd_t *w) {
   assert(rv == sizeof(req));
   memcpy(&req, buf, sizeof(req));
 
+  if (req.signal) {
+    return child_handle_signal(fd, w, &req);
+  }
+
+  if (pwd_lookup(child_user(&req)) == NULL) {
+    return child_reject(fd, ENOENT);
+  }
+
   if (req.tty) {
     return child_handle_interactive(fd, w, &req);
   } else {
@@ -3,6 +3,8 @@ package uid_pool
 import (
 	"fmt"
 	"sync"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
 )
 
 type UnixUIDPool struct {
@@ -19,6 +21,10 @@ func (e PoolExhaustedError) Error() string {
 	return "UID pool is exhausted"
 }
 
+func (e PoolExhaustedError) Code() backend.ErrorCode {
+	return backend.ErrorCodeCapacityExhausted
+}
+
 type UIDTakenError struct {
 	UID uint32
 }
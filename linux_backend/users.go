@@ -0,0 +1,103 @@
+package linux_backend
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// isRootUser reports whether a process run as the user would have uid or
+// gid 0, resolving the user against the passwd file the way wshd does: a
+// name by its entry, a uid by its entry's group (or as its own gid if it
+// has no entry), and a uid:gid as given.
+func isRootUser(passwdPath, user string) (bool, error) {
+	uid, gid, numeric := parseNumericUser(user)
+	if !numeric {
+		entry, err := findPasswdEntry(passwdPath, func(name string, uid uint64) bool {
+			return name == user
+		})
+		if err != nil || entry == nil {
+			return false, err
+		}
+
+		return entry.uid == 0 || entry.gid == 0, nil
+	}
+
+	if uid == 0 || gid == 0 {
+		return true, nil
+	}
+
+	if strings.Contains(user, ":") {
+		return false, nil
+	}
+
+	entry, err := findPasswdEntry(passwdPath, func(name string, entryUID uint64) bool {
+		return entryUID == uid
+	})
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	return entry.gid == 0, nil
+}
+
+// parseNumericUser parses a "uid" or "uid:gid"; a lone uid is also its gid.
+func parseNumericUser(user string) (uid, gid uint64, ok bool) {
+	segs := strings.SplitN(user, ":", 2)
+
+	uid, err := strconv.ParseUint(segs[0], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if len(segs) == 1 {
+		return uid, uid, true
+	}
+
+	gid, err = strconv.ParseUint(segs[1], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return uid, gid, true
+}
+
+type passwdEntry struct {
+	uid uint64
+	gid uint64
+}
+
+func findPasswdEntry(passwdPath string, matches func(name string, uid uint64) bool) (*passwdEntry, error) {
+	file, err := os.Open(passwdPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 4 {
+			continue
+		}
+
+		uid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		gid, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		if matches(fields[0], uid) {
+			return &passwdEntry{uid: uid, gid: gid}, nil
+		}
+	}
+
+	return nil, scanner.Err()
+}
@@ -1,23 +1,27 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
 	"github.com/pivotal-cf-experimental/garden/command_runner"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/linux_backend"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/container_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/network_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/port_pool"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/quota_manager"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/rootfs_catalog"
 	"github.com/pivotal-cf-experimental/garden/linux_backend/uid_pool"
 	"github.com/pivotal-cf-experimental/garden/server"
 )
@@ -34,6 +38,60 @@ var listenAddr = flag.String(
 	"address to listen on",
 )
 
+var httpListenAddr = flag.String(
+	"httpListenAddr",
+	"",
+	"tcp address to serve the HTTP API on (disabled if empty; loopback only without -tlsClientCA)",
+)
+
+var tlsCert = flag.String(
+	"tlsCert",
+	"",
+	"certificate to serve TLS with (disabled if empty)",
+)
+
+var tlsKey = flag.String(
+	"tlsKey",
+	"",
+	"private key for the TLS certificate",
+)
+
+var tlsClientCA = flag.String(
+	"tlsClientCA",
+	"",
+	"CA certificates to require and verify client certificates against",
+)
+
+var admins = flag.String(
+	"admins",
+	"",
+	"comma-separated client certificate common names that may operate on any container",
+)
+
+var forbidPrivileged = flag.Bool(
+	"forbidPrivileged",
+	false,
+	"forbid non-admin clients from running privileged processes",
+)
+
+var forbidHostBindMounts = flag.Bool(
+	"forbidHostBindMounts",
+	false,
+	"forbid non-admin clients from bind mounting paths from the host",
+)
+
+var hostCopyRoot = flag.String(
+	"hostCopyRoot",
+	"",
+	"directory on the host that non-admin clients may copy files in from and out to",
+)
+
+var ownersPath = flag.String(
+	"owners",
+	"",
+	"file to persist which client created each container in",
+)
+
 var snapshotsPath = flag.String(
 	"snapshots",
 	"",
@@ -64,6 +122,18 @@ var rootFSPath = flag.String(
 	"directory of the rootfs for the containers",
 )
 
+var rootFSCatalogPath = flag.String(
+	"rootfsCatalog",
+	"",
+	"directory of named rootfses that containers may request instead of the default",
+)
+
+var allowRootFSPaths = flag.Bool(
+	"allowRootFSPaths",
+	false,
+	"let containers request any directory on the host as their rootfs (forbidden to non-admins with -tlsClientCA)",
+)
+
 var disableQuotas = flag.Bool(
 	"disableQuotas",
 	false,
@@ -76,6 +146,12 @@ var containerGraceTime = flag.Int(
 	"time (in seconds) after which to destroy idle containers",
 )
 
+var processOutputHistory = flag.Uint64(
+	"processOutputHistory",
+	1024*1024,
+	"bytes of each process's stdout and stderr to retain for clients attaching from an offset",
+)
+
 var debug = flag.Bool(
 	"debug",
 	false,
@@ -92,6 +168,8 @@ func main() {
 
 	var backend backend.Backend
 
+	eventHub := event_hub.New()
+
 	switch *backendName {
 	case "linux":
 		if *binPath == "" {
@@ -135,14 +213,17 @@ func main() {
 			*binPath,
 			*depotPath,
 			*rootFSPath,
+			rootfs_catalog.New(*rootFSCatalogPath, *allowRootFSPaths),
 			uidPool,
 			networkPool,
 			portPool,
 			runner,
 			quotaManager,
+			*processOutputHistory,
+			eventHub,
 		)
 
-		backend = linux_backend.New(pool, *snapshotsPath)
+		backend = linux_backend.New(pool, quotaManager, *snapshotsPath)
 	case "fake":
 		backend = fake_backend.New()
 	}
@@ -158,13 +239,57 @@ func main() {
 
 	graceTime := time.Duration(*containerGraceTime) * time.Second
 
-	wardenServer := server.New(*listenNetwork, *listenAddr, graceTime, backend)
+	var tlsConfig *tls.Config
+
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err = server.NewTLSConfig(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			log.Fatalln("failed to configure TLS:", err)
+		}
+	} else if *tlsClientCA != "" {
+		log.Fatalln("-tlsClientCA requires -tlsCert and -tlsKey")
+	}
+
+	var authorizer *server.Authorizer
+
+	// clients can only be told apart if they authenticate with certificates
+	if *tlsClientCA == "" {
+		for _, name := range []string{"admins", "forbidPrivileged", "forbidHostBindMounts", "hostCopyRoot", "owners"} {
+			if flagSet(name) {
+				log.Fatalf("-%s requires -tlsClientCA\n", name)
+			}
+		}
+	} else {
+		policy := server.AuthorizationPolicy{
+			ForbidPrivileged:     *forbidPrivileged,
+			ForbidHostBindMounts: *forbidHostBindMounts,
+			HostCopyRoot:         *hostCopyRoot,
+		}
+
+		if *admins != "" {
+			policy.Admins = strings.Split(*admins, ",")
+		}
+
+		authorizer, err = server.NewAuthorizer(policy, *ownersPath)
+		if err != nil {
+			log.Fatalln("failed to configure authorization:", err)
+		}
+	}
+
+	wardenServer := server.New(*listenNetwork, *listenAddr, graceTime, backend, eventHub, tlsConfig, authorizer)
 
 	err = wardenServer.Start()
 	if err != nil {
 		log.Fatalln("failed to start:", err)
 	}
 
+	if *httpListenAddr != "" {
+		err = wardenServer.StartHTTP("tcp", *httpListenAddr)
+		if err != nil {
+			log.Fatalln("failed to start HTTP API:", err)
+		}
+	}
+
 	signals := make(chan os.Signal, 1)
 
 	go func() {
@@ -178,3 +303,15 @@ func main() {
 
 	select {}
 }
+
+func flagSet(name string) bool {
+	set := false
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
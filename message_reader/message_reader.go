@@ -15,6 +15,10 @@ type WardenError struct {
 	Message   string
 	Data      string
 	Backtrace []string
+
+	// Code says what kind of error it was, e.g. so that a request can be
+	// retried if the server was out of capacity.
+	Code protocol.ErrorResponse_Code
 }
 
 func (e *WardenError) Error() string {
@@ -35,21 +39,38 @@ func (e *TypeMismatchError) Error() string {
 }
 
 func ReadMessage(read *bufio.Reader, response proto.Message) error {
-	payload, err := readPayload(read)
+	message, err := ReadEnvelope(read)
 	if err != nil {
 		return err
 	}
 
+	return DecodeResponse(message, response)
+}
+
+// ReadEnvelope reads the next framed message without decoding its payload,
+// e.g. to see which multiplexed request it belongs to.
+func ReadEnvelope(read *bufio.Reader) (*protocol.Message, error) {
+	payload, err := readPayload(read)
+	if err != nil {
+		return nil, err
+	}
+
 	message := &protocol.Message{}
 	err = proto.Unmarshal(payload, message)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return message, nil
+}
+
+// DecodeResponse decodes a message's payload into the response, returning
+// a *WardenError if the server responded with an error instead.
+func DecodeResponse(message *protocol.Message, response proto.Message) error {
 	// error response from server
 	if message.GetType() == protocol.Message_Type(1) {
 		errorResponse := &protocol.ErrorResponse{}
-		err = proto.Unmarshal(message.Payload, errorResponse)
+		err := proto.Unmarshal(message.Payload, errorResponse)
 		if err != nil {
 			return errors.New("error unmarshalling error!")
 		}
@@ -58,6 +79,7 @@ func ReadMessage(read *bufio.Reader, response proto.Message) error {
 			Message:   errorResponse.GetMessage(),
 			Data:      errorResponse.GetData(),
 			Backtrace: errorResponse.GetBacktrace(),
+			Code:      errorResponse.GetCode(),
 		}
 	}
 
@@ -73,25 +95,26 @@ func ReadMessage(read *bufio.Reader, response proto.Message) error {
 }
 
 func ReadRequest(read *bufio.Reader) (proto.Message, error) {
-	payload, err := readPayload(read)
-	if err != nil {
-		return nil, err
-	}
+	request, _, err := ReadRequestWithID(read)
+	return request, err
+}
 
-	message := &protocol.Message{}
-	err = proto.Unmarshal(payload, message)
+// ReadRequestWithID reads the next request along with the ID it was tagged
+// with, which is nil for requests that are not multiplexed.
+func ReadRequestWithID(read *bufio.Reader) (proto.Message, *uint32, error) {
+	message, err := ReadEnvelope(read)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	request := protocol.RequestMessageForType(message.GetType())
 
 	err = proto.Unmarshal(message.GetPayload(), request)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return request, nil
+	return request, message.RequestId, nil
 }
 
 func readPayload(read *bufio.Reader) ([]byte, error) {
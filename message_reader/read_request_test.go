@@ -32,6 +32,29 @@ var _ = Describe("Reading request messages over the wire", func() {
 		})
 	})
 
+	Context("when a request is tagged with a request ID", func() {
+		It("returns the request and its ID", func() {
+			payload := bufio.NewReader(
+				protocol.MessagesForRequest(42, &protocol.PingRequest{}),
+			)
+
+			request, requestID, err := message_reader.ReadRequestWithID(payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request).To(Equal(&protocol.PingRequest{}))
+			Expect(requestID).To(Equal(proto.Uint32(42)))
+		})
+	})
+
+	Context("when a request is not tagged with a request ID", func() {
+		It("returns a nil ID", func() {
+			payload := bufio.NewReader(protocol.Messages(&protocol.PingRequest{}))
+
+			_, requestID, err := message_reader.ReadRequestWithID(payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestID).To(BeNil())
+		})
+	})
+
 	Context("when the connection is broken", func() {
 		It("returns an error", func() {
 			payload := protocol.Messages(&protocol.PingRequest{})
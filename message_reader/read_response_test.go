@@ -31,6 +31,24 @@ var _ = Describe("Reading response messages over the wire", func() {
 		})
 	})
 
+	Context("when a message is tagged with a request ID", func() {
+		It("can be read and decoded separately", func() {
+			message, err := message_reader.ReadEnvelope(
+				bufio.NewReader(protocol.MessagesForRequest(42, &protocol.EchoResponse{
+					Message: proto.String("some message"),
+				})),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(message.GetRequestId()).To(Equal(uint32(42)))
+
+			var echoResponse protocol.EchoResponse
+
+			err = message_reader.DecodeResponse(message, &echoResponse)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(echoResponse.GetMessage()).To(Equal("some message"))
+		})
+	})
+
 	Context("when the connection is broken", func() {
 		It("returns an error", func() {
 			var dummyResponse protocol.PingResponse
@@ -58,6 +76,7 @@ var _ = Describe("Reading response messages over the wire", func() {
 						"backtrace line 1",
 						"backtrace line 2",
 					},
+					Code: protocol.ErrorResponse_capacity_exhausted.Enum(),
 				})),
 				&dummyResponse,
 			)
@@ -70,9 +89,30 @@ var _ = Describe("Reading response messages over the wire", func() {
 						"backtrace line 1",
 						"backtrace line 2",
 					},
+					Code: protocol.ErrorResponse_capacity_exhausted,
 				},
 			))
 		})
+
+		Context("without a code", func() {
+			It("returns a WardenError of unknown kind", func() {
+				var dummyResponse protocol.PingResponse
+
+				err := message_reader.ReadMessage(
+					bufio.NewReader(protocol.Messages(&protocol.ErrorResponse{
+						Message: proto.String("some message"),
+					})),
+					&dummyResponse,
+				)
+
+				Expect(err).To(Equal(
+					&message_reader.WardenError{
+						Message: "some message",
+						Code:    protocol.ErrorResponse_unknown,
+					},
+				))
+			})
+		})
 	})
 
 	Context("when a message of the wrong type is received", func() {
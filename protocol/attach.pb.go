@@ -16,6 +16,9 @@ var _ = math.Inf
 type AttachRequest struct {
 	Handle           *string `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
 	ProcessId        *uint32 `protobuf:"varint,2,req,name=process_id" json:"process_id,omitempty"`
+	BinaryPayloads   *bool   `protobuf:"varint,3,opt,name=binary_payloads,def=0" json:"binary_payloads,omitempty"`
+	StdoutOffset     *uint64 `protobuf:"varint,4,opt,name=stdout_offset" json:"stdout_offset,omitempty"`
+	StderrOffset     *uint64 `protobuf:"varint,5,opt,name=stderr_offset" json:"stderr_offset,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -23,6 +26,8 @@ func (m *AttachRequest) Reset()         { *m = AttachRequest{} }
 func (m *AttachRequest) String() string { return proto.CompactTextString(m) }
 func (*AttachRequest) ProtoMessage()    {}
 
+const Default_AttachRequest_BinaryPayloads bool = false
+
 func (m *AttachRequest) GetHandle() string {
 	if m != nil && m.Handle != nil {
 		return *m.Handle
@@ -37,5 +42,26 @@ func (m *AttachRequest) GetProcessId() uint32 {
 	return 0
 }
 
+func (m *AttachRequest) GetBinaryPayloads() bool {
+	if m != nil && m.BinaryPayloads != nil {
+		return *m.BinaryPayloads
+	}
+	return Default_AttachRequest_BinaryPayloads
+}
+
+func (m *AttachRequest) GetStdoutOffset() uint64 {
+	if m != nil && m.StdoutOffset != nil {
+		return *m.StdoutOffset
+	}
+	return 0
+}
+
+func (m *AttachRequest) GetStderrOffset() uint64 {
+	if m != nil && m.StderrOffset != nil {
+		return *m.StderrOffset
+	}
+	return 0
+}
+
 func init() {
 }
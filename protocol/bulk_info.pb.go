@@ -0,0 +1,81 @@
+// Code generated by protoc-gen-gogo.
+// source: bulk_info.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type BulkInfoRequest struct {
+	Handles          []string `protobuf:"bytes,1,rep,name=handles" json:"handles,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *BulkInfoRequest) Reset()         { *m = BulkInfoRequest{} }
+func (m *BulkInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*BulkInfoRequest) ProtoMessage()    {}
+
+func (m *BulkInfoRequest) GetHandles() []string {
+	if m != nil {
+		return m.Handles
+	}
+	return nil
+}
+
+type BulkInfoResponse struct {
+	Entries          []*BulkInfoResponse_Entry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+	XXX_unrecognized []byte                    `json:"-"`
+}
+
+func (m *BulkInfoResponse) Reset()         { *m = BulkInfoResponse{} }
+func (m *BulkInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*BulkInfoResponse) ProtoMessage()    {}
+
+func (m *BulkInfoResponse) GetEntries() []*BulkInfoResponse_Entry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type BulkInfoResponse_Entry struct {
+	Handle           *string        `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	Info             *InfoResponse  `protobuf:"bytes,2,opt,name=info" json:"info,omitempty"`
+	Error            *ErrorResponse `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (m *BulkInfoResponse_Entry) Reset()         { *m = BulkInfoResponse_Entry{} }
+func (m *BulkInfoResponse_Entry) String() string { return proto.CompactTextString(m) }
+func (*BulkInfoResponse_Entry) ProtoMessage()    {}
+
+func (m *BulkInfoResponse_Entry) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+func (m *BulkInfoResponse_Entry) GetInfo() *InfoResponse {
+	if m != nil {
+		return m.Info
+	}
+	return nil
+}
+
+func (m *BulkInfoResponse_Entry) GetError() *ErrorResponse {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func init() {
+}
@@ -17,6 +17,7 @@ type CopyInRequest struct {
 	Handle           *string `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
 	SrcPath          *string `protobuf:"bytes,2,req,name=src_path" json:"src_path,omitempty"`
 	DstPath          *string `protobuf:"bytes,3,req,name=dst_path" json:"dst_path,omitempty"`
+	User             *string `protobuf:"bytes,4,opt,name=user" json:"user,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -45,6 +46,13 @@ func (m *CopyInRequest) GetDstPath() string {
 	return ""
 }
 
+func (m *CopyInRequest) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
 type CopyInResponse struct {
 	XXX_unrecognized []byte `json:"-"`
 }
@@ -18,6 +18,7 @@ type CopyOutRequest struct {
 	SrcPath          *string `protobuf:"bytes,2,req,name=src_path" json:"src_path,omitempty"`
 	DstPath          *string `protobuf:"bytes,3,req,name=dst_path" json:"dst_path,omitempty"`
 	Owner            *string `protobuf:"bytes,4,opt,name=owner" json:"owner,omitempty"`
+	User             *string `protobuf:"bytes,5,opt,name=user" json:"user,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -53,6 +54,13 @@ func (m *CopyOutRequest) GetOwner() string {
 	return ""
 }
 
+func (m *CopyOutRequest) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
 type CopyOutResponse struct {
 	XXX_unrecognized []byte `json:"-"`
 }
@@ -85,6 +85,7 @@ type CreateRequest struct {
 	Handle           *string                    `protobuf:"bytes,3,opt,name=handle" json:"handle,omitempty"`
 	Network          *string                    `protobuf:"bytes,4,opt,name=network" json:"network,omitempty"`
 	Rootfs           *string                    `protobuf:"bytes,5,opt,name=rootfs" json:"rootfs,omitempty"`
+	Properties       []*Property                `protobuf:"bytes,6,rep,name=properties" json:"properties,omitempty"`
 	XXX_unrecognized []byte                     `json:"-"`
 }
 
@@ -127,6 +128,13 @@ func (m *CreateRequest) GetRootfs() string {
 	return ""
 }
 
+func (m *CreateRequest) GetProperties() []*Property {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
 type CreateRequest_BindMount struct {
 	SrcPath          *string                         `protobuf:"bytes,1,req,name=src_path" json:"src_path,omitempty"`
 	DstPath          *string                         `protobuf:"bytes,2,req,name=dst_path" json:"dst_path,omitempty"`
@@ -0,0 +1,41 @@
+// Code generated by protoc-gen-gogo.
+// source: environment_variable.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type EnvironmentVariable struct {
+	Key              *string `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *EnvironmentVariable) Reset()         { *m = EnvironmentVariable{} }
+func (m *EnvironmentVariable) String() string { return proto.CompactTextString(m) }
+func (*EnvironmentVariable) ProtoMessage()    {}
+
+func (m *EnvironmentVariable) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *EnvironmentVariable) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func init() {
+}
@@ -13,17 +13,68 @@ var _ = proto.Marshal
 var _ = &json.SyntaxError{}
 var _ = math.Inf
 
+type ErrorResponse_Code int32
+
+const (
+	ErrorResponse_unknown            ErrorResponse_Code = 0
+	ErrorResponse_unknown_handle     ErrorResponse_Code = 1
+	ErrorResponse_unknown_process    ErrorResponse_Code = 2
+	ErrorResponse_capacity_exhausted ErrorResponse_Code = 3
+	ErrorResponse_invalid_request    ErrorResponse_Code = 4
+	ErrorResponse_not_permitted      ErrorResponse_Code = 5
+	ErrorResponse_timed_out          ErrorResponse_Code = 6
+)
+
+var ErrorResponse_Code_name = map[int32]string{
+	0: "unknown",
+	1: "unknown_handle",
+	2: "unknown_process",
+	3: "capacity_exhausted",
+	4: "invalid_request",
+	5: "not_permitted",
+	6: "timed_out",
+}
+var ErrorResponse_Code_value = map[string]int32{
+	"unknown":            0,
+	"unknown_handle":     1,
+	"unknown_process":    2,
+	"capacity_exhausted": 3,
+	"invalid_request":    4,
+	"not_permitted":      5,
+	"timed_out":          6,
+}
+
+func (x ErrorResponse_Code) Enum() *ErrorResponse_Code {
+	p := new(ErrorResponse_Code)
+	*p = x
+	return p
+}
+func (x ErrorResponse_Code) String() string {
+	return proto.EnumName(ErrorResponse_Code_name, int32(x))
+}
+func (x *ErrorResponse_Code) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(ErrorResponse_Code_value, data, "ErrorResponse_Code")
+	if err != nil {
+		return err
+	}
+	*x = ErrorResponse_Code(value)
+	return nil
+}
+
 type ErrorResponse struct {
-	Message          *string  `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
-	Data             *string  `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
-	Backtrace        []string `protobuf:"bytes,3,rep,name=backtrace" json:"backtrace,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	Message          *string             `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Data             *string             `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
+	Backtrace        []string            `protobuf:"bytes,3,rep,name=backtrace" json:"backtrace,omitempty"`
+	Code             *ErrorResponse_Code `protobuf:"varint,5,opt,name=code,enum=warden.ErrorResponse_Code,def=0" json:"code,omitempty"`
+	XXX_unrecognized []byte              `json:"-"`
 }
 
 func (m *ErrorResponse) Reset()         { *m = ErrorResponse{} }
 func (m *ErrorResponse) String() string { return proto.CompactTextString(m) }
 func (*ErrorResponse) ProtoMessage()    {}
 
+const Default_ErrorResponse_Code ErrorResponse_Code = ErrorResponse_unknown
+
 func (m *ErrorResponse) GetMessage() string {
 	if m != nil && m.Message != nil {
 		return *m.Message
@@ -45,5 +96,13 @@ func (m *ErrorResponse) GetBacktrace() []string {
 	return nil
 }
 
+func (m *ErrorResponse) GetCode() ErrorResponse_Code {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return Default_ErrorResponse_Code
+}
+
 func init() {
+	proto.RegisterEnum("warden.ErrorResponse_Code", ErrorResponse_Code_name, ErrorResponse_Code_value)
 }
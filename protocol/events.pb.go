@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-gogo.
+// source: events.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type EventsRequest struct {
+	Handles          []string `protobuf:"bytes,1,rep,name=handles" json:"handles,omitempty"`
+	Types            []string `protobuf:"bytes,2,rep,name=types" json:"types,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *EventsRequest) Reset()         { *m = EventsRequest{} }
+func (m *EventsRequest) String() string { return proto.CompactTextString(m) }
+func (*EventsRequest) ProtoMessage()    {}
+
+func (m *EventsRequest) GetHandles() []string {
+	if m != nil {
+		return m.Handles
+	}
+	return nil
+}
+
+func (m *EventsRequest) GetTypes() []string {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+type Event struct {
+	Type             *string         `protobuf:"bytes,1,req,name=type" json:"type,omitempty"`
+	Time             *uint64         `protobuf:"varint,2,req,name=time" json:"time,omitempty"`
+	Handle           *string         `protobuf:"bytes,3,req,name=handle" json:"handle,omitempty"`
+	Details          []*Event_Detail `protobuf:"bytes,4,rep,name=details" json:"details,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+func (m *Event) GetType() string {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return ""
+}
+
+func (m *Event) GetTime() uint64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *Event) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+func (m *Event) GetDetails() []*Event_Detail {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+type Event_Detail struct {
+	Key              *string `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Event_Detail) Reset()         { *m = Event_Detail{} }
+func (m *Event_Detail) String() string { return proto.CompactTextString(m) }
+func (*Event_Detail) ProtoMessage()    {}
+
+func (m *Event_Detail) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *Event_Detail) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func init() {
+}
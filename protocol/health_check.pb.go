@@ -0,0 +1,132 @@
+// Code generated by protoc-gen-gogo.
+// source: health_check.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type HealthCheckRequest struct {
+	Handle           *string                `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	Script           *string                `protobuf:"bytes,2,opt,name=script" json:"script,omitempty"`
+	Privileged       *bool                  `protobuf:"varint,3,opt,name=privileged,def=0" json:"privileged,omitempty"`
+	Path             *string                `protobuf:"bytes,4,opt,name=path" json:"path,omitempty"`
+	Args             []string               `protobuf:"bytes,5,rep,name=args" json:"args,omitempty"`
+	Env              []*EnvironmentVariable `protobuf:"bytes,6,rep,name=env" json:"env,omitempty"`
+	Dir              *string                `protobuf:"bytes,7,opt,name=dir" json:"dir,omitempty"`
+	User             *string                `protobuf:"bytes,8,opt,name=user" json:"user,omitempty"`
+	Interval         *uint32                `protobuf:"varint,9,opt,name=interval" json:"interval,omitempty"`
+	Timeout          *uint32                `protobuf:"varint,10,opt,name=timeout" json:"timeout,omitempty"`
+	FailureThreshold *uint32                `protobuf:"varint,11,opt,name=failure_threshold" json:"failure_threshold,omitempty"`
+	Remove           *bool                  `protobuf:"varint,12,opt,name=remove,def=0" json:"remove,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (m *HealthCheckRequest) Reset()         { *m = HealthCheckRequest{} }
+func (m *HealthCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*HealthCheckRequest) ProtoMessage()    {}
+
+const Default_HealthCheckRequest_Privileged bool = false
+const Default_HealthCheckRequest_Remove bool = false
+
+func (m *HealthCheckRequest) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+func (m *HealthCheckRequest) GetScript() string {
+	if m != nil && m.Script != nil {
+		return *m.Script
+	}
+	return ""
+}
+
+func (m *HealthCheckRequest) GetPrivileged() bool {
+	if m != nil && m.Privileged != nil {
+		return *m.Privileged
+	}
+	return Default_HealthCheckRequest_Privileged
+}
+
+func (m *HealthCheckRequest) GetPath() string {
+	if m != nil && m.Path != nil {
+		return *m.Path
+	}
+	return ""
+}
+
+func (m *HealthCheckRequest) GetArgs() []string {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *HealthCheckRequest) GetEnv() []*EnvironmentVariable {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+func (m *HealthCheckRequest) GetDir() string {
+	if m != nil && m.Dir != nil {
+		return *m.Dir
+	}
+	return ""
+}
+
+func (m *HealthCheckRequest) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
+func (m *HealthCheckRequest) GetInterval() uint32 {
+	if m != nil && m.Interval != nil {
+		return *m.Interval
+	}
+	return 0
+}
+
+func (m *HealthCheckRequest) GetTimeout() uint32 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *HealthCheckRequest) GetFailureThreshold() uint32 {
+	if m != nil && m.FailureThreshold != nil {
+		return *m.FailureThreshold
+	}
+	return 0
+}
+
+func (m *HealthCheckRequest) GetRemove() bool {
+	if m != nil && m.Remove != nil {
+		return *m.Remove
+	}
+	return Default_HealthCheckRequest_Remove
+}
+
+type HealthCheckResponse struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *HealthCheckResponse) Reset()         { *m = HealthCheckResponse{} }
+func (m *HealthCheckResponse) String() string { return proto.CompactTextString(m) }
+func (*HealthCheckResponse) ProtoMessage()    {}
+
+func init() {
+}
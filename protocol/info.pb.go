@@ -30,17 +30,20 @@ func (m *InfoRequest) GetHandle() string {
 }
 
 type InfoResponse struct {
-	State            *string                     `protobuf:"bytes,10,opt,name=state" json:"state,omitempty"`
-	Events           []string                    `protobuf:"bytes,20,rep,name=events" json:"events,omitempty"`
-	HostIp           *string                     `protobuf:"bytes,30,opt,name=host_ip" json:"host_ip,omitempty"`
-	ContainerIp      *string                     `protobuf:"bytes,31,opt,name=container_ip" json:"container_ip,omitempty"`
-	ContainerPath    *string                     `protobuf:"bytes,32,opt,name=container_path" json:"container_path,omitempty"`
-	MemoryStat       *InfoResponse_MemoryStat    `protobuf:"bytes,40,opt,name=memory_stat" json:"memory_stat,omitempty"`
-	CpuStat          *InfoResponse_CpuStat       `protobuf:"bytes,41,opt,name=cpu_stat" json:"cpu_stat,omitempty"`
-	DiskStat         *InfoResponse_DiskStat      `protobuf:"bytes,42,opt,name=disk_stat" json:"disk_stat,omitempty"`
-	BandwidthStat    *InfoResponse_BandwidthStat `protobuf:"bytes,43,opt,name=bandwidth_stat" json:"bandwidth_stat,omitempty"`
-	ProcessIds       []uint64                    `protobuf:"varint,44,rep,name=process_ids" json:"process_ids,omitempty"`
-	XXX_unrecognized []byte                      `json:"-"`
+	State               *string                           `protobuf:"bytes,10,opt,name=state" json:"state,omitempty"`
+	Events              []string                          `protobuf:"bytes,20,rep,name=events" json:"events,omitempty"`
+	HostIp              *string                           `protobuf:"bytes,30,opt,name=host_ip" json:"host_ip,omitempty"`
+	ContainerIp         *string                           `protobuf:"bytes,31,opt,name=container_ip" json:"container_ip,omitempty"`
+	ContainerPath       *string                           `protobuf:"bytes,32,opt,name=container_path" json:"container_path,omitempty"`
+	MemoryStat          *InfoResponse_MemoryStat          `protobuf:"bytes,40,opt,name=memory_stat" json:"memory_stat,omitempty"`
+	CpuStat             *InfoResponse_CpuStat             `protobuf:"bytes,41,opt,name=cpu_stat" json:"cpu_stat,omitempty"`
+	DiskStat            *InfoResponse_DiskStat            `protobuf:"bytes,42,opt,name=disk_stat" json:"disk_stat,omitempty"`
+	BandwidthStat       *InfoResponse_BandwidthStat       `protobuf:"bytes,43,opt,name=bandwidth_stat" json:"bandwidth_stat,omitempty"`
+	ProcessIds          []uint64                          `protobuf:"varint,44,rep,name=process_ids" json:"process_ids,omitempty"`
+	SupervisedProcesses []*InfoResponse_SupervisedProcess `protobuf:"bytes,45,rep,name=supervised_processes" json:"supervised_processes,omitempty"`
+	HealthCheck         *InfoResponse_HealthCheck         `protobuf:"bytes,46,opt,name=health_check" json:"health_check,omitempty"`
+	Properties          []*Property                       `protobuf:"bytes,47,rep,name=properties" json:"properties,omitempty"`
+	XXX_unrecognized    []byte                            `json:"-"`
 }
 
 func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
@@ -117,6 +120,27 @@ func (m *InfoResponse) GetProcessIds() []uint64 {
 	return nil
 }
 
+func (m *InfoResponse) GetSupervisedProcesses() []*InfoResponse_SupervisedProcess {
+	if m != nil {
+		return m.SupervisedProcesses
+	}
+	return nil
+}
+
+func (m *InfoResponse) GetHealthCheck() *InfoResponse_HealthCheck {
+	if m != nil {
+		return m.HealthCheck
+	}
+	return nil
+}
+
+func (m *InfoResponse) GetProperties() []*Property {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
 type InfoResponse_MemoryStat struct {
 	Cache                   *uint64 `protobuf:"varint,1,opt,name=cache" json:"cache,omitempty"`
 	Rss                     *uint64 `protobuf:"varint,2,opt,name=rss" json:"rss,omitempty"`
@@ -445,5 +469,125 @@ func (m *InfoResponse_BandwidthStat) GetOutBurst() uint64 {
 	return 0
 }
 
+type InfoResponse_SupervisedProcess struct {
+	Id               *uint32             `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	ProcessId        *uint32             `protobuf:"varint,2,opt,name=process_id" json:"process_id,omitempty"`
+	Running          *bool               `protobuf:"varint,3,opt,name=running" json:"running,omitempty"`
+	Restarts         *uint32             `protobuf:"varint,4,opt,name=restarts" json:"restarts,omitempty"`
+	LastExitStatus   *uint32             `protobuf:"varint,5,opt,name=last_exit_status" json:"last_exit_status,omitempty"`
+	Restart          *RunRequest_Restart `protobuf:"varint,6,opt,name=restart,enum=warden.RunRequest_Restart" json:"restart,omitempty"`
+	MaxRestarts      *uint32             `protobuf:"varint,7,opt,name=max_restarts" json:"max_restarts,omitempty"`
+	XXX_unrecognized []byte              `json:"-"`
+}
+
+func (m *InfoResponse_SupervisedProcess) Reset()         { *m = InfoResponse_SupervisedProcess{} }
+func (m *InfoResponse_SupervisedProcess) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse_SupervisedProcess) ProtoMessage()    {}
+
+func (m *InfoResponse_SupervisedProcess) GetId() uint32 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *InfoResponse_SupervisedProcess) GetProcessId() uint32 {
+	if m != nil && m.ProcessId != nil {
+		return *m.ProcessId
+	}
+	return 0
+}
+
+func (m *InfoResponse_SupervisedProcess) GetRunning() bool {
+	if m != nil && m.Running != nil {
+		return *m.Running
+	}
+	return false
+}
+
+func (m *InfoResponse_SupervisedProcess) GetRestarts() uint32 {
+	if m != nil && m.Restarts != nil {
+		return *m.Restarts
+	}
+	return 0
+}
+
+func (m *InfoResponse_SupervisedProcess) GetLastExitStatus() uint32 {
+	if m != nil && m.LastExitStatus != nil {
+		return *m.LastExitStatus
+	}
+	return 0
+}
+
+func (m *InfoResponse_SupervisedProcess) GetRestart() RunRequest_Restart {
+	if m != nil && m.Restart != nil {
+		return *m.Restart
+	}
+	return RunRequest_never
+}
+
+func (m *InfoResponse_SupervisedProcess) GetMaxRestarts() uint32 {
+	if m != nil && m.MaxRestarts != nil {
+		return *m.MaxRestarts
+	}
+	return 0
+}
+
+type InfoResponse_HealthCheck struct {
+	Status              *string `protobuf:"bytes,1,req,name=status" json:"status,omitempty"`
+	ConsecutiveFailures *uint32 `protobuf:"varint,2,opt,name=consecutive_failures" json:"consecutive_failures,omitempty"`
+	LastCheckedAt       *uint64 `protobuf:"varint,3,opt,name=last_checked_at" json:"last_checked_at,omitempty"`
+	Interval            *uint32 `protobuf:"varint,4,opt,name=interval" json:"interval,omitempty"`
+	Timeout             *uint32 `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+	FailureThreshold    *uint32 `protobuf:"varint,6,opt,name=failure_threshold" json:"failure_threshold,omitempty"`
+	XXX_unrecognized    []byte  `json:"-"`
+}
+
+func (m *InfoResponse_HealthCheck) Reset()         { *m = InfoResponse_HealthCheck{} }
+func (m *InfoResponse_HealthCheck) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse_HealthCheck) ProtoMessage()    {}
+
+func (m *InfoResponse_HealthCheck) GetStatus() string {
+	if m != nil && m.Status != nil {
+		return *m.Status
+	}
+	return ""
+}
+
+func (m *InfoResponse_HealthCheck) GetConsecutiveFailures() uint32 {
+	if m != nil && m.ConsecutiveFailures != nil {
+		return *m.ConsecutiveFailures
+	}
+	return 0
+}
+
+func (m *InfoResponse_HealthCheck) GetLastCheckedAt() uint64 {
+	if m != nil && m.LastCheckedAt != nil {
+		return *m.LastCheckedAt
+	}
+	return 0
+}
+
+func (m *InfoResponse_HealthCheck) GetInterval() uint32 {
+	if m != nil && m.Interval != nil {
+		return *m.Interval
+	}
+	return 0
+}
+
+func (m *InfoResponse_HealthCheck) GetTimeout() uint32 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *InfoResponse_HealthCheck) GetFailureThreshold() uint32 {
+	if m != nil && m.FailureThreshold != nil {
+		return *m.FailureThreshold
+	}
+	return 0
+}
+
 func init() {
 }
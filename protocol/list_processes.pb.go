@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-gogo.
+// source: list_processes.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type ListProcessesRequest struct {
+	Handle           *string `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ListProcessesRequest) Reset()         { *m = ListProcessesRequest{} }
+func (m *ListProcessesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListProcessesRequest) ProtoMessage()    {}
+
+func (m *ListProcessesRequest) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+type ListProcessesResponse struct {
+	Processes        []*ListProcessesResponse_Process `protobuf:"bytes,1,rep,name=processes" json:"processes,omitempty"`
+	XXX_unrecognized []byte                           `json:"-"`
+}
+
+func (m *ListProcessesResponse) Reset()         { *m = ListProcessesResponse{} }
+func (m *ListProcessesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListProcessesResponse) ProtoMessage()    {}
+
+func (m *ListProcessesResponse) GetProcesses() []*ListProcessesResponse_Process {
+	if m != nil {
+		return m.Processes
+	}
+	return nil
+}
+
+type ListProcessesResponse_Process struct {
+	ProcessId        *uint32 `protobuf:"varint,1,req,name=process_id" json:"process_id,omitempty"`
+	Command          *string `protobuf:"bytes,2,opt,name=command" json:"command,omitempty"`
+	StartedAt        *uint64 `protobuf:"varint,3,opt,name=started_at" json:"started_at,omitempty"`
+	FinishedAt       *uint64 `protobuf:"varint,4,opt,name=finished_at" json:"finished_at,omitempty"`
+	ExitStatus       *uint32 `protobuf:"varint,5,opt,name=exit_status" json:"exit_status,omitempty"`
+	TimedOut         *bool   `protobuf:"varint,6,opt,name=timed_out" json:"timed_out,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ListProcessesResponse_Process) Reset()         { *m = ListProcessesResponse_Process{} }
+func (m *ListProcessesResponse_Process) String() string { return proto.CompactTextString(m) }
+func (*ListProcessesResponse_Process) ProtoMessage()    {}
+
+func (m *ListProcessesResponse_Process) GetProcessId() uint32 {
+	if m != nil && m.ProcessId != nil {
+		return *m.ProcessId
+	}
+	return 0
+}
+
+func (m *ListProcessesResponse_Process) GetCommand() string {
+	if m != nil && m.Command != nil {
+		return *m.Command
+	}
+	return ""
+}
+
+func (m *ListProcessesResponse_Process) GetStartedAt() uint64 {
+	if m != nil && m.StartedAt != nil {
+		return *m.StartedAt
+	}
+	return 0
+}
+
+func (m *ListProcessesResponse_Process) GetFinishedAt() uint64 {
+	if m != nil && m.FinishedAt != nil {
+		return *m.FinishedAt
+	}
+	return 0
+}
+
+func (m *ListProcessesResponse_Process) GetExitStatus() uint32 {
+	if m != nil && m.ExitStatus != nil {
+		return *m.ExitStatus
+	}
+	return 0
+}
+
+func (m *ListProcessesResponse_Process) GetTimedOut() bool {
+	if m != nil && m.TimedOut != nil {
+		return *m.TimedOut
+	}
+	return false
+}
+
+func init() {
+}
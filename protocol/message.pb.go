@@ -21,6 +21,8 @@ const (
 	Message_Stop           Message_Type = 12
 	Message_Destroy        Message_Type = 13
 	Message_Info           Message_Type = 14
+	Message_HealthCheck    Message_Type = 15
+	Message_BulkInfo       Message_Type = 16
 	Message_NetIn          Message_Type = 31
 	Message_NetOut         Message_Type = 32
 	Message_CopyIn         Message_Type = 41
@@ -32,9 +34,13 @@ const (
 	Message_Run            Message_Type = 71
 	Message_Attach         Message_Type = 72
 	Message_ProcessPayload Message_Type = 73
+	Message_Signal         Message_Type = 74
+	Message_Wait           Message_Type = 75
+	Message_ListProcesses  Message_Type = 76
 	Message_Ping           Message_Type = 91
 	Message_List           Message_Type = 92
 	Message_Echo           Message_Type = 93
+	Message_Events         Message_Type = 94
 )
 
 var Message_Type_name = map[int32]string{
@@ -43,6 +49,8 @@ var Message_Type_name = map[int32]string{
 	12: "Stop",
 	13: "Destroy",
 	14: "Info",
+	15: "HealthCheck",
+	16: "BulkInfo",
 	31: "NetIn",
 	32: "NetOut",
 	41: "CopyIn",
@@ -54,9 +62,13 @@ var Message_Type_name = map[int32]string{
 	71: "Run",
 	72: "Attach",
 	73: "ProcessPayload",
+	74: "Signal",
+	75: "Wait",
+	76: "ListProcesses",
 	91: "Ping",
 	92: "List",
 	93: "Echo",
+	94: "Events",
 }
 var Message_Type_value = map[string]int32{
 	"Error":          1,
@@ -64,6 +76,8 @@ var Message_Type_value = map[string]int32{
 	"Stop":           12,
 	"Destroy":        13,
 	"Info":           14,
+	"HealthCheck":    15,
+	"BulkInfo":       16,
 	"NetIn":          31,
 	"NetOut":         32,
 	"CopyIn":         41,
@@ -75,9 +89,13 @@ var Message_Type_value = map[string]int32{
 	"Run":            71,
 	"Attach":         72,
 	"ProcessPayload": 73,
+	"Signal":         74,
+	"Wait":           75,
+	"ListProcesses":  76,
 	"Ping":           91,
 	"List":           92,
 	"Echo":           93,
+	"Events":         94,
 }
 
 func (x Message_Type) Enum() *Message_Type {
@@ -100,6 +118,7 @@ func (x *Message_Type) UnmarshalJSON(data []byte) error {
 type Message struct {
 	Type             *Message_Type `protobuf:"varint,1,req,name=type,enum=warden.Message_Type" json:"type,omitempty"`
 	Payload          []byte        `protobuf:"bytes,2,req,name=payload" json:"payload,omitempty"`
+	RequestId        *uint32       `protobuf:"varint,3,opt,name=request_id" json:"request_id,omitempty"`
 	XXX_unrecognized []byte        `json:"-"`
 }
 
@@ -121,6 +140,13 @@ func (m *Message) GetPayload() []byte {
 	return nil
 }
 
+func (m *Message) GetRequestId() uint32 {
+	if m != nil && m.RequestId != nil {
+		return *m.RequestId
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("warden.Message_Type", Message_Type_name, Message_Type_value)
 }
@@ -54,6 +54,11 @@ type ProcessPayload struct {
 	Source           *ProcessPayload_Source `protobuf:"varint,2,opt,name=source,enum=warden.ProcessPayload_Source" json:"source,omitempty"`
 	Data             *string                `protobuf:"bytes,3,opt,name=data" json:"data,omitempty"`
 	ExitStatus       *uint32                `protobuf:"varint,4,opt,name=exit_status" json:"exit_status,omitempty"`
+	Eof              *bool                  `protobuf:"varint,5,opt,name=eof" json:"eof,omitempty"`
+	Tty              *TTY                   `protobuf:"bytes,6,opt,name=tty" json:"tty,omitempty"`
+	DataBytes        []byte                 `protobuf:"bytes,7,opt,name=data_bytes" json:"data_bytes,omitempty"`
+	Offset           *uint64                `protobuf:"varint,8,opt,name=offset" json:"offset,omitempty"`
+	TimedOut         *bool                  `protobuf:"varint,9,opt,name=timed_out" json:"timed_out,omitempty"`
 	XXX_unrecognized []byte                 `json:"-"`
 }
 
@@ -89,6 +94,41 @@ func (m *ProcessPayload) GetExitStatus() uint32 {
 	return 0
 }
 
+func (m *ProcessPayload) GetEof() bool {
+	if m != nil && m.Eof != nil {
+		return *m.Eof
+	}
+	return false
+}
+
+func (m *ProcessPayload) GetTty() *TTY {
+	if m != nil {
+		return m.Tty
+	}
+	return nil
+}
+
+func (m *ProcessPayload) GetDataBytes() []byte {
+	if m != nil {
+		return m.DataBytes
+	}
+	return nil
+}
+
+func (m *ProcessPayload) GetOffset() uint64 {
+	if m != nil && m.Offset != nil {
+		return *m.Offset
+	}
+	return 0
+}
+
+func (m *ProcessPayload) GetTimedOut() bool {
+	if m != nil && m.TimedOut != nil {
+		return *m.TimedOut
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterEnum("warden.ProcessPayload_Source", ProcessPayload_Source_name, ProcessPayload_Source_value)
 }
@@ -13,12 +13,61 @@ var _ = proto.Marshal
 var _ = &json.SyntaxError{}
 var _ = math.Inf
 
+type RunRequest_Restart int32
+
+const (
+	RunRequest_never      RunRequest_Restart = 0
+	RunRequest_on_failure RunRequest_Restart = 1
+	RunRequest_always     RunRequest_Restart = 2
+)
+
+var RunRequest_Restart_name = map[int32]string{
+	0: "never",
+	1: "on_failure",
+	2: "always",
+}
+var RunRequest_Restart_value = map[string]int32{
+	"never":      0,
+	"on_failure": 1,
+	"always":     2,
+}
+
+func (x RunRequest_Restart) Enum() *RunRequest_Restart {
+	p := new(RunRequest_Restart)
+	*p = x
+	return p
+}
+func (x RunRequest_Restart) String() string {
+	return proto.EnumName(RunRequest_Restart_name, int32(x))
+}
+func (x *RunRequest_Restart) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(RunRequest_Restart_value, data, "RunRequest_Restart")
+	if err != nil {
+		return err
+	}
+	*x = RunRequest_Restart(value)
+	return nil
+}
+
 type RunRequest struct {
-	Handle           *string         `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
-	Script           *string         `protobuf:"bytes,2,req,name=script" json:"script,omitempty"`
-	Privileged       *bool           `protobuf:"varint,3,opt,name=privileged,def=0" json:"privileged,omitempty"`
-	Rlimits          *ResourceLimits `protobuf:"bytes,4,opt,name=rlimits" json:"rlimits,omitempty"`
-	XXX_unrecognized []byte          `json:"-"`
+	Handle           *string                `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	Script           *string                `protobuf:"bytes,2,opt,name=script" json:"script,omitempty"`
+	Privileged       *bool                  `protobuf:"varint,3,opt,name=privileged,def=0" json:"privileged,omitempty"`
+	Rlimits          *ResourceLimits        `protobuf:"bytes,4,opt,name=rlimits" json:"rlimits,omitempty"`
+	StreamStdin      *bool                  `protobuf:"varint,5,opt,name=stream_stdin,def=0" json:"stream_stdin,omitempty"`
+	Tty              *TTY                   `protobuf:"bytes,6,opt,name=tty" json:"tty,omitempty"`
+	Path             *string                `protobuf:"bytes,7,opt,name=path" json:"path,omitempty"`
+	Args             []string               `protobuf:"bytes,8,rep,name=args" json:"args,omitempty"`
+	Env              []*EnvironmentVariable `protobuf:"bytes,9,rep,name=env" json:"env,omitempty"`
+	Dir              *string                `protobuf:"bytes,10,opt,name=dir" json:"dir,omitempty"`
+	User             *string                `protobuf:"bytes,11,opt,name=user" json:"user,omitempty"`
+	BinaryPayloads   *bool                  `protobuf:"varint,12,opt,name=binary_payloads,def=0" json:"binary_payloads,omitempty"`
+	Detach           *bool                  `protobuf:"varint,13,opt,name=detach,def=0" json:"detach,omitempty"`
+	Timeout          *uint32                `protobuf:"varint,14,opt,name=timeout" json:"timeout,omitempty"`
+	Restart          *RunRequest_Restart    `protobuf:"varint,15,opt,name=restart,enum=warden.RunRequest_Restart" json:"restart,omitempty"`
+	MaxRestarts      *uint32                `protobuf:"varint,16,opt,name=max_restarts" json:"max_restarts,omitempty"`
+	RestartBackoff   *uint32                `protobuf:"varint,17,opt,name=restart_backoff" json:"restart_backoff,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
 }
 
 func (m *RunRequest) Reset()         { *m = RunRequest{} }
@@ -26,6 +75,9 @@ func (m *RunRequest) String() string { return proto.CompactTextString(m) }
 func (*RunRequest) ProtoMessage()    {}
 
 const Default_RunRequest_Privileged bool = false
+const Default_RunRequest_StreamStdin bool = false
+const Default_RunRequest_BinaryPayloads bool = false
+const Default_RunRequest_Detach bool = false
 
 func (m *RunRequest) GetHandle() string {
 	if m != nil && m.Handle != nil {
@@ -55,5 +107,97 @@ func (m *RunRequest) GetRlimits() *ResourceLimits {
 	return nil
 }
 
+func (m *RunRequest) GetStreamStdin() bool {
+	if m != nil && m.StreamStdin != nil {
+		return *m.StreamStdin
+	}
+	return Default_RunRequest_StreamStdin
+}
+
+func (m *RunRequest) GetTty() *TTY {
+	if m != nil {
+		return m.Tty
+	}
+	return nil
+}
+
+func (m *RunRequest) GetPath() string {
+	if m != nil && m.Path != nil {
+		return *m.Path
+	}
+	return ""
+}
+
+func (m *RunRequest) GetArgs() []string {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *RunRequest) GetEnv() []*EnvironmentVariable {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+func (m *RunRequest) GetDir() string {
+	if m != nil && m.Dir != nil {
+		return *m.Dir
+	}
+	return ""
+}
+
+func (m *RunRequest) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
+func (m *RunRequest) GetBinaryPayloads() bool {
+	if m != nil && m.BinaryPayloads != nil {
+		return *m.BinaryPayloads
+	}
+	return Default_RunRequest_BinaryPayloads
+}
+
+func (m *RunRequest) GetDetach() bool {
+	if m != nil && m.Detach != nil {
+		return *m.Detach
+	}
+	return Default_RunRequest_Detach
+}
+
+func (m *RunRequest) GetTimeout() uint32 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *RunRequest) GetRestart() RunRequest_Restart {
+	if m != nil && m.Restart != nil {
+		return *m.Restart
+	}
+	return RunRequest_never
+}
+
+func (m *RunRequest) GetMaxRestarts() uint32 {
+	if m != nil && m.MaxRestarts != nil {
+		return *m.MaxRestarts
+	}
+	return 0
+}
+
+func (m *RunRequest) GetRestartBackoff() uint32 {
+	if m != nil && m.RestartBackoff != nil {
+		return *m.RestartBackoff
+	}
+	return 0
+}
+
 func init() {
+	proto.RegisterEnum("warden.RunRequest_Restart", RunRequest_Restart_name, RunRequest_Restart_value)
 }
@@ -0,0 +1,108 @@
+// Code generated by protoc-gen-gogo.
+// source: signal.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type SignalRequest_Signal int32
+
+const (
+	SignalRequest_hup  SignalRequest_Signal = 1
+	SignalRequest_int  SignalRequest_Signal = 2
+	SignalRequest_quit SignalRequest_Signal = 3
+	SignalRequest_kill SignalRequest_Signal = 9
+	SignalRequest_usr1 SignalRequest_Signal = 10
+	SignalRequest_usr2 SignalRequest_Signal = 12
+	SignalRequest_term SignalRequest_Signal = 15
+)
+
+var SignalRequest_Signal_name = map[int32]string{
+	1:  "hup",
+	2:  "int",
+	3:  "quit",
+	9:  "kill",
+	10: "usr1",
+	12: "usr2",
+	15: "term",
+}
+var SignalRequest_Signal_value = map[string]int32{
+	"hup":  1,
+	"int":  2,
+	"quit": 3,
+	"kill": 9,
+	"usr1": 10,
+	"usr2": 12,
+	"term": 15,
+}
+
+func (x SignalRequest_Signal) Enum() *SignalRequest_Signal {
+	p := new(SignalRequest_Signal)
+	*p = x
+	return p
+}
+func (x SignalRequest_Signal) String() string {
+	return proto.EnumName(SignalRequest_Signal_name, int32(x))
+}
+func (x *SignalRequest_Signal) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(SignalRequest_Signal_value, data, "SignalRequest_Signal")
+	if err != nil {
+		return err
+	}
+	*x = SignalRequest_Signal(value)
+	return nil
+}
+
+type SignalRequest struct {
+	Handle           *string               `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	ProcessId        *uint32               `protobuf:"varint,2,req,name=process_id" json:"process_id,omitempty"`
+	Signal           *SignalRequest_Signal `protobuf:"varint,3,opt,name=signal,enum=warden.SignalRequest_Signal,def=15" json:"signal,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (m *SignalRequest) Reset()         { *m = SignalRequest{} }
+func (m *SignalRequest) String() string { return proto.CompactTextString(m) }
+func (*SignalRequest) ProtoMessage()    {}
+
+const Default_SignalRequest_Signal SignalRequest_Signal = SignalRequest_term
+
+func (m *SignalRequest) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+func (m *SignalRequest) GetProcessId() uint32 {
+	if m != nil && m.ProcessId != nil {
+		return *m.ProcessId
+	}
+	return 0
+}
+
+func (m *SignalRequest) GetSignal() SignalRequest_Signal {
+	if m != nil && m.Signal != nil {
+		return *m.Signal
+	}
+	return Default_SignalRequest_Signal
+}
+
+type SignalResponse struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *SignalResponse) Reset()         { *m = SignalResponse{} }
+func (m *SignalResponse) String() string { return proto.CompactTextString(m) }
+func (*SignalResponse) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterEnum("warden.SignalRequest_Signal", SignalRequest_Signal_name, SignalRequest_Signal_value)
+}
@@ -8,6 +8,16 @@ import (
 )
 
 func Messages(msgs ...proto.Message) *bytes.Buffer {
+	return frameMessages(nil, msgs)
+}
+
+// MessagesForRequest frames messages like Messages, tagging each with the
+// ID of the multiplexed request they belong to.
+func MessagesForRequest(requestID uint32, msgs ...proto.Message) *bytes.Buffer {
+	return frameMessages(&requestID, msgs)
+}
+
+func frameMessages(requestID *uint32, msgs []proto.Message) *bytes.Buffer {
 	buf := bytes.NewBuffer([]byte{})
 
 	for _, msg := range msgs {
@@ -17,8 +27,9 @@ func Messages(msgs ...proto.Message) *bytes.Buffer {
 		}
 
 		message := &Message{
-			Type:    TypeForMessage(msg).Enum(),
-			Payload: payload,
+			Type:      TypeForMessage(msg).Enum(),
+			Payload:   payload,
+			RequestId: requestID,
 		}
 
 		messagePayload, err := proto.Marshal(message)
@@ -45,6 +56,10 @@ func TypeForMessage(msg proto.Message) Message_Type {
 		return Message_Destroy
 	case *InfoRequest, *InfoResponse:
 		return Message_Info
+	case *HealthCheckRequest, *HealthCheckResponse:
+		return Message_HealthCheck
+	case *BulkInfoRequest, *BulkInfoResponse:
+		return Message_BulkInfo
 
 	case *NetInRequest, *NetInResponse:
 		return Message_NetIn
@@ -71,6 +86,12 @@ func TypeForMessage(msg proto.Message) Message_Type {
 		return Message_Attach
 	case *ProcessPayload:
 		return Message_ProcessPayload
+	case *SignalRequest, *SignalResponse:
+		return Message_Signal
+	case *WaitRequest, *WaitResponse:
+		return Message_Wait
+	case *ListProcessesRequest, *ListProcessesResponse:
+		return Message_ListProcesses
 
 	case *PingRequest, *PingResponse:
 		return Message_Ping
@@ -78,6 +99,8 @@ func TypeForMessage(msg proto.Message) Message_Type {
 		return Message_List
 	case *EchoRequest, *EchoResponse:
 		return Message_Echo
+	case *EventsRequest, *Event:
+		return Message_Events
 	}
 
 	panic("unknown message type")
@@ -93,6 +116,10 @@ func RequestMessageForType(t Message_Type) proto.Message {
 		return &DestroyRequest{}
 	case Message_Info:
 		return &InfoRequest{}
+	case Message_HealthCheck:
+		return &HealthCheckRequest{}
+	case Message_BulkInfo:
+		return &BulkInfoRequest{}
 
 	case Message_NetIn:
 		return &NetInRequest{}
@@ -117,6 +144,14 @@ func RequestMessageForType(t Message_Type) proto.Message {
 		return &RunRequest{}
 	case Message_Attach:
 		return &AttachRequest{}
+	case Message_ProcessPayload:
+		return &ProcessPayload{}
+	case Message_Signal:
+		return &SignalRequest{}
+	case Message_Wait:
+		return &WaitRequest{}
+	case Message_ListProcesses:
+		return &ListProcessesRequest{}
 
 	case Message_Ping:
 		return &PingRequest{}
@@ -124,6 +159,8 @@ func RequestMessageForType(t Message_Type) proto.Message {
 		return &ListRequest{}
 	case Message_Echo:
 		return &EchoRequest{}
+	case Message_Events:
+		return &EventsRequest{}
 	}
 
 	panic("unknown message type")
@@ -139,6 +176,10 @@ func ResponseMessageForType(t Message_Type) proto.Message {
 		return &DestroyResponse{}
 	case Message_Info:
 		return &InfoResponse{}
+	case Message_HealthCheck:
+		return &HealthCheckResponse{}
+	case Message_BulkInfo:
+		return &BulkInfoResponse{}
 	case Message_NetIn:
 		return &NetInResponse{}
 	case Message_NetOut:
@@ -160,6 +201,12 @@ func ResponseMessageForType(t Message_Type) proto.Message {
 
 	case Message_Run, Message_Attach:
 		return &ProcessPayload{}
+	case Message_Signal:
+		return &SignalResponse{}
+	case Message_Wait:
+		return &WaitResponse{}
+	case Message_ListProcesses:
+		return &ListProcessesResponse{}
 
 	case Message_Ping:
 		return &PingResponse{}
@@ -167,6 +214,8 @@ func ResponseMessageForType(t Message_Type) proto.Message {
 		return &ListResponse{}
 	case Message_Echo:
 		return &EchoResponse{}
+	case Message_Events:
+		return &Event{}
 	}
 
 	panic("unknown message type")
@@ -0,0 +1,65 @@
+// Code generated by protoc-gen-gogo.
+// source: wait.proto
+// DO NOT EDIT!
+
+package warden
+
+import proto "code.google.com/p/gogoprotobuf/proto"
+import json "encoding/json"
+import math "math"
+
+// Reference proto, json, and math imports to suppress error if they are not otherwise used.
+var _ = proto.Marshal
+var _ = &json.SyntaxError{}
+var _ = math.Inf
+
+type WaitRequest struct {
+	Handle           *string `protobuf:"bytes,1,req,name=handle" json:"handle,omitempty"`
+	ProcessId        *uint32 `protobuf:"varint,2,req,name=process_id" json:"process_id,omitempty"`
+	Timeout          *uint32 `protobuf:"varint,3,opt,name=timeout" json:"timeout,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WaitRequest) Reset()         { *m = WaitRequest{} }
+func (m *WaitRequest) String() string { return proto.CompactTextString(m) }
+func (*WaitRequest) ProtoMessage()    {}
+
+func (m *WaitRequest) GetHandle() string {
+	if m != nil && m.Handle != nil {
+		return *m.Handle
+	}
+	return ""
+}
+
+func (m *WaitRequest) GetProcessId() uint32 {
+	if m != nil && m.ProcessId != nil {
+		return *m.ProcessId
+	}
+	return 0
+}
+
+func (m *WaitRequest) GetTimeout() uint32 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+type WaitResponse struct {
+	ExitStatus       *uint32 `protobuf:"varint,1,req,name=exit_status" json:"exit_status,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WaitResponse) Reset()         { *m = WaitResponse{} }
+func (m *WaitResponse) String() string { return proto.CompactTextString(m) }
+func (*WaitResponse) ProtoMessage()    {}
+
+func (m *WaitResponse) GetExitStatus() uint32 {
+	if m != nil && m.ExitStatus != nil {
+		return *m.ExitStatus
+	}
+	return 0
+}
+
+func init() {
+}
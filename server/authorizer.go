@@ -0,0 +1,298 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+)
+
+// AuthorizationPolicy says what clients other than admins may do. Clients
+// are known by the common name of the certificate they authenticated with.
+type AuthorizationPolicy struct {
+	// Admins may operate on any container and are not restricted by the
+	// rest of the policy.
+	Admins []string
+
+	ForbidPrivileged     bool
+	ForbidHostBindMounts bool
+
+	// HostCopyRoot, if set, is the directory on the host that non-admins
+	// may copy files into containers from and out of containers to.
+	// Otherwise they may copy anywhere on the host, unless host bind
+	// mounts are forbidden, in which case they may not copy to or from the
+	// host at all.
+	HostCopyRoot string
+}
+
+// UnauthorizedError is returned for containers a client does not own. It
+// reads the same as an unknown handle, so that clients cannot learn of
+// each other's containers.
+type UnauthorizedError struct {
+	Client string
+	Handle string
+}
+
+func (e UnauthorizedError) Error() string {
+	return "unknown handle: " + e.Handle
+}
+
+type PolicyViolationError struct {
+	Client    string
+	Violation string
+}
+
+func (e PolicyViolationError) Error() string {
+	return fmt.Sprintf("not permitted: %s", e.Violation)
+}
+
+// Authorizer records which client created each container and restricts
+// operations on it to that client or an admin.
+type Authorizer struct {
+	policy AuthorizationPolicy
+	admins map[string]bool
+
+	owners      map[string]string
+	ownersPath  string
+	ownersMutex *sync.RWMutex
+}
+
+// NewAuthorizer returns an authorizer enforcing the policy. If an owners
+// path is given, ownership is saved there, and loaded from it if it
+// exists, so that it survives restarts.
+func NewAuthorizer(policy AuthorizationPolicy, ownersPath string) (*Authorizer, error) {
+	authorizer := &Authorizer{
+		policy: policy,
+		admins: make(map[string]bool),
+
+		owners:      make(map[string]string),
+		ownersPath:  ownersPath,
+		ownersMutex: &sync.RWMutex{},
+	}
+
+	for _, admin := range policy.Admins {
+		authorizer.admins[admin] = true
+	}
+
+	if ownersPath == "" {
+		return authorizer, nil
+	}
+
+	owners, err := ioutil.ReadFile(ownersPath)
+	if os.IsNotExist(err) {
+		return authorizer, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading owners: %s", err)
+	}
+
+	err = json.Unmarshal(owners, &authorizer.owners)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing owners: %s", err)
+	}
+
+	return authorizer, nil
+}
+
+func (a *Authorizer) IsAdmin(client string) bool {
+	return a.admins[client]
+}
+
+// Owns reports whether the client may operate on the container.
+func (a *Authorizer) Owns(client, handle string) bool {
+	if a.IsAdmin(client) {
+		return true
+	}
+
+	a.ownersMutex.RLock()
+	defer a.ownersMutex.RUnlock()
+
+	owner, found := a.owners[handle]
+
+	return found && owner == client
+}
+
+func (a *Authorizer) Authorize(client, handle string) error {
+	if !a.Owns(client, handle) {
+		return UnauthorizedError{client, handle}
+	}
+
+	return nil
+}
+
+func (a *Authorizer) AuthorizeCreate(client, rootfs string, bindMounts []backend.BindMount) error {
+	if a.IsAdmin(client) {
+		return nil
+	}
+
+	// a path could lead to any directory on the host, such as another
+	// container's
+	if filepath.IsAbs(rootfs) {
+		return PolicyViolationError{client, "rootfs paths"}
+	}
+
+	if !a.policy.ForbidHostBindMounts {
+		return nil
+	}
+
+	for _, bindMount := range bindMounts {
+		if bindMount.Origin == backend.BindMountOriginHost {
+			return PolicyViolationError{client, "bind mounts from the host"}
+		}
+	}
+
+	return nil
+}
+
+func (a *Authorizer) AuthorizeRun(client, handle string, privileged bool) error {
+	err := a.Authorize(client, handle)
+	if err != nil {
+		return err
+	}
+
+	if privileged && a.policy.ForbidPrivileged && !a.IsAdmin(client) {
+		return PolicyViolationError{client, "privileged processes"}
+	}
+
+	return nil
+}
+
+// AuthorizeCopy authorizes copying between the host path and the
+// container, as root if privileged.
+func (a *Authorizer) AuthorizeCopy(client, handle, hostPath string, privileged bool) error {
+	err := a.AuthorizeRun(client, handle, privileged)
+	if err != nil {
+		return err
+	}
+
+	if a.IsAdmin(client) {
+		return nil
+	}
+
+	if a.policy.HostCopyRoot != "" {
+		if !withinDirectory(a.policy.HostCopyRoot, hostPath) {
+			return PolicyViolationError{client, "copying outside " + a.policy.HostCopyRoot}
+		}
+
+		return nil
+	}
+
+	if a.policy.ForbidHostBindMounts {
+		return PolicyViolationError{client, "copying to or from the host"}
+	}
+
+	return nil
+}
+
+// Claim records the client as the owner of the container, unless the
+// handle is already someone's.
+func (a *Authorizer) Claim(client, handle string) error {
+	a.ownersMutex.Lock()
+	defer a.ownersMutex.Unlock()
+
+	if _, found := a.owners[handle]; found {
+		return backend.HandleExistsError{Handle: handle}
+	}
+
+	a.owners[handle] = client
+
+	a.saveOwners()
+
+	return nil
+}
+
+// Release forgets the owner of a container that is gone.
+func (a *Authorizer) Release(handle string) {
+	a.ownersMutex.Lock()
+	defer a.ownersMutex.Unlock()
+
+	if _, found := a.owners[handle]; !found {
+		return
+	}
+
+	delete(a.owners, handle)
+
+	a.saveOwners()
+}
+
+func (a *Authorizer) saveOwners() {
+	if a.ownersPath == "" {
+		return
+	}
+
+	owners, err := json.Marshal(a.owners)
+	if err != nil {
+		log.Println("failed to encode owners:", err)
+		return
+	}
+
+	err = ioutil.WriteFile(a.ownersPath, owners, 0600)
+	if err != nil {
+		log.Println("failed to save owners:", err)
+	}
+}
+
+// withinDirectory reports whether the path is the directory or inside it,
+// once any symlinks in either have been followed, so that a link cannot
+// lead out of it.
+func withinDirectory(dir, p string) bool {
+	if !filepath.IsAbs(p) {
+		return false
+	}
+
+	rel, err := filepath.Rel(resolveSymlinks(dir), resolveSymlinks(p))
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// resolveSymlinks follows the symlinks in as much of the path as exists.
+func resolveSymlinks(p string) string {
+	p = filepath.Clean(p)
+	rest := ""
+
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(resolved, rest)
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest)
+		}
+
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// clientName identifies the client by the common name of its verified
+// certificate, or returns "" if it did not authenticate with one.
+func clientName(conn net.Conn) string {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+
+	return tlsClientName(tlsConn.ConnectionState())
+}
+
+func tlsClientName(state tls.ConnectionState) string {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return state.VerifiedChains[0][0].Subject.CommonName
+}
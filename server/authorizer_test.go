@@ -0,0 +1,230 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/server"
+)
+
+var _ = Describe("Authorizer", func() {
+	var policy server.AuthorizationPolicy
+	var ownersPath string
+
+	var authorizer *server.Authorizer
+
+	BeforeEach(func() {
+		policy = server.AuthorizationPolicy{
+			Admins: []string{"admin"},
+		}
+
+		ownersPath = ""
+	})
+
+	JustBeforeEach(func() {
+		var err error
+
+		authorizer, err = server.NewAuthorizer(policy, ownersPath)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ownership", func() {
+		It("lets only the client that claimed a container, or an admin, operate on it", func() {
+			authorizer.Claim("alice", "some-handle")
+
+			Expect(authorizer.Authorize("alice", "some-handle")).ToNot(HaveOccurred())
+			Expect(authorizer.Authorize("admin", "some-handle")).ToNot(HaveOccurred())
+
+			err := authorizer.Authorize("bob", "some-handle")
+			Expect(err).To(Equal(server.UnauthorizedError{"bob", "some-handle"}))
+			Expect(err.Error()).To(Equal("unknown handle: some-handle"))
+		})
+
+		It("does not let a claimed handle be claimed again", func() {
+			err := authorizer.Claim("alice", "some-handle")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = authorizer.Claim("bob", "some-handle")
+			Expect(err).To(Equal(backend.HandleExistsError{Handle: "some-handle"}))
+
+			Expect(authorizer.Owns("alice", "some-handle")).To(BeTrue())
+			Expect(authorizer.Owns("bob", "some-handle")).To(BeFalse())
+		})
+
+		It("lets only admins operate on containers nobody has claimed", func() {
+			Expect(authorizer.Owns("alice", "some-handle")).To(BeFalse())
+			Expect(authorizer.Owns("admin", "some-handle")).To(BeTrue())
+		})
+
+		It("forgets the owner once released", func() {
+			authorizer.Claim("alice", "some-handle")
+			authorizer.Release("some-handle")
+
+			Expect(authorizer.Owns("alice", "some-handle")).To(BeFalse())
+		})
+
+		Context("with an owners path", func() {
+			BeforeEach(func() {
+				tmpdir, err := ioutil.TempDir(os.TempDir(), "warden-server-test")
+				Expect(err).ToNot(HaveOccurred())
+
+				ownersPath = path.Join(tmpdir, "owners.json")
+			})
+
+			It("remembers owners across authorizers", func() {
+				authorizer.Claim("alice", "some-handle")
+				authorizer.Claim("bob", "some-other-handle")
+				authorizer.Release("some-other-handle")
+
+				reloaded, err := server.NewAuthorizer(policy, ownersPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(reloaded.Owns("alice", "some-handle")).To(BeTrue())
+				Expect(reloaded.Owns("bob", "some-other-handle")).To(BeFalse())
+			})
+
+			Context("when the owners file is corrupt", func() {
+				It("fails to load", func() {
+					err := ioutil.WriteFile(ownersPath, []byte("{"), 0600)
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = server.NewAuthorizer(policy, ownersPath)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	Describe("the policy", func() {
+		hostMount := backend.BindMount{
+			SrcPath: "/etc",
+			DstPath: "/host-etc",
+			Origin:  backend.BindMountOriginHost,
+		}
+
+		containerMount := backend.BindMount{
+			SrcPath: "/tmp",
+			DstPath: "/other-tmp",
+			Origin:  backend.BindMountOriginContainer,
+		}
+
+		BeforeEach(func() {
+			policy.ForbidPrivileged = true
+			policy.ForbidHostBindMounts = true
+		})
+
+		JustBeforeEach(func() {
+			authorizer.Claim("alice", "some-handle")
+			authorizer.Claim("admin", "some-admin-handle")
+		})
+
+		It("forbids privileged processes for clients other than admins", func() {
+			err := authorizer.AuthorizeRun("alice", "some-handle", true)
+			Expect(err).To(Equal(server.PolicyViolationError{"alice", "privileged processes"}))
+
+			Expect(authorizer.AuthorizeRun("alice", "some-handle", false)).ToNot(HaveOccurred())
+			Expect(authorizer.AuthorizeRun("admin", "some-admin-handle", true)).ToNot(HaveOccurred())
+		})
+
+		It("still checks ownership for runs", func() {
+			err := authorizer.AuthorizeRun("alice", "some-admin-handle", false)
+			Expect(err).To(Equal(server.UnauthorizedError{"alice", "some-admin-handle"}))
+		})
+
+		It("forbids bind mounts from the host for clients other than admins", func() {
+			err := authorizer.AuthorizeCreate("alice", "", []backend.BindMount{containerMount, hostMount})
+			Expect(err).To(Equal(server.PolicyViolationError{"alice", "bind mounts from the host"}))
+
+			Expect(authorizer.AuthorizeCreate("alice", "", []backend.BindMount{containerMount})).ToNot(HaveOccurred())
+			Expect(authorizer.AuthorizeCreate("admin", "", []backend.BindMount{hostMount})).ToNot(HaveOccurred())
+		})
+
+		It("forbids rootfs paths for clients other than admins", func() {
+			err := authorizer.AuthorizeCreate("alice", "/depot/other-container", nil)
+			Expect(err).To(Equal(server.PolicyViolationError{"alice", "rootfs paths"}))
+
+			Expect(authorizer.AuthorizeCreate("alice", "lucid64", nil)).ToNot(HaveOccurred())
+			Expect(authorizer.AuthorizeCreate("admin", "/var/rootfses/lucid64", nil)).ToNot(HaveOccurred())
+		})
+
+		It("forbids copying to or from the host for clients other than admins", func() {
+			err := authorizer.AuthorizeCopy("alice", "some-handle", "/etc/shadow", false)
+			Expect(err).To(Equal(server.PolicyViolationError{"alice", "copying to or from the host"}))
+
+			Expect(authorizer.AuthorizeCopy("admin", "some-admin-handle", "/etc/shadow", false)).ToNot(HaveOccurred())
+		})
+
+		It("still checks privileges and ownership for copies", func() {
+			err := authorizer.AuthorizeCopy("alice", "some-handle", "/src", true)
+			Expect(err).To(Equal(server.PolicyViolationError{"alice", "privileged processes"}))
+
+			err = authorizer.AuthorizeCopy("alice", "some-admin-handle", "/src", false)
+			Expect(err).To(Equal(server.UnauthorizedError{"alice", "some-admin-handle"}))
+		})
+
+		Context("with a host copy root", func() {
+			var copyRoot string
+
+			BeforeEach(func() {
+				tmpdir, err := ioutil.TempDir("", "authorizer-copy-root")
+				Expect(err).ToNot(HaveOccurred())
+
+				copyRoot = path.Join(tmpdir, "copies")
+
+				err = os.Mkdir(copyRoot, 0755)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = os.Symlink("/etc", path.Join(copyRoot, "etc"))
+				Expect(err).ToNot(HaveOccurred())
+
+				policy.HostCopyRoot = copyRoot
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(path.Dir(copyRoot))
+			})
+
+			It("lets clients other than admins copy within it", func() {
+				Expect(authorizer.AuthorizeCopy("alice", "some-handle", copyRoot, false)).ToNot(HaveOccurred())
+				Expect(authorizer.AuthorizeCopy("alice", "some-handle", copyRoot+"/some/file", false)).ToNot(HaveOccurred())
+				Expect(authorizer.AuthorizeCopy("alice", "some-handle", copyRoot+"/dir/", false)).ToNot(HaveOccurred())
+			})
+
+			It("forbids clients other than admins from copying outside it", func() {
+				notPermitted := server.PolicyViolationError{"alice", "copying outside " + copyRoot}
+
+				for _, hostPath := range []string{
+					"/etc/shadow",
+					path.Dir(copyRoot),
+					copyRoot + "-other/file",
+					copyRoot + "/../other",
+					copyRoot + "/etc/shadow",
+					"copies/file",
+				} {
+					err := authorizer.AuthorizeCopy("alice", "some-handle", hostPath, false)
+					Expect(err).To(Equal(notPermitted), "copying "+hostPath)
+				}
+
+				Expect(authorizer.AuthorizeCopy("admin", "some-admin-handle", "/etc/shadow", false)).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when nothing is forbidden", func() {
+			BeforeEach(func() {
+				policy.ForbidPrivileged = false
+				policy.ForbidHostBindMounts = false
+			})
+
+			It("allows privileged processes, host bind mounts, and copies anywhere on the host", func() {
+				Expect(authorizer.AuthorizeRun("alice", "some-handle", true)).ToNot(HaveOccurred())
+				Expect(authorizer.AuthorizeCreate("alice", "", []backend.BindMount{hostMount})).ToNot(HaveOccurred())
+				Expect(authorizer.AuthorizeCopy("alice", "some-handle", "/etc/shadow", false)).ToNot(HaveOccurred())
+			})
+		})
+	})
+})
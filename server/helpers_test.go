@@ -1,7 +1,20 @@
 package server_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
 	"net"
+	"path"
+	"time"
+
+	. "github.com/onsi/gomega"
 )
 
 func ErrorDialing(network, addr string) func() error {
@@ -18,3 +31,73 @@ func ErrorDialing(network, addr string) func() error {
 func uint64ptr(n uint64) *uint64 {
 	return &n
 }
+
+type testCertificate struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+
+	CertFile string
+	KeyFile  string
+}
+
+func (c testCertificate) TLSCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{c.Certificate.Raw},
+		PrivateKey:  c.Key,
+	}
+}
+
+// generateCertificate writes a certificate and its key to the directory,
+// signed by the given CA or, if it is nil, by itself as a CA.
+func generateCertificate(dir, name string, ca *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	parent := template
+	var signer interface{} = key
+
+	if ca == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent = ca.Certificate
+		signer = ca.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	Expect(err).ToNot(HaveOccurred())
+
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	certFile := path.Join(dir, name+".crt")
+	keyFile := path.Join(dir, name+".key")
+
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	Expect(err).ToNot(HaveOccurred())
+
+	return testCertificate{
+		Certificate: certificate,
+		Key:         key,
+
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+}
@@ -0,0 +1,677 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/gogoprotobuf/proto"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+)
+
+// The HTTP API exposes the same operations as the warden protocol, taking
+// and returning the protocol's messages as JSON. Process output and events
+// are streamed as newline-delimited JSON in a chunked response.
+
+type BadRequestError struct {
+	OriginalError error
+}
+
+func (e BadRequestError) Error() string {
+	return fmt.Sprintf("bad request: %s", e.OriginalError)
+}
+
+type httpParams map[string]string
+
+type httpRoute struct {
+	method string
+	path   []string
+
+	// request builds the request for routes that have a single response,
+	// which are handled like any other request
+	request func(*http.Request, httpParams) (proto.Message, error)
+
+	// stream serves routes that write their own, possibly long-lived,
+	// response
+	stream func(string, http.ResponseWriter, *http.Request, httpParams)
+}
+
+type httpHandler struct {
+	server *WardenServer
+	routes []httpRoute
+}
+
+// HTTPHandler returns a handler for the HTTP API. The server must have been
+// started.
+func (s *WardenServer) HTTPHandler() http.Handler {
+	return &httpHandler{
+		server: s,
+		routes: s.httpRoutes(),
+	}
+}
+
+// UnauthenticatedHTTPError is returned when the HTTP API would be served
+// beyond the host without clients having to authenticate.
+type UnauthenticatedHTTPError struct {
+	ListenAddr string
+}
+
+func (e UnauthenticatedHTTPError) Error() string {
+	return fmt.Sprintf("refusing to serve the HTTP API on %s without client certificate auth", e.ListenAddr)
+}
+
+// StartHTTP serves the HTTP API on the given address, in addition to the
+// warden protocol, until the server is stopped. It is served over TLS if
+// the server was configured with it. Unless clients must authenticate
+// with certificates, it may only listen on a loopback address or a unix
+// socket, as anyone who can reach it could otherwise do anything.
+func (s *WardenServer) StartHTTP(listenNetwork, listenAddr string) error {
+	if !s.requiresClientCertificates() && !isLocalAddr(listenNetwork, listenAddr) {
+		return UnauthenticatedHTTPError{listenAddr}
+	}
+
+	listener, err := s.listen(listenNetwork, listenAddr)
+	if err != nil {
+		return err
+	}
+
+	s.httpListener = listener
+
+	go http.Serve(listener, s.HTTPHandler())
+
+	return nil
+}
+
+func (s *WardenServer) requiresClientCertificates() bool {
+	return s.tlsConfig != nil && s.tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert
+}
+
+// isLocalAddr reports whether only the host itself can connect to the
+// address: a unix socket, or a loopback host such as 127.0.0.1 or localhost.
+func isLocalAddr(network, addr string) bool {
+	if network == "unix" {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *WardenServer) httpRoutes() []httpRoute {
+	return []httpRoute{
+		{method: "GET", path: []string{"ping"}, request: pingRequest},
+
+		{method: "GET", path: []string{"containers"}, request: listRequest},
+		{method: "POST", path: []string{"containers"}, request: createRequest},
+		{method: "GET", path: []string{"containers", "info"}, request: bulkInfoRequest},
+		{method: "DELETE", path: []string{"containers", ":handle"}, request: destroyRequest},
+		{method: "GET", path: []string{"containers", ":handle", "info"}, request: infoRequest},
+		{method: "POST", path: []string{"containers", ":handle", "stop"}, request: stopRequest},
+
+		{method: "POST", path: []string{"containers", ":handle", "copy_in"}, request: copyInRequest},
+		{method: "POST", path: []string{"containers", ":handle", "copy_out"}, request: copyOutRequest},
+
+		{method: "POST", path: []string{"containers", ":handle", "limits", "memory"}, request: limitMemoryRequest},
+		{method: "POST", path: []string{"containers", ":handle", "limits", "disk"}, request: limitDiskRequest},
+		{method: "POST", path: []string{"containers", ":handle", "limits", "bandwidth"}, request: limitBandwidthRequest},
+		{method: "POST", path: []string{"containers", ":handle", "limits", "cpu"}, request: limitCpuRequest},
+
+		{method: "POST", path: []string{"containers", ":handle", "net", "in"}, request: netInRequest},
+		{method: "POST", path: []string{"containers", ":handle", "net", "out"}, request: netOutRequest},
+
+		{method: "PUT", path: []string{"containers", ":handle", "health_check"}, request: healthCheckRequest},
+		{method: "DELETE", path: []string{"containers", ":handle", "health_check"}, request: removeHealthCheckRequest},
+
+		{method: "GET", path: []string{"containers", ":handle", "processes"}, request: listProcessesRequest},
+		{method: "POST", path: []string{"containers", ":handle", "processes"}, stream: s.httpRun},
+		{method: "GET", path: []string{"containers", ":handle", "processes", ":process_id"}, stream: s.httpAttach},
+		{method: "POST", path: []string{"containers", ":handle", "processes", ":process_id", "stdin"}, stream: s.httpStdin},
+		{method: "POST", path: []string{"containers", ":handle", "processes", ":process_id", "signal"}, request: signalRequest},
+		{method: "GET", path: []string{"containers", ":handle", "processes", ":process_id", "wait"}, stream: s.httpWait},
+
+		{method: "GET", path: []string{"events"}, stream: s.httpEvents},
+	}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if <-h.server.stopping {
+		writeHTTPError(w, http.StatusServiceUnavailable, errors.New("server is stopping"))
+		return
+	}
+
+	route, params, found := h.route(r)
+	if !found {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("not found: %s %s", r.Method, r.URL.Path))
+		return
+	}
+
+	client := ""
+	if r.TLS != nil {
+		client = tlsClientName(*r.TLS)
+	}
+
+	if route.stream != nil {
+		route.stream(client, w, r, params)
+		return
+	}
+
+	request, err := route.request(r, params)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	h.server.openRequests.Incr()
+	defer h.server.openRequests.Decr()
+
+	response, err := h.server.handleRequest(client, request)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *httpHandler) route(r *http.Request) (httpRoute, httpParams, bool) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	for _, route := range h.routes {
+		if route.method != r.Method || len(route.path) != len(segments) {
+			continue
+		}
+
+		params := httpParams{}
+		matched := true
+
+		for i, segment := range route.path {
+			if strings.HasPrefix(segment, ":") {
+				params[segment[1:]] = segments[i]
+			} else if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return route, params, true
+		}
+	}
+
+	return httpRoute{}, nil, false
+}
+
+func pingRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.PingRequest{}, nil
+}
+
+// listRequest lists the containers having all of the properties given as
+// repeated property=key:value query parameters.
+func listRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.ListRequest{}
+
+	for _, property := range r.URL.Query()["property"] {
+		keyValue := strings.SplitN(property, ":", 2)
+		if len(keyValue) != 2 {
+			return nil, BadRequestError{fmt.Errorf("invalid property: %s", property)}
+		}
+
+		request.Properties = append(request.Properties, &protocol.Property{
+			Key:   proto.String(keyValue[0]),
+			Value: proto.String(keyValue[1]),
+		})
+	}
+
+	return request, nil
+}
+
+func createRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.CreateRequest{}
+	return request, decodeHTTPRequest(r, request)
+}
+
+func destroyRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.DestroyRequest{Handle: proto.String(params["handle"])}, nil
+}
+
+func infoRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.InfoRequest{Handle: proto.String(params["handle"])}, nil
+}
+
+// bulkInfoRequest gets the info of the containers given as repeated handle
+// query parameters, or of all of them if none are given.
+func bulkInfoRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.BulkInfoRequest{Handles: r.URL.Query()["handle"]}, nil
+}
+
+func stopRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.StopRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func copyInRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.CopyInRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func copyOutRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.CopyOutRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func limitMemoryRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.LimitMemoryRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func limitDiskRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.LimitDiskRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func limitBandwidthRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.LimitBandwidthRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func limitCpuRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.LimitCpuRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func netInRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.NetInRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func netOutRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.NetOutRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func healthCheckRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	request := &protocol.HealthCheckRequest{}
+	err := decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	return request, err
+}
+
+func removeHealthCheckRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.HealthCheckRequest{
+		Handle: proto.String(params["handle"]),
+		Remove: proto.Bool(true),
+	}, nil
+}
+
+func listProcessesRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	return &protocol.ListProcessesRequest{Handle: proto.String(params["handle"])}, nil
+}
+
+func signalRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	processID, err := httpProcessID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &protocol.SignalRequest{}
+	err = decodeHTTPRequest(r, request)
+	request.Handle = proto.String(params["handle"])
+	request.ProcessId = proto.Uint32(processID)
+	return request, err
+}
+
+func waitRequest(r *http.Request, params httpParams) (proto.Message, error) {
+	processID, err := httpProcessID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &protocol.WaitRequest{
+		Handle:    proto.String(params["handle"]),
+		ProcessId: proto.Uint32(processID),
+	}
+
+	if timeout := r.URL.Query().Get("timeout"); timeout != "" {
+		seconds, err := strconv.ParseUint(timeout, 10, 32)
+		if err != nil {
+			return nil, BadRequestError{err}
+		}
+
+		request.Timeout = proto.Uint32(uint32(seconds))
+	}
+
+	return request, nil
+}
+
+// httpWait waits for the process to exit, giving up if the client goes
+// away in the meantime.
+func (s *WardenServer) httpWait(client string, w http.ResponseWriter, r *http.Request, params httpParams) {
+	request, err := waitRequest(r, params)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	response, err := s.handleWait(client, request.(*protocol.WaitRequest), func() bool {
+		select {
+		case <-closed:
+			return true
+		default:
+			return false
+		}
+	})
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// httpStdin writes the request body to the process's stdin, closing it once
+// the body has been written.
+func (s *WardenServer) httpStdin(client string, w http.ResponseWriter, r *http.Request, params httpParams) {
+	processID, err := httpProcessID(params)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.authorizeHandle(client, params["handle"])
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	s.openRequests.Incr()
+	defer s.openRequests.Decr()
+
+	err = s.writeStdin(params["handle"], processID, r.Body)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	streamHTTP(w, &protocol.ProcessPayload{ProcessId: proto.Uint32(processID)})
+}
+
+func (s *WardenServer) writeStdin(handle string, processID uint32, input io.Reader) error {
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	stdin, err := container.Stdin(processID)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(stdin, input)
+
+	closeErr := stdin.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+func (s *WardenServer) httpRun(client string, w http.ResponseWriter, r *http.Request, params httpParams) {
+	request := &protocol.RunRequest{}
+
+	err := decodeHTTPRequest(r, request)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	request.Handle = proto.String(params["handle"])
+
+	err = s.authorize(client, request)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	container, err := s.backend.Lookup(request.GetHandle())
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	processID, stream, err := startProcess(container, request)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	streamHTTP(w, &protocol.ProcessPayload{ProcessId: proto.Uint32(processID)})
+
+	if stream == nil {
+		return
+	}
+
+	streamProcessToHTTP(w, processID, stream, request.GetBinaryPayloads())
+}
+
+func (s *WardenServer) httpAttach(client string, w http.ResponseWriter, r *http.Request, params httpParams) {
+	processID, err := httpProcessID(params)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	offsets := backend.ProcessStreamOffsets{}
+
+	for name, offset := range map[string]**uint64{
+		"stdout_offset": &offsets.Stdout,
+		"stderr_offset": &offsets.Stderr,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, BadRequestError{err})
+			return
+		}
+
+		*offset = &parsed
+	}
+
+	err = s.authorizeHandle(client, params["handle"])
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	container, err := s.backend.Lookup(params["handle"])
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	stream, err := container.Attach(processID, offsets)
+	if err != nil {
+		writeHTTPError(w, httpErrorStatus(err), err)
+		return
+	}
+
+	streamProcessToHTTP(w, processID, stream, query.Get("binary_payloads") == "true")
+}
+
+// httpEvents streams events, optionally only for the containers and of the
+// types given as repeated handle and type query parameters, until the
+// client goes away.
+func (s *WardenServer) httpEvents(client string, w http.ResponseWriter, r *http.Request, params httpParams) {
+	query := r.URL.Query()
+
+	types := []backend.EventType{}
+	for _, eventType := range query["type"] {
+		types = append(types, backend.EventType(eventType))
+	}
+
+	subscription := s.subscribe(client, query["handle"], types)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flushHTTP(w)
+
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			err := streamHTTP(w, eventMessage(event))
+			if err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+		}
+	}
+}
+
+func streamProcessToHTTP(w http.ResponseWriter, processID uint32, stream <-chan backend.ProcessStream, binaryPayloads bool) {
+	for payload := range stream {
+		err := streamHTTP(w, processPayload(processID, payload, binaryPayloads))
+		if err != nil {
+			// the client went away; keep the process from blocking on output
+			// nobody will read
+			go backend.DrainProcessStream(stream)
+			return
+		}
+	}
+}
+
+// streamHTTP writes a message as one line of a streamed response, flushing
+// it to the client.
+func streamHTTP(w http.ResponseWriter, message proto.Message) error {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	err := json.NewEncoder(w).Encode(message)
+	if err != nil {
+		return err
+	}
+
+	flushHTTP(w)
+
+	return nil
+}
+
+func flushHTTP(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func decodeHTTPRequest(r *http.Request, request proto.Message) error {
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err == io.EOF {
+		// no body; every field is optional or given in the path
+		return nil
+	}
+
+	if err != nil {
+		return BadRequestError{err}
+	}
+
+	return nil
+}
+
+func httpProcessID(params httpParams) (uint32, error) {
+	processID, err := strconv.ParseUint(params["process_id"], 10, 32)
+	if err != nil {
+		return 0, BadRequestError{err}
+	}
+
+	return uint32(processID), nil
+}
+
+func httpErrorStatus(err error) int {
+	switch errorCode(err) {
+	case protocol.ErrorResponse_invalid_request:
+		return http.StatusBadRequest
+	case protocol.ErrorResponse_unknown_handle, protocol.ErrorResponse_unknown_process:
+		return http.StatusNotFound
+	case protocol.ErrorResponse_not_permitted:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encodeErr := json.NewEncoder(w).Encode(errorResponse(err))
+	if encodeErr != nil {
+		log.Println("failed to write error response:", encodeErr)
+	}
+}
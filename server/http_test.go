@@ -0,0 +1,536 @@
+package server_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf-experimental/garden/backend"
+	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
+	protocol "github.com/pivotal-cf-experimental/garden/protocol"
+	"github.com/pivotal-cf-experimental/garden/server"
+)
+
+var _ = Describe("The HTTP API", func() {
+	var serverBackend *fake_backend.FakeBackend
+	var eventHub *event_hub.Hub
+
+	var httpServer *httptest.Server
+
+	BeforeEach(func() {
+		tmpdir, err := ioutil.TempDir(os.TempDir(), "warden-server-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		serverBackend = fake_backend.New()
+		eventHub = event_hub.New()
+
+		wardenServer := server.New(
+			"unix",
+			path.Join(tmpdir, "warden.sock"),
+			42*time.Second,
+			serverBackend,
+			eventHub,
+			nil,
+			nil,
+		)
+
+		err = wardenServer.Start()
+		Expect(err).ToNot(HaveOccurred())
+
+		httpServer = httptest.NewServer(wardenServer.HTTPHandler())
+	})
+
+	AfterEach(func() {
+		httpServer.Close()
+	})
+
+	request := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, httpServer.URL+path, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+
+		response, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		return response
+	}
+
+	decode := func(response *http.Response, message interface{}) {
+		defer response.Body.Close()
+
+		err := json.NewDecoder(response.Body).Decode(message)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	createContainer := func(handle string) *fake_backend.FakeContainer {
+		container, err := serverBackend.Create(backend.ContainerSpec{Handle: handle})
+		Expect(err).ToNot(HaveOccurred())
+
+		return container.(*fake_backend.FakeContainer)
+	}
+
+	It("responds to pings", func() {
+		response := request("GET", "/ping", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(Equal("application/json"))
+	})
+
+	It("responds with 404 for unknown routes", func() {
+		response := request("GET", "/bogus", "")
+		Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+
+		var errResponse protocol.ErrorResponse
+		decode(response, &errResponse)
+		Expect(errResponse.GetMessage()).To(ContainSubstring("/bogus"))
+	})
+
+	Describe("creating a container", func() {
+		It("creates it with the given spec", func() {
+			response := request("POST", "/containers", `{"handle":"some-handle","grace_time":1}`)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			var createResponse protocol.CreateResponse
+			decode(response, &createResponse)
+			Expect(createResponse.GetHandle()).To(Equal("some-handle"))
+
+			container, found := serverBackend.CreatedContainers["some-handle"]
+			Expect(found).To(BeTrue())
+			Expect(container.GraceTime()).To(Equal(1 * time.Second))
+		})
+
+		It("allows an empty body", func() {
+			response := request("POST", "/containers", "")
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(serverBackend.CreatedContainers).To(HaveLen(1))
+		})
+
+		Context("when the body is not valid JSON", func() {
+			It("responds with 400", func() {
+				response := request("POST", "/containers", "{")
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+
+				Expect(serverBackend.CreatedContainers).To(BeEmpty())
+			})
+		})
+
+		Context("when creating fails", func() {
+			BeforeEach(func() {
+				serverBackend.CreateError = errors.New("oh no!")
+			})
+
+			It("responds with 500 and the error message", func() {
+				response := request("POST", "/containers", "")
+				Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+
+				var errResponse protocol.ErrorResponse
+				decode(response, &errResponse)
+				Expect(errResponse.GetMessage()).To(Equal("oh no!"))
+			})
+		})
+	})
+
+	It("lists containers", func() {
+		createContainer("some-handle")
+
+		response := request("GET", "/containers", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var listResponse protocol.ListResponse
+		decode(response, &listResponse)
+		Expect(listResponse.GetHandles()).To(Equal([]string{"some-handle"}))
+	})
+
+	Describe("listing containers by property", func() {
+		It("lists only containers with the given properties", func() {
+			createContainer("some-handle")
+
+			_, err := serverBackend.Create(backend.ContainerSpec{
+				Handle:     "another-handle",
+				Properties: backend.Properties{"some-key": "some-value"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			response := request("GET", "/containers?property=some-key:some-value", "")
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			var listResponse protocol.ListResponse
+			decode(response, &listResponse)
+			Expect(listResponse.GetHandles()).To(Equal([]string{"another-handle"}))
+		})
+
+		Context("when a property has no value", func() {
+			It("responds with 400", func() {
+				response := request("GET", "/containers?property=some-key", "")
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	It("reports info for many containers at once", func() {
+		createContainer("some-handle").ReportedInfo = backend.ContainerInfo{State: "active"}
+		createContainer("another-handle")
+
+		response := request("GET", "/containers/info?handle=some-handle", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var bulkInfoResponse protocol.BulkInfoResponse
+		decode(response, &bulkInfoResponse)
+		Expect(bulkInfoResponse.GetEntries()).To(HaveLen(1))
+		Expect(bulkInfoResponse.GetEntries()[0].GetHandle()).To(Equal("some-handle"))
+		Expect(bulkInfoResponse.GetEntries()[0].GetInfo().GetState()).To(Equal("active"))
+	})
+
+	It("destroys containers", func() {
+		createContainer("some-handle")
+
+		response := request("DELETE", "/containers/some-handle", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(serverBackend.DestroyedContainers).To(ContainElement("some-handle"))
+	})
+
+	It("removes containers' health checks", func() {
+		container := createContainer("some-handle")
+		container.HealthCheck = &backend.HealthCheckSpec{
+			Process: backend.ProcessSpec{Script: "/some/check"},
+		}
+
+		response := request("DELETE", "/containers/some-handle/health_check", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(container.HealthCheck).To(BeNil())
+	})
+
+	It("stops containers", func() {
+		container := createContainer("some-handle")
+
+		response := request("POST", "/containers/some-handle/stop", `{"kill":true}`)
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(container.Stopped()).To(ContainElement(fake_backend.StopSpec{Killed: true}))
+	})
+
+	Describe("running a process", func() {
+		var container *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container = createContainer("some-handle")
+		})
+
+		It("streams the process's payloads as lines of JSON", func() {
+			container.RunningProcessID = 123
+			exitStatus := uint32(42)
+
+			container.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("process out\n"),
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			response := request("POST", "/containers/some-handle/processes", `{"script":"/some/script"}`)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			payloads := json.NewDecoder(response.Body)
+
+			var payload protocol.ProcessPayload
+
+			err := payloads.Decode(&payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.GetProcessId()).To(Equal(uint32(123)))
+			Expect(payload.Data).To(BeNil())
+
+			payload = protocol.ProcessPayload{}
+			err = payloads.Decode(&payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.GetSource()).To(Equal(protocol.ProcessPayload_stdout))
+			Expect(payload.GetData()).To(Equal("process out\n"))
+
+			payload = protocol.ProcessPayload{}
+			err = payloads.Decode(&payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload.GetExitStatus()).To(Equal(uint32(42)))
+
+			Expect(container.RunningProcesses).To(HaveLen(1))
+			Expect(container.RunningProcesses[0].Script).To(Equal("/some/script"))
+		})
+
+		It("keeps stdin open for writing separately if asked to", func() {
+			container.RunningProcessID = 123
+
+			response := request("POST", "/containers/some-handle/processes", `{"script":"/some/script","detach":true,"stream_stdin":true}`)
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(container.RunningProcesses).To(HaveLen(1))
+			Expect(container.RunningProcesses[0].StreamStdin).To(BeTrue())
+		})
+
+		Context("when detached", func() {
+			It("responds with only the process ID", func() {
+				container.RunningProcessID = 123
+
+				response := request("POST", "/containers/some-handle/processes", `{"script":"/some/script","detach":true}`)
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(strings.TrimSpace(string(body))).To(Equal(`{"process_id":123}`))
+			})
+		})
+
+		Context("when the container is not found", func() {
+			It("responds with 404 and the error's code", func() {
+				response := request("POST", "/containers/bogus-handle/processes", `{"script":"/some/script"}`)
+				Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+
+				var errResponse protocol.ErrorResponse
+				decode(response, &errResponse)
+				Expect(errResponse.GetCode()).To(Equal(protocol.ErrorResponse_unknown_handle))
+			})
+		})
+	})
+
+	Describe("attaching to a process", func() {
+		It("streams from the given offsets", func() {
+			container := createContainer("some-handle")
+
+			container.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStderr,
+					Data:   []byte("process err\n"),
+				},
+			}
+
+			response := request("GET", "/containers/some-handle/processes/123?stdout_offset=3&stderr_offset=7", "")
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			var payload protocol.ProcessPayload
+			decode(response, &payload)
+			Expect(payload.GetProcessId()).To(Equal(uint32(123)))
+			Expect(payload.GetSource()).To(Equal(protocol.ProcessPayload_stderr))
+			Expect(payload.GetData()).To(Equal("process err\n"))
+
+			Expect(container.Attached).To(HaveLen(1))
+			Expect(container.Attached[0].ProcessID).To(Equal(uint32(123)))
+			Expect(container.Attached[0].Offsets.Stdout).To(Equal(uint64ptr(3)))
+			Expect(container.Attached[0].Offsets.Stderr).To(Equal(uint64ptr(7)))
+		})
+
+		Context("when the process ID is not a number", func() {
+			It("responds with 400", func() {
+				createContainer("some-handle")
+
+				response := request("GET", "/containers/some-handle/processes/bogus", "")
+				Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Context("when the process is not found", func() {
+		It("responds with 404", func() {
+			container := createContainer("some-handle")
+			container.StdinError = fake_backend.UnknownProcessError{ProcessID: 123}
+
+			response := request("POST", "/containers/some-handle/processes/123/stdin", "some input")
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+
+			var errResponse protocol.ErrorResponse
+			decode(response, &errResponse)
+			Expect(errResponse.GetCode()).To(Equal(protocol.ErrorResponse_unknown_process))
+		})
+	})
+
+	Context("with an authorizer forbidding privileged processes", func() {
+		var authorizedServer *httptest.Server
+
+		BeforeEach(func() {
+			tmpdir, err := ioutil.TempDir(os.TempDir(), "warden-server-test")
+			Expect(err).ToNot(HaveOccurred())
+
+			authorizer, err := server.NewAuthorizer(server.AuthorizationPolicy{ForbidPrivileged: true}, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			wardenServer := server.New(
+				"unix",
+				path.Join(tmpdir, "warden.sock"),
+				42*time.Second,
+				serverBackend,
+				eventHub,
+				nil,
+				authorizer,
+			)
+
+			err = wardenServer.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			authorizedServer = httptest.NewServer(wardenServer.HTTPHandler())
+
+			response, err := http.Post(authorizedServer.URL+"/containers", "application/json", strings.NewReader(`{"handle":"some-handle"}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		AfterEach(func() {
+			authorizedServer.Close()
+		})
+
+		It("responds with 403 to privileged runs", func() {
+			response, err := http.Post(authorizedServer.URL+"/containers/some-handle/processes", "application/json", strings.NewReader(`{"script":"/some/script","privileged":true}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+			var errResponse protocol.ErrorResponse
+			decode(response, &errResponse)
+			Expect(errResponse.GetCode()).To(Equal(protocol.ErrorResponse_not_permitted))
+		})
+	})
+
+	It("writes the body to a process's stdin and closes it", func() {
+		container := createContainer("some-handle")
+
+		response := request("POST", "/containers/some-handle/processes/123/stdin", "some input")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(container.StdinWriter.Written()).To(Equal("some input"))
+		Expect(container.StdinWriter.IsClosed()).To(BeTrue())
+	})
+
+	It("signals processes", func() {
+		container := createContainer("some-handle")
+
+		response := request("POST", "/containers/some-handle/processes/123/signal", `{"signal":"term"}`)
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(container.Signalled).To(ContainElement(fake_backend.SignalSpec{ProcessID: 123, Signal: syscall.SIGTERM}))
+	})
+
+	It("waits for processes", func() {
+		container := createContainer("some-handle")
+		container.WaitExitStatus = 42
+
+		response := request("GET", "/containers/some-handle/processes/123/wait?timeout=5", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var waitResponse protocol.WaitResponse
+		decode(response, &waitResponse)
+		Expect(waitResponse.GetExitStatus()).To(Equal(uint32(42)))
+
+		Expect(container.Waited()).To(ContainElement(fake_backend.WaitSpec{ProcessID: 123, Timeout: time.Second}))
+	})
+
+	It("streams events matching the query", func(done Done) {
+		response := request("GET", "/events?handle=some-handle&type=started", "")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		defer response.Body.Close()
+
+		eventHub.Publish(backend.ContainerEvent{
+			Type:   backend.EventTypeStarted,
+			Handle: "other-handle",
+			Time:   time.Now(),
+		})
+
+		eventHub.Publish(backend.ContainerEvent{
+			Type:   backend.EventTypeStopped,
+			Handle: "some-handle",
+			Time:   time.Now(),
+		})
+
+		eventHub.Publish(backend.ContainerEvent{
+			Type:   backend.EventTypeStarted,
+			Handle: "some-handle",
+			Time:   time.Unix(123, 0),
+		})
+
+		line, err := bufio.NewReader(response.Body).ReadBytes('\n')
+		Expect(err).ToNot(HaveOccurred())
+
+		var event protocol.Event
+		err = json.Unmarshal(line, &event)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(event.GetType()).To(Equal("started"))
+		Expect(event.GetHandle()).To(Equal("some-handle"))
+		Expect(event.GetTime()).To(Equal(uint64(123)))
+
+		close(done)
+	}, 5.0)
+})
+
+var _ = Describe("Starting the HTTP API", func() {
+	var tlsConfig *tls.Config
+	var wardenServer *server.WardenServer
+
+	BeforeEach(func() {
+		tlsConfig = nil
+	})
+
+	JustBeforeEach(func() {
+		tmpdir, err := ioutil.TempDir(os.TempDir(), "warden-server-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		wardenServer = server.New(
+			"unix",
+			path.Join(tmpdir, "warden.sock"),
+			0,
+			fake_backend.New(),
+			event_hub.New(),
+			tlsConfig,
+			nil,
+		)
+
+		err = wardenServer.Start()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		wardenServer.Stop()
+	})
+
+	It("serves on a loopback address without authentication", func() {
+		err := wardenServer.StartHTTP("tcp", "127.0.0.1:60131")
+		Expect(err).ToNot(HaveOccurred())
+
+		response, err := http.Get("http://127.0.0.1:60131/ping")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("refuses to serve beyond the host without authentication", func() {
+		for _, addr := range []string{"0.0.0.0:60131", ":60131", "example.com:60131"} {
+			err := wardenServer.StartHTTP("tcp", addr)
+			Expect(err).To(Equal(server.UnauthenticatedHTTPError{addr}))
+		}
+
+		Expect(ErrorDialing("tcp", "127.0.0.1:60131")()).To(HaveOccurred())
+	})
+
+	Context("when clients must authenticate with certificates", func() {
+		BeforeEach(func() {
+			tlsConfig = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+		})
+
+		It("serves beyond the host", func() {
+			err := wardenServer.StartHTTP("tcp", "0.0.0.0:60131")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ErrorDialing("tcp", "127.0.0.1:60131")()).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -1,7 +1,12 @@
 package server
 
 import (
-	"net"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"sort"
+	"syscall"
 	"time"
 
 	"code.google.com/p/gogoprotobuf/proto"
@@ -18,35 +23,47 @@ func (s *WardenServer) handleEcho(echo *protocol.EchoRequest) (proto.Message, er
 	return &protocol.EchoResponse{Message: echo.Message}, nil
 }
 
-func (s *WardenServer) handleCreate(create *protocol.CreateRequest) (proto.Message, error) {
-	bindMounts := []backend.BindMount{}
-
-	for _, bm := range create.GetBindMounts() {
-		bindMount := backend.BindMount{
-			SrcPath: bm.GetSrcPath(),
-			DstPath: bm.GetDstPath(),
-			Mode:    backend.BindMountMode(bm.GetMode()),
-			Origin:  backend.BindMountOrigin(bm.GetOrigin()),
-		}
-
-		bindMounts = append(bindMounts, bindMount)
-	}
-
+func (s *WardenServer) handleCreate(client string, create *protocol.CreateRequest) (proto.Message, error) {
 	graceTime := s.containerGraceTime
 
 	if create.GraceTime != nil {
 		graceTime = time.Duration(create.GetGraceTime()) * time.Second
 	}
 
+	handle := create.GetHandle()
+
+	// claimed before the container exists, so that its owner sees its
+	// created and started events, and nobody else can take the handle
+	if s.authorizer != nil {
+		if handle == "" {
+			generated, err := generateHandle()
+			if err != nil {
+				return nil, err
+			}
+
+			handle = generated
+		}
+
+		err := s.authorizer.Claim(client, handle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	container, err := s.backend.Create(backend.ContainerSpec{
-		Handle:     create.GetHandle(),
+		Handle:     handle,
 		GraceTime:  graceTime,
 		RootFSPath: create.GetRootfs(),
 		Network:    create.GetNetwork(),
-		BindMounts: bindMounts,
+		BindMounts: bindMounts(create),
+		Properties: properties(create.GetProperties()),
 	})
 
 	if err != nil {
+		if s.authorizer != nil {
+			s.authorizer.Release(handle)
+		}
+
 		return nil, err
 	}
 
@@ -67,18 +84,91 @@ func (s *WardenServer) handleDestroy(destroy *protocol.DestroyRequest) (proto.Me
 
 	s.bomberman.Defuse(handle)
 
+	if s.authorizer != nil {
+		s.authorizer.Release(handle)
+	}
+
 	return &protocol.DestroyResponse{}, nil
 }
 
-func (s *WardenServer) handleList(list *protocol.ListRequest) (proto.Message, error) {
+// generateHandle makes up a handle for a container whose owner has to
+// claim it before the backend would otherwise pick one.
+func generateHandle() (string, error) {
+	id := make([]byte, 8)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+func bindMounts(create *protocol.CreateRequest) []backend.BindMount {
+	bindMounts := []backend.BindMount{}
+
+	for _, bm := range create.GetBindMounts() {
+		bindMount := backend.BindMount{
+			SrcPath: bm.GetSrcPath(),
+			DstPath: bm.GetDstPath(),
+			Mode:    backend.BindMountMode(bm.GetMode()),
+			Origin:  backend.BindMountOrigin(bm.GetOrigin()),
+		}
+
+		bindMounts = append(bindMounts, bindMount)
+	}
+
+	return bindMounts
+}
+
+func properties(props []*protocol.Property) backend.Properties {
+	properties := backend.Properties{}
+
+	for _, prop := range props {
+		properties[prop.GetKey()] = prop.GetValue()
+	}
+
+	return properties
+}
+
+func protocolProperties(properties backend.Properties) []*protocol.Property {
+	keys := []string{}
+	for key := range properties {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	props := []*protocol.Property{}
+	for _, key := range keys {
+		props = append(props, &protocol.Property{
+			Key:   proto.String(key),
+			Value: proto.String(properties[key]),
+		})
+	}
+
+	return props
+}
+
+func (s *WardenServer) handleList(client string, list *protocol.ListRequest) (proto.Message, error) {
 	containers, err := s.backend.Containers()
 	if err != nil {
 		return nil, err
 	}
 
+	filter := properties(list.GetProperties())
+
 	handles := []string{}
 
 	for _, container := range containers {
+		if s.authorizer != nil && !s.authorizer.Owns(client, container.Handle()) {
+			continue
+		}
+
+		if !container.Properties().Matches(filter) {
+			continue
+		}
+
 		handles = append(handles, container.Handle())
 	}
 
@@ -90,6 +180,14 @@ func (s *WardenServer) handleCopyOut(copyOut *protocol.CopyOutRequest) (proto.Me
 	srcPath := copyOut.GetSrcPath()
 	dstPath := copyOut.GetDstPath()
 	owner := copyOut.GetOwner()
+	user := copyOut.GetUser()
+
+	for _, user := range []string{owner, user} {
+		err := backend.ValidateUser(user)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	container, err := s.backend.Lookup(handle)
 	if err != nil {
@@ -99,7 +197,7 @@ func (s *WardenServer) handleCopyOut(copyOut *protocol.CopyOutRequest) (proto.Me
 	s.bomberman.Pause(container.Handle())
 	defer s.bomberman.Unpause(container.Handle())
 
-	err = container.CopyOut(srcPath, dstPath, owner)
+	err = container.CopyOut(srcPath, dstPath, owner, user)
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +234,12 @@ func (s *WardenServer) handleCopyIn(copyIn *protocol.CopyInRequest) (proto.Messa
 	handle := copyIn.GetHandle()
 	srcPath := copyIn.GetSrcPath()
 	dstPath := copyIn.GetDstPath()
+	user := copyIn.GetUser()
+
+	err := backend.ValidateUser(user)
+	if err != nil {
+		return nil, err
+	}
 
 	container, err := s.backend.Lookup(handle)
 	if err != nil {
@@ -145,7 +249,7 @@ func (s *WardenServer) handleCopyIn(copyIn *protocol.CopyInRequest) (proto.Messa
 	s.bomberman.Pause(container.Handle())
 	defer s.bomberman.Unpause(container.Handle())
 
-	err = container.CopyIn(srcPath, dstPath)
+	err = container.CopyIn(srcPath, dstPath, user)
 	if err != nil {
 		return nil, err
 	}
@@ -377,40 +481,156 @@ func (s *WardenServer) handleNetOut(request *protocol.NetOutRequest) (proto.Mess
 	return &protocol.NetOutResponse{}, nil
 }
 
-func (s *WardenServer) streamProcessToConnection(processID uint32, stream <-chan backend.ProcessStream, conn net.Conn) proto.Message {
+func (s *WardenServer) streamProcessToConnection(
+	responses *responseWriter,
+	requests *requestQueue,
+	container backend.Container,
+	processID uint32,
+	stream <-chan backend.ProcessStream,
+	binaryPayloads bool,
+) proto.Message {
+	streaming := make(chan struct{})
+	forwarding := make(chan struct{})
+
+	go func() {
+		s.forwardProcessInput(requests, container, processID, streaming)
+		close(forwarding)
+	}()
+
+	defer func() {
+		close(streaming)
+		<-forwarding
+	}()
+
 	for payload := range stream {
 		if payload.ExitStatus != nil {
-			return &protocol.ProcessPayload{
-				ProcessId:  proto.Uint32(processID),
-				ExitStatus: proto.Uint32(*payload.ExitStatus),
-			}
+			return processPayload(processID, payload, binaryPayloads)
 		}
 
-		var payloadSource protocol.ProcessPayload_Source
+		responses.Write(processPayload(processID, payload, binaryPayloads))
+	}
+
+	return nil
+}
+
+func processPayload(processID uint32, payload backend.ProcessStream, binaryPayloads bool) *protocol.ProcessPayload {
+	if payload.ExitStatus != nil {
+		exitPayload := &protocol.ProcessPayload{
+			ProcessId:  proto.Uint32(processID),
+			ExitStatus: proto.Uint32(*payload.ExitStatus),
+		}
 
-		switch payload.Source {
-		case backend.ProcessStreamSourceStdout:
-			payloadSource = protocol.ProcessPayload_stdout
-		case backend.ProcessStreamSourceStderr:
-			payloadSource = protocol.ProcessPayload_stderr
-		case backend.ProcessStreamSourceStdin:
-			payloadSource = protocol.ProcessPayload_stdin
+		if payload.TimedOut {
+			exitPayload.TimedOut = proto.Bool(true)
 		}
 
-		protocol.Messages(&protocol.ProcessPayload{
-			ProcessId: proto.Uint32(processID),
-			Source:    &payloadSource,
-			Data:      proto.String(string(payload.Data)),
-		}).WriteTo(conn)
+		return exitPayload
 	}
 
-	return nil
+	var payloadSource protocol.ProcessPayload_Source
+
+	switch payload.Source {
+	case backend.ProcessStreamSourceStdout:
+		payloadSource = protocol.ProcessPayload_stdout
+	case backend.ProcessStreamSourceStderr:
+		payloadSource = protocol.ProcessPayload_stderr
+	case backend.ProcessStreamSourceStdin:
+		payloadSource = protocol.ProcessPayload_stdin
+	}
+
+	dataPayload := &protocol.ProcessPayload{
+		ProcessId: proto.Uint32(processID),
+		Source:    &payloadSource,
+		Offset:    proto.Uint64(payload.Offset),
+	}
+
+	// clients that did not ask for binary payloads only know of the string
+	// field, which is not guaranteed to survive non-UTF-8 data
+	if binaryPayloads {
+		dataPayload.DataBytes = payload.Data
+	} else {
+		dataPayload.Data = proto.String(string(payload.Data))
+	}
+
+	return dataPayload
 }
 
-func (s *WardenServer) handleRun(conn net.Conn, request *protocol.RunRequest) (proto.Message, error) {
+func (s *WardenServer) forwardProcessInput(
+	requests *requestQueue,
+	container backend.Container,
+	processID uint32,
+	streaming <-chan struct{},
+) {
+	for {
+		select {
+		case request, ok := <-requests.incoming:
+			if !ok {
+				return
+			}
+
+			payload, ok := request.(*protocol.ProcessPayload)
+			if !ok || payload.GetProcessId() != processID {
+				requests.held = request
+				return
+			}
+
+			if size := payload.GetTty().GetWindowSize(); size != nil {
+				err := container.SetWindowSize(processID, backend.WindowSize{
+					Columns: int(size.GetColumns()),
+					Rows:    int(size.GetRows()),
+				})
+
+				if err != nil {
+					log.Println("failed to resize tty for process", processID, "in", container.Handle()+":", err)
+				}
+			}
+
+			if payload.GetSource() != protocol.ProcessPayload_stdin {
+				continue
+			}
+
+			if payload.Data == nil && payload.DataBytes == nil && !payload.GetEof() {
+				continue
+			}
+
+			stdin, err := container.Stdin(processID)
+			if err != nil {
+				log.Println("dropping stdin for process", processID, "in", container.Handle()+":", err)
+				continue
+			}
+
+			var data []byte
+
+			if payload.DataBytes != nil {
+				data = payload.GetDataBytes()
+			} else if payload.Data != nil {
+				data = []byte(payload.GetData())
+			}
+
+			if data != nil {
+				_, err := stdin.Write(data)
+				if err != nil {
+					log.Println("failed to write stdin for process", processID, "in", container.Handle()+":", err)
+				}
+			}
+
+			if payload.GetEof() {
+				stdin.Close()
+			}
+
+		case <-streaming:
+			return
+		}
+	}
+}
+
+func (s *WardenServer) handleRun(client string, responses *responseWriter, requests *requestQueue, request *protocol.RunRequest) (proto.Message, error) {
+	err := s.authorize(client, request)
+	if err != nil {
+		return nil, err
+	}
+
 	handle := request.GetHandle()
-	script := request.GetScript()
-	privileged := request.GetPrivileged()
 
 	container, err := s.backend.Lookup(handle)
 	if err != nil {
@@ -420,31 +640,244 @@ func (s *WardenServer) handleRun(conn net.Conn, request *protocol.RunRequest) (p
 	s.bomberman.Pause(container.Handle())
 	defer s.bomberman.Unpause(container.Handle())
 
+	processID, stream, err := startProcess(container, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if stream == nil {
+		return &protocol.ProcessPayload{
+			ProcessId: proto.Uint32(processID),
+		}, nil
+	}
+
+	responses.Write(&protocol.ProcessPayload{
+		ProcessId: proto.Uint32(processID),
+	})
+
+	return s.streamProcessToConnection(responses, requests, container, processID, stream, request.GetBinaryPayloads()), nil
+}
+
+// startProcess runs the requested process in the container, returning the
+// stream of its output, or nil if the process was detached or is
+// supervised and so is not to be streamed.
+func startProcess(container backend.Container, request *protocol.RunRequest) (uint32, <-chan backend.ProcessStream, error) {
+	script := request.GetScript()
+	privileged := request.GetPrivileged()
+	streamStdin := request.GetStreamStdin()
+
 	ProcessSpec := backend.ProcessSpec{
-		Script:     script,
-		Privileged: privileged,
+		Script:      script,
+		Path:        request.GetPath(),
+		Args:        request.GetArgs(),
+		Dir:         request.GetDir(),
+		User:        request.GetUser(),
+		Privileged:  privileged,
+		StreamStdin: streamStdin,
+		Timeout:     time.Duration(request.GetTimeout()) * time.Second,
+	}
+
+	for _, env := range request.GetEnv() {
+		ProcessSpec.Env = append(ProcessSpec.Env, env.GetKey()+"="+env.GetValue())
 	}
 
 	if request.Rlimits != nil {
 		ProcessSpec.Limits = resourceLimits(request.Rlimits)
 	}
 
+	if request.Tty != nil {
+		ProcessSpec.TTY = &backend.TTYSpec{}
+
+		if size := request.Tty.GetWindowSize(); size != nil {
+			ProcessSpec.TTY.WindowSize = &backend.WindowSize{
+				Columns: int(size.GetColumns()),
+				Rows:    int(size.GetRows()),
+			}
+		}
+	}
+
+	if request.Restart != nil {
+		processID, err := container.Supervise(ProcessSpec, backend.RestartPolicy{
+			Mode:        restartMode(request.GetRestart()),
+			MaxRestarts: int(request.GetMaxRestarts()),
+			Backoff:     time.Duration(request.GetRestartBackoff()) * time.Second,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// supervised processes outlive any one run, so they are never streamed
+		return processID, nil, nil
+	}
+
 	processID, stream, err := container.Run(ProcessSpec)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if request.GetDetach() {
+		// nobody is streaming the output, so keep it moving rather than
+		// hold up the process; it can still be attached to later
+		go backend.DrainProcessStream(stream)
+
+		return processID, nil, nil
+	}
+
+	return processID, stream, nil
+}
+
+func (s *WardenServer) handleAttach(client string, responses *responseWriter, requests *requestQueue, request *protocol.AttachRequest) (proto.Message, error) {
+	err := s.authorize(client, request)
 	if err != nil {
 		return nil, err
 	}
 
-	protocol.Messages(&protocol.ProcessPayload{
-		ProcessId: proto.Uint32(processID),
-	}).WriteTo(conn)
+	handle := request.GetHandle()
+	processID := request.GetProcessId()
+
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.streamProcessToConnection(processID, stream, conn), nil
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	stream, err := container.Attach(processID, backend.ProcessStreamOffsets{
+		Stdout: request.StdoutOffset,
+		Stderr: request.StderrOffset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.streamProcessToConnection(responses, requests, container, processID, stream, request.GetBinaryPayloads()), nil
 }
 
-func (s *WardenServer) handleAttach(conn net.Conn, request *protocol.AttachRequest) (proto.Message, error) {
+func (s *WardenServer) handleSignal(request *protocol.SignalRequest) (proto.Message, error) {
 	handle := request.GetHandle()
 	processID := request.GetProcessId()
 
+	var signal os.Signal
+
+	switch request.GetSignal() {
+	case protocol.SignalRequest_hup:
+		signal = syscall.SIGHUP
+	case protocol.SignalRequest_int:
+		signal = syscall.SIGINT
+	case protocol.SignalRequest_quit:
+		signal = syscall.SIGQUIT
+	case protocol.SignalRequest_kill:
+		signal = syscall.SIGKILL
+	case protocol.SignalRequest_usr1:
+		signal = syscall.SIGUSR1
+	case protocol.SignalRequest_usr2:
+		signal = syscall.SIGUSR2
+	case protocol.SignalRequest_term:
+		signal = syscall.SIGTERM
+	default:
+		return nil, UnknownSignalError{request.GetSignal()}
+	}
+
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	err = container.Signal(processID, signal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.SignalResponse{}, nil
+}
+
+// handleWait waits for the process to exit, a slice of the timeout at a
+// time so that it can give up once the client has gone, as reported by
+// abandoned, or the server is stopping.
+func (s *WardenServer) handleWait(client string, request *protocol.WaitRequest, abandoned func() bool) (proto.Message, error) {
+	err := s.authorize(client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := request.GetHandle()
+	processID := request.GetProcessId()
+	timeout := time.Duration(request.GetTimeout()) * time.Second
+
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		wait := waitPollInterval
+		last := false
+
+		if timeout != 0 {
+			remaining := deadline.Sub(time.Now())
+			if remaining <= wait {
+				wait = remaining
+				last = true
+			}
+
+			// the backend would take no time at all to mean forever
+			if wait <= 0 {
+				wait = time.Nanosecond
+			}
+		}
+
+		exitStatus, err := container.Wait(processID, wait)
+		if err == nil {
+			return &protocol.WaitResponse{
+				ExitStatus: proto.Uint32(exitStatus),
+			}, nil
+		}
+
+		if last || errorCode(err) != protocol.ErrorResponse_timed_out {
+			return nil, err
+		}
+
+		if abandoned() || <-s.stopping {
+			return nil, err
+		}
+	}
+}
+
+// abandonedBy reports whether the client has closed the connection the
+// requests are read from. A request that arrives in the meantime is held
+// for once the current one is done.
+func abandonedBy(requests *requestQueue) func() bool {
+	return func() bool {
+		if requests.held != nil {
+			return false
+		}
+
+		select {
+		case request, ok := <-requests.incoming:
+			if !ok {
+				return true
+			}
+
+			requests.held = request
+		default:
+		}
+
+		return false
+	}
+}
+
+func (s *WardenServer) handleListProcesses(request *protocol.ListProcessesRequest) (proto.Message, error) {
+	handle := request.GetHandle()
+
 	container, err := s.backend.Lookup(handle)
 	if err != nil {
 		return nil, err
@@ -453,12 +886,109 @@ func (s *WardenServer) handleAttach(conn net.Conn, request *protocol.AttachReque
 	s.bomberman.Pause(container.Handle())
 	defer s.bomberman.Unpause(container.Handle())
 
-	stream, err := container.Attach(processID)
+	processes, err := container.Processes()
 	if err != nil {
 		return nil, err
 	}
 
-	return s.streamProcessToConnection(processID, stream, conn), nil
+	response := &protocol.ListProcessesResponse{}
+
+	for _, process := range processes {
+		processInfo := &protocol.ListProcessesResponse_Process{
+			ProcessId: proto.Uint32(process.ID),
+			Command:   proto.String(process.Command),
+			StartedAt: proto.Uint64(uint64(process.StartedAt.Unix())),
+		}
+
+		if process.ExitStatus != nil {
+			processInfo.FinishedAt = proto.Uint64(uint64(process.FinishedAt.Unix()))
+			processInfo.ExitStatus = proto.Uint32(*process.ExitStatus)
+			processInfo.TimedOut = proto.Bool(process.TimedOut)
+		}
+
+		response.Processes = append(response.Processes, processInfo)
+	}
+
+	return response, nil
+}
+
+func (s *WardenServer) handleHealthCheck(request *protocol.HealthCheckRequest) (proto.Message, error) {
+	handle := request.GetHandle()
+
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bomberman.Pause(container.Handle())
+	defer s.bomberman.Unpause(container.Handle())
+
+	if request.GetRemove() {
+		err = container.RemoveHealthCheck()
+		if err != nil {
+			return nil, err
+		}
+
+		return &protocol.HealthCheckResponse{}, nil
+	}
+
+	spec := backend.HealthCheckSpec{
+		Process: backend.ProcessSpec{
+			Script:     request.GetScript(),
+			Path:       request.GetPath(),
+			Args:       request.GetArgs(),
+			Dir:        request.GetDir(),
+			User:       request.GetUser(),
+			Privileged: request.GetPrivileged(),
+		},
+
+		Interval:         time.Duration(request.GetInterval()) * time.Second,
+		Timeout:          time.Duration(request.GetTimeout()) * time.Second,
+		FailureThreshold: int(request.GetFailureThreshold()),
+	}
+
+	for _, env := range request.GetEnv() {
+		spec.Process.Env = append(spec.Process.Env, env.GetKey()+"="+env.GetValue())
+	}
+
+	err = container.SetHealthCheck(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.HealthCheckResponse{}, nil
+}
+
+// handleEvents streams events to the client until it closes the connection
+// or sends another request, which is then handled as usual. A multiplexed
+// stream is ended by any further message with its request ID.
+func (s *WardenServer) handleEvents(client string, responses *responseWriter, requests *requestQueue, request *protocol.EventsRequest) (proto.Message, error) {
+	types := []backend.EventType{}
+	for _, eventType := range request.GetTypes() {
+		types = append(types, backend.EventType(eventType))
+	}
+
+	subscription := s.subscribe(client, request.GetHandles(), types)
+	defer subscription.Close()
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			err := responses.Write(eventMessage(event))
+			if err != nil {
+				return nil, nil
+			}
+
+		case request, ok := <-requests.incoming:
+			if !ok {
+				return nil, nil
+			}
+
+			requests.held = request
+
+			return nil, nil
+		}
+	}
 }
 
 func (s *WardenServer) handleInfo(request *protocol.InfoRequest) (proto.Message, error) {
@@ -477,11 +1007,111 @@ func (s *WardenServer) handleInfo(request *protocol.InfoRequest) (proto.Message,
 		return nil, err
 	}
 
+	return infoResponse(info), nil
+}
+
+// handleBulkInfo gets the info of the given containers, or of all of them
+// if none are given, with an error in place of the info of any that could
+// not be had. Unlike Info, it does not reset the containers' grace times, so
+// that polling for metrics does not keep idle containers around.
+func (s *WardenServer) handleBulkInfo(client string, request *protocol.BulkInfoRequest) (proto.Message, error) {
+	handles := request.GetHandles()
+
+	if len(handles) == 0 {
+		containers, err := s.backend.Containers()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range containers {
+			if s.authorizer != nil && !s.authorizer.Owns(client, container.Handle()) {
+				continue
+			}
+
+			handles = append(handles, container.Handle())
+		}
+
+		sort.Strings(handles)
+	}
+
+	unauthorized := make(map[string]error)
+	authorized := []string{}
+
+	for _, handle := range handles {
+		err := s.authorizeHandle(client, handle)
+		if err != nil {
+			unauthorized[handle] = err
+			continue
+		}
+
+		authorized = append(authorized, handle)
+	}
+
+	infos := s.backend.BulkInfo(authorized)
+
+	response := &protocol.BulkInfoResponse{}
+
+	for _, handle := range handles {
+		entry := &protocol.BulkInfoResponse_Entry{
+			Handle: proto.String(handle),
+		}
+
+		err, found := unauthorized[handle]
+		if !found {
+			err = infos[handle].Err
+		}
+
+		if err != nil {
+			entry.Error = errorResponse(err)
+		} else {
+			entry.Info = infoResponse(infos[handle].Info)
+		}
+
+		response.Entries = append(response.Entries, entry)
+	}
+
+	return response, nil
+}
+
+func infoResponse(info backend.ContainerInfo) *protocol.InfoResponse {
 	processIDs := make([]uint64, len(info.ProcessIDs))
 	for i, processID := range info.ProcessIDs {
 		processIDs[i] = uint64(processID)
 	}
 
+	supervisedProcesses := []*protocol.InfoResponse_SupervisedProcess{}
+	for _, supervised := range info.SupervisedProcesses {
+		supervisedProcess := &protocol.InfoResponse_SupervisedProcess{
+			Id:          proto.Uint32(supervised.ID),
+			ProcessId:   proto.Uint32(supervised.ProcessID),
+			Running:     proto.Bool(supervised.Running),
+			Restarts:    proto.Uint32(uint32(supervised.Restarts)),
+			Restart:     protocolRestartMode(supervised.Policy.Mode).Enum(),
+			MaxRestarts: proto.Uint32(uint32(supervised.Policy.MaxRestarts)),
+		}
+
+		if supervised.LastExitStatus != nil {
+			supervisedProcess.LastExitStatus = proto.Uint32(*supervised.LastExitStatus)
+		}
+
+		supervisedProcesses = append(supervisedProcesses, supervisedProcess)
+	}
+
+	var healthCheck *protocol.InfoResponse_HealthCheck
+	if info.HealthCheck != nil {
+		healthCheck = &protocol.InfoResponse_HealthCheck{
+			Status:              proto.String(string(info.HealthCheck.Status)),
+			ConsecutiveFailures: proto.Uint32(uint32(info.HealthCheck.ConsecutiveFailures)),
+			Interval:            proto.Uint32(uint32(info.HealthCheck.Spec.Interval / time.Second)),
+			Timeout:             proto.Uint32(uint32(info.HealthCheck.Spec.Timeout / time.Second)),
+			FailureThreshold:    proto.Uint32(uint32(info.HealthCheck.Spec.FailureThreshold)),
+		}
+
+		if !info.HealthCheck.LastCheckedAt.IsZero() {
+			healthCheck.LastCheckedAt = proto.Uint64(uint64(info.HealthCheck.LastCheckedAt.Unix()))
+		}
+	}
+
 	return &protocol.InfoResponse{
 		State:         proto.String(info.State),
 		Events:        info.Events,
@@ -489,6 +1119,10 @@ func (s *WardenServer) handleInfo(request *protocol.InfoRequest) (proto.Message,
 		ContainerIp:   proto.String(info.ContainerIP),
 		ContainerPath: proto.String(info.ContainerPath),
 		ProcessIds:    processIDs,
+		Properties:    protocolProperties(info.Properties),
+
+		SupervisedProcesses: supervisedProcesses,
+		HealthCheck:         healthCheck,
 
 		MemoryStat: &protocol.InfoResponse_MemoryStat{
 			Cache:                   proto.Uint64(info.MemoryStat.Cache),
@@ -538,7 +1172,7 @@ func (s *WardenServer) handleInfo(request *protocol.InfoRequest) (proto.Message,
 			OutRate:  proto.Uint64(info.BandwidthStat.OutRate),
 			OutBurst: proto.Uint64(info.BandwidthStat.OutBurst),
 		},
-	}, nil
+	}
 }
 
 func resourceLimits(limits *protocol.ResourceLimits) backend.ResourceLimits {
@@ -560,3 +1194,49 @@ func resourceLimits(limits *protocol.ResourceLimits) backend.ResourceLimits {
 		Stack:      limits.Stack,
 	}
 }
+
+func eventMessage(event backend.ContainerEvent) *protocol.Event {
+	keys := []string{}
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	details := []*protocol.Event_Detail{}
+	for _, key := range keys {
+		details = append(details, &protocol.Event_Detail{
+			Key:   proto.String(key),
+			Value: proto.String(event.Details[key]),
+		})
+	}
+
+	return &protocol.Event{
+		Type:    proto.String(string(event.Type)),
+		Time:    proto.Uint64(uint64(event.Time.Unix())),
+		Handle:  proto.String(event.Handle),
+		Details: details,
+	}
+}
+
+func restartMode(restart protocol.RunRequest_Restart) backend.RestartMode {
+	switch restart {
+	case protocol.RunRequest_on_failure:
+		return backend.RestartOnFailure
+	case protocol.RunRequest_always:
+		return backend.RestartAlways
+	default:
+		return backend.RestartNever
+	}
+}
+
+func protocolRestartMode(mode backend.RestartMode) protocol.RunRequest_Restart {
+	switch mode {
+	case backend.RestartOnFailure:
+		return protocol.RunRequest_on_failure
+	case backend.RestartAlways:
+		return protocol.RunRequest_always
+	default:
+		return protocol.RunRequest_never
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"path"
+	"syscall"
 	"time"
 
 	"code.google.com/p/gogoprotobuf/proto"
@@ -17,6 +18,10 @@ import (
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/network"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/network_pool"
+	"github.com/pivotal-cf-experimental/garden/linux_backend/uid_pool"
 	"github.com/pivotal-cf-experimental/garden/message_reader"
 	protocol "github.com/pivotal-cf-experimental/garden/protocol"
 	"github.com/pivotal-cf-experimental/garden/server"
@@ -26,6 +31,7 @@ var _ = Describe("When a client connects", func() {
 	var socketPath string
 
 	var serverBackend *fake_backend.FakeBackend
+	var eventHub *event_hub.Hub
 
 	var serverContainerGraceTime time.Duration
 
@@ -41,12 +47,16 @@ var _ = Describe("When a client connects", func() {
 		socketPath = path.Join(tmpdir, "warden.sock")
 		serverBackend = fake_backend.New()
 		serverContainerGraceTime = 42 * time.Second
+		eventHub = event_hub.New()
 
 		wardenServer = server.New(
 			"unix",
 			socketPath,
 			serverContainerGraceTime,
 			serverBackend,
+			eventHub,
+			nil,
+			nil,
 		)
 
 		err = wardenServer.Start()
@@ -113,6 +123,291 @@ var _ = Describe("When a client connects", func() {
 		}, 1.0)
 	})
 
+	Context("and the client sends requests tagged with request IDs", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		writeMessagesForRequest := func(requestID uint32, message proto.Message) {
+			num, err := protocol.MessagesForRequest(requestID, message).WriteTo(serverConnection)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(num).ToNot(Equal(0))
+		}
+
+		readEnvelope := func() *protocol.Message {
+			message, err := message_reader.ReadEnvelope(responses)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			return message
+		}
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeContainer = container.(*fake_backend.FakeContainer)
+
+			fakeContainer.RunningProcessID = 123
+			exitStatus := uint32(42)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("process out\n"),
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			fakeContainer.StreamDelay = 500 * time.Millisecond
+		})
+
+		It("tags each response with its request's ID", func(done Done) {
+			writeMessagesForRequest(7, &protocol.PingRequest{})
+
+			message := readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(7)))
+
+			err := message_reader.DecodeResponse(message, &protocol.PingResponse{})
+			Expect(err).ToNot(HaveOccurred())
+
+			close(done)
+		}, 1.0)
+
+		It("handles other requests while a process is streaming", func(done Done) {
+			writeMessagesForRequest(1, &protocol.RunRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+				Script: proto.String("/some/script"),
+			})
+
+			var payload protocol.ProcessPayload
+
+			message := readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(1)))
+			Expect(message_reader.DecodeResponse(message, &payload)).ToNot(HaveOccurred())
+			Expect(payload.GetProcessId()).To(Equal(uint32(123)))
+
+			writeMessagesForRequest(2, &protocol.PingRequest{})
+
+			message = readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(2)))
+			Expect(message_reader.DecodeResponse(message, &protocol.PingResponse{})).ToNot(HaveOccurred())
+
+			message = readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(1)))
+			Expect(message_reader.DecodeResponse(message, &payload)).ToNot(HaveOccurred())
+			Expect(payload.GetData()).To(Equal("process out\n"))
+
+			message = readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(1)))
+			Expect(message_reader.DecodeResponse(message, &payload)).ToNot(HaveOccurred())
+			Expect(payload.GetExitStatus()).To(Equal(uint32(42)))
+
+			close(done)
+		}, 3.0)
+
+		It("forwards later messages with a stream's request ID to it", func(done Done) {
+			writeMessagesForRequest(1, &protocol.RunRequest{
+				Handle:      proto.String(fakeContainer.Handle()),
+				Script:      proto.String("/some/script"),
+				StreamStdin: proto.Bool(true),
+			})
+
+			readEnvelope()
+
+			stdin := protocol.ProcessPayload_stdin
+
+			writeMessagesForRequest(1, &protocol.ProcessPayload{
+				ProcessId: proto.Uint32(123),
+				Source:    &stdin,
+				Data:      proto.String("hello\n"),
+				Eof:       proto.Bool(true),
+			})
+
+			Eventually(fakeContainer.StdinWriter.Written).Should(Equal("hello\n"))
+			Eventually(fakeContainer.StdinWriter.IsClosed).Should(BeTrue())
+
+			close(done)
+		}, 2.0)
+
+		It("handles other requests while one is slow to take its input", func(done Done) {
+			fakeContainer.WaitTimesOut = true
+
+			writeMessagesForRequest(1, &protocol.WaitRequest{
+				Handle:    proto.String(fakeContainer.Handle()),
+				ProcessId: proto.Uint32(123),
+			})
+
+			// a wait does not read further input until it is done
+			writeMessagesForRequest(1, &protocol.PingRequest{})
+			writeMessagesForRequest(1, &protocol.PingRequest{})
+
+			writeMessagesForRequest(2, &protocol.PingRequest{})
+
+			message := readEnvelope()
+			Expect(message.GetRequestId()).To(Equal(uint32(2)))
+			Expect(message_reader.DecodeResponse(message, &protocol.PingResponse{})).ToNot(HaveOccurred())
+
+			close(done)
+		}, 0.5)
+
+		It("still handles untagged requests in turn, without tagging them", func(done Done) {
+			writeMessagesForRequest(1, &protocol.RunRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+				Script: proto.String("/some/script"),
+			})
+
+			readEnvelope()
+
+			writeMessages(&protocol.PingRequest{})
+
+			message := readEnvelope()
+			Expect(message.RequestId).To(BeNil())
+			Expect(message_reader.DecodeResponse(message, &protocol.PingResponse{})).ToNot(HaveOccurred())
+
+			close(done)
+		}, 2.0)
+	})
+
+	Context("and the client sends an EventsRequest", func() {
+		oom := backend.ContainerEvent{
+			Type:   backend.EventTypeOutOfMemory,
+			Time:   time.Unix(123, 0),
+			Handle: "some-handle",
+			Details: map[string]string{
+				"b": "2",
+				"a": "1",
+			},
+		}
+
+		started := backend.ContainerEvent{
+			Type:   backend.EventTypeStarted,
+			Time:   time.Unix(456, 0),
+			Handle: "some-other-handle",
+		}
+
+		var stopPublishing chan struct{}
+
+		// the subscription is made some time after the request is sent, so
+		// keep publishing until the client has seen what it is waiting for
+		publishRepeatedly := func(events ...backend.ContainerEvent) {
+			stop := make(chan struct{})
+			stopPublishing = stop
+
+			go func() {
+				for {
+					for _, event := range events {
+						eventHub.Publish(event)
+					}
+
+					select {
+					case <-stop:
+						return
+					case <-time.After(10 * time.Millisecond):
+					}
+				}
+			}()
+		}
+
+		AfterEach(func() {
+			if stopPublishing != nil {
+				close(stopPublishing)
+				stopPublishing = nil
+			}
+		})
+
+		It("streams events as they are published", func(done Done) {
+			writeMessages(&protocol.EventsRequest{})
+
+			publishRepeatedly(oom)
+
+			var event protocol.Event
+			readResponse(&event)
+
+			Expect(event).To(Equal(protocol.Event{
+				Type:   proto.String("out of memory"),
+				Time:   proto.Uint64(123),
+				Handle: proto.String("some-handle"),
+				Details: []*protocol.Event_Detail{
+					{Key: proto.String("a"), Value: proto.String("1")},
+					{Key: proto.String("b"), Value: proto.String("2")},
+				},
+			}))
+
+			readResponse(&event)
+			Expect(event.GetType()).To(Equal("out of memory"))
+
+			close(done)
+		}, 1.0)
+
+		Context("with handles", func() {
+			It("only streams events for those containers", func(done Done) {
+				writeMessages(&protocol.EventsRequest{
+					Handles: []string{"some-other-handle"},
+				})
+
+				publishRepeatedly(oom, started)
+
+				for i := 0; i < 3; i++ {
+					var event protocol.Event
+					readResponse(&event)
+					Expect(event.GetHandle()).To(Equal("some-other-handle"))
+				}
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("with types", func() {
+			It("only streams events of those types", func(done Done) {
+				writeMessages(&protocol.EventsRequest{
+					Types: []string{"started"},
+				})
+
+				publishRepeatedly(oom, started)
+
+				for i := 0; i < 3; i++ {
+					var event protocol.Event
+					readResponse(&event)
+					Expect(event.GetType()).To(Equal("started"))
+				}
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the client sends another request", func() {
+			It("stops streaming and handles it", func(done Done) {
+				writeMessages(&protocol.EventsRequest{})
+
+				publishRepeatedly(oom)
+
+				var event protocol.Event
+				readResponse(&event)
+
+				close(stopPublishing)
+				stopPublishing = nil
+
+				writeMessages(&protocol.EchoRequest{Message: proto.String("hello")})
+
+				for {
+					var echo protocol.EchoResponse
+
+					err := message_reader.ReadMessage(responses, &echo)
+					if _, isEvent := err.(*message_reader.TypeMismatchError); isEvent {
+						// published before the stream ended
+						continue
+					}
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(echo.GetMessage()).To(Equal("hello"))
+
+					break
+				}
+
+				close(done)
+			}, 1.0)
+		})
+	})
+
 	Context("and the client sends a EchoRequest", func() {
 		It("sends an EchoResponse with the same message", func(done Done) {
 			message := proto.String("Hello, world!")
@@ -162,6 +457,12 @@ var _ = Describe("When a client connects", func() {
 						Origin:  &bindMountOrigin,
 					},
 				},
+				Properties: []*protocol.Property{
+					{
+						Key:   proto.String("some-key"),
+						Value: proto.String("some-value"),
+					},
+				},
 			})
 
 			var response protocol.CreateResponse
@@ -183,6 +484,9 @@ var _ = Describe("When a client connects", func() {
 						Origin:  backend.BindMountOriginContainer,
 					},
 				},
+				Properties: backend.Properties{
+					"some-key": "some-value",
+				},
 			}))
 
 			close(done)
@@ -214,6 +518,27 @@ var _ = Describe("When a client connects", func() {
 			}, 5.0)
 		})
 
+		Context("when the container is reaped", func() {
+			It("publishes a reaped event", func(done Done) {
+				subscription := eventHub.Subscribe(nil, nil)
+
+				writeMessages(&protocol.CreateRequest{
+					Handle:    proto.String("some-handle"),
+					GraceTime: proto.Uint32(1),
+				})
+
+				var response protocol.CreateResponse
+				readResponse(&response)
+
+				event := <-subscription.Events()
+				Expect(event.Type).To(Equal(backend.EventTypeReaped))
+				Expect(event.Handle).To(Equal("some-handle"))
+				Expect(event.Details).To(Equal(map[string]string{"grace_time": "1s"}))
+
+				close(done)
+			}, 5.0)
+		})
+
 		Context("when a grace time is not given", func() {
 			It("defaults it to the server's grace time", func(done Done) {
 				writeMessages(&protocol.CreateRequest{
@@ -249,6 +574,52 @@ var _ = Describe("When a client connects", func() {
 				close(done)
 			}, 1.0)
 		})
+
+		Context("when the host is out of capacity", func() {
+			BeforeEach(func() {
+				serverBackend.CreateError = uid_pool.PoolExhaustedError{}
+			})
+
+			It("sends a WardenError response saying so", func(done Done) {
+				writeMessages(&protocol.CreateRequest{
+					Handle: proto.String("some-handle"),
+				})
+
+				var response protocol.CreateResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "UID pool is exhausted",
+					Code:    protocol.ErrorResponse_capacity_exhausted,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the requested network is already taken", func() {
+			BeforeEach(func() {
+				_, ipNet, err := net.ParseCIDR("10.254.0.0/30")
+				Expect(err).ToNot(HaveOccurred())
+
+				serverBackend.CreateError = network_pool.NetworkTakenError{Network: network.New(ipNet)}
+			})
+
+			It("sends a WardenError response saying the request is invalid", func(done Done) {
+				writeMessages(&protocol.CreateRequest{
+					Handle:  proto.String("some-handle"),
+					Network: proto.String("10.254.0.0/30"),
+				})
+
+				var response protocol.CreateResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "network already acquired: 10.254.0.0/30",
+					Code:    protocol.ErrorResponse_invalid_request,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
 	})
 
 	Context("and the client sends a DestroyRequest", func() {
@@ -333,6 +704,44 @@ var _ = Describe("When a client connects", func() {
 			close(done)
 		}, 1.0)
 
+		Context("when properties are given", func() {
+			BeforeEach(func() {
+				_, err := serverBackend.Create(backend.ContainerSpec{
+					Handle: "matching-handle",
+					Properties: backend.Properties{
+						"a": "b",
+						"c": "d",
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = serverBackend.Create(backend.ContainerSpec{
+					Handle: "mismatched-handle",
+					Properties: backend.Properties{
+						"a": "b",
+						"c": "e",
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("only lists containers with all of them", func(done Done) {
+				writeMessages(&protocol.ListRequest{
+					Properties: []*protocol.Property{
+						{Key: proto.String("a"), Value: proto.String("b")},
+						{Key: proto.String("c"), Value: proto.String("d")},
+					},
+				})
+
+				var response protocol.ListResponse
+				readResponse(&response)
+
+				Expect(response.GetHandles()).To(Equal([]string{"matching-handle"}))
+
+				close(done)
+			}, 1.0)
+		})
+
 		Context("when getting the containers fails", func() {
 			BeforeEach(func() {
 				serverBackend.ContainersError = errors.New("oh no!")
@@ -414,6 +823,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -460,13 +870,14 @@ var _ = Describe("When a client connects", func() {
 				Handle:  proto.String(fakeContainer.Handle()),
 				SrcPath: proto.String("/src/path"),
 				DstPath: proto.String("/dst/path"),
+				User:    proto.String("some-service"),
 			})
 
 			var response protocol.CopyInResponse
 			readResponse(&response)
 
 			Expect(fakeContainer.CopiedIn).To(ContainElement(
-				[]string{"/src/path", "/dst/path"},
+				[]string{"/src/path", "/dst/path", "some-service"},
 			))
 
 			close(done)
@@ -494,8 +905,31 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the user is not a plain name or id", func() {
+			It("sends a WardenError response without copying", func(done Done) {
+				writeMessages(&protocol.CopyInRequest{
+					Handle:  proto.String(fakeContainer.Handle()),
+					SrcPath: proto.String("/src/path"),
+					DstPath: proto.String("/dst/path"),
+					User:    proto.String("root --socket /depot/other/run/wshd.sock"),
+				})
+
+				var response protocol.CopyInResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: `invalid user: "root --socket /depot/other/run/wshd.sock"`,
+					Code:    protocol.ErrorResponse_invalid_request,
 				}))
 
+				Expect(fakeContainer.CopiedIn).To(BeEmpty())
+
 				close(done)
 			}, 1.0)
 		})
@@ -537,13 +971,14 @@ var _ = Describe("When a client connects", func() {
 				SrcPath: proto.String("/src/path"),
 				DstPath: proto.String("/dst/path"),
 				Owner:   proto.String("someuser"),
+				User:    proto.String("1000:1000"),
 			})
 
 			var response protocol.CopyOutResponse
 			readResponse(&response)
 
 			Expect(fakeContainer.CopiedOut).To(ContainElement(
-				[]string{"/src/path", "/dst/path", "someuser"},
+				[]string{"/src/path", "/dst/path", "someuser", "1000:1000"},
 			))
 
 			close(done)
@@ -572,8 +1007,31 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the owner is not a plain name or id", func() {
+			It("sends a WardenError response without copying", func(done Done) {
+				writeMessages(&protocol.CopyOutRequest{
+					Handle:  proto.String(fakeContainer.Handle()),
+					SrcPath: proto.String("/src/path"),
+					DstPath: proto.String("/dst/path"),
+					Owner:   proto.String("-R"),
+				})
+
+				var response protocol.CopyOutResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: `invalid user: "-R"`,
+					Code:    protocol.ErrorResponse_invalid_request,
 				}))
 
+				Expect(fakeContainer.CopiedOut).To(BeEmpty())
+
 				close(done)
 			}, 1.0)
 		})
@@ -654,7 +1112,9 @@ var _ = Describe("When a client connects", func() {
 			Expect(response.GetData()).To(BeZero())
 			Expect(response.GetExitStatus()).To(Equal(uint32(42)))
 
-			Expect(fakeContainer.Attached).To(ContainElement(uint32(123)))
+			Expect(fakeContainer.Attached).To(ContainElement(fake_backend.AttachSpec{
+				ProcessID: 123,
+			}))
 
 			close(done)
 		}, 1.0)
@@ -712,23 +1172,136 @@ var _ = Describe("When a client connects", func() {
 			close(done)
 		}, 5.0)
 
-		Context("when the container is not found", func() {
-			BeforeEach(func() {
-				serverBackend.Destroy(fakeContainer.Handle())
+		It("streams output in the bytes field when binary payloads are requested", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStderr,
+					Data:   []byte{0xc3, 0x28},
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			writeMessages(&protocol.AttachRequest{
+				Handle:         proto.String(fakeContainer.Handle()),
+				ProcessId:      proto.Uint32(123),
+				BinaryPayloads: proto.Bool(true),
 			})
 
-			It("sends a WardenError response", func(done Done) {
-				writeMessages(&protocol.AttachRequest{
-					Handle:    proto.String(fakeContainer.Handle()),
-					ProcessId: proto.Uint32(123),
-				})
+			var response protocol.ProcessPayload
 
-				var response protocol.ProcessPayload
+			readResponse(&response)
+			Expect(response.GetSource()).To(Equal(protocol.ProcessPayload_stderr))
+			Expect(response.GetDataBytes()).To(Equal([]byte{0xc3, 0x28}))
+			Expect(response.Data).To(BeNil())
 
-				err := message_reader.ReadMessage(responses, &response)
-				Expect(err).To(Equal(&message_reader.WardenError{
-					Message: "unknown handle: some-handle",
-				}))
+			readResponse(&response)
+			Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+			close(done)
+		}, 1.0)
+
+		It("attaches from the requested offsets and tags payloads with their offsets", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source: backend.ProcessStreamSourceStdout,
+					Data:   []byte("out\n"),
+					Offset: 12,
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			writeMessages(&protocol.AttachRequest{
+				Handle:       proto.String(fakeContainer.Handle()),
+				ProcessId:    proto.Uint32(123),
+				StdoutOffset: proto.Uint64(12),
+				StderrOffset: proto.Uint64(3),
+			})
+
+			var response protocol.ProcessPayload
+
+			readResponse(&response)
+			Expect(response.GetData()).To(Equal("out\n"))
+			Expect(response.Offset).ToNot(BeNil())
+			Expect(response.GetOffset()).To(Equal(uint64(12)))
+
+			readResponse(&response)
+			Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+			stdoutOffset := uint64(12)
+			stderrOffset := uint64(3)
+
+			Expect(fakeContainer.Attached).To(ContainElement(fake_backend.AttachSpec{
+				ProcessID: 123,
+				Offsets: backend.ProcessStreamOffsets{
+					Stdout: &stdoutOffset,
+					Stderr: &stderrOffset,
+				},
+			}))
+
+			close(done)
+		}, 1.0)
+
+		It("writes stdin payloads to the attached process", func(done Done) {
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			fakeContainer.StreamDelay = 500 * time.Millisecond
+
+			writeMessages(&protocol.AttachRequest{
+				Handle:    proto.String(fakeContainer.Handle()),
+				ProcessId: proto.Uint32(123),
+			})
+
+			stdin := protocol.ProcessPayload_stdin
+
+			writeMessages(&protocol.ProcessPayload{
+				ProcessId: proto.Uint32(123),
+				Source:    &stdin,
+				Data:      proto.String("hello\n"),
+				Eof:       proto.Bool(true),
+			})
+
+			var response protocol.ProcessPayload
+			readResponse(&response)
+			Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+			Expect(fakeContainer.StdinWriter.Written()).To(Equal("hello\n"))
+			Expect(fakeContainer.StdinWriter.IsClosed()).To(BeTrue())
+
+			close(done)
+		}, 2.0)
+
+		Context("when the container is not found", func() {
+			BeforeEach(func() {
+				serverBackend.Destroy(fakeContainer.Handle())
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.AttachRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				var response protocol.ProcessPayload
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
 
 				close(done)
 			}, 1.0)
@@ -861,6 +1434,232 @@ var _ = Describe("When a client connects", func() {
 			close(done)
 		}, 1.0)
 
+		Context("when binary payloads are requested", func() {
+			It("streams output byte-for-byte in the bytes field", func(done Done) {
+				fakeContainer.RunningProcessID = 123
+				exitStatus := uint32(0)
+
+				fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+					{
+						Source: backend.ProcessStreamSourceStdout,
+						Data:   []byte{0xff, 0x00, 0xfe},
+					},
+					{
+						ExitStatus: &exitStatus,
+					},
+				}
+
+				writeMessages(&protocol.RunRequest{
+					Handle:         proto.String(fakeContainer.Handle()),
+					Script:         proto.String("/some/script"),
+					BinaryPayloads: proto.Bool(true),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+
+				readResponse(&response)
+				Expect(response.GetSource()).To(Equal(protocol.ProcessPayload_stdout))
+				Expect(response.GetDataBytes()).To(Equal([]byte{0xff, 0x00, 0xfe}))
+				Expect(response.Data).To(BeNil())
+
+				readResponse(&response)
+				Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+				close(done)
+			}, 1.0)
+		})
+
+		It("runs the given executable with its arguments, environment, and working directory", func(done Done) {
+			fakeContainer.RunningProcessID = 123
+			exitStatus := uint32(0)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			writeMessages(&protocol.RunRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+				Path:   proto.String("/some/executable"),
+				Args:   []string{"arg1", "arg 2"},
+				Env: []*protocol.EnvironmentVariable{
+					{Key: proto.String("FOO"), Value: proto.String("bar")},
+					{Key: proto.String("BAZ"), Value: proto.String("a=b")},
+				},
+				Dir:  proto.String("/some/dir"),
+				User: proto.String("some-service"),
+			})
+
+			var response protocol.ProcessPayload
+			readResponse(&response)
+			readResponse(&response)
+			Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+			Expect(fakeContainer.RunningProcesses).To(ContainElement(
+				backend.ProcessSpec{
+					Path: "/some/executable",
+					Args: []string{"arg1", "arg 2"},
+					Env:  []string{"FOO=bar", "BAZ=a=b"},
+					Dir:  "/some/dir",
+					User: "some-service",
+				},
+			))
+
+			close(done)
+		}, 1.0)
+
+		Context("while the process is streaming", func() {
+			BeforeEach(func() {
+				fakeContainer.RunningProcessID = 123
+				exitStatus := uint32(0)
+
+				fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+					{
+						Source: backend.ProcessStreamSourceStdout,
+						Data:   []byte("process out\n"),
+					},
+					{
+						ExitStatus: &exitStatus,
+					},
+				}
+
+				fakeContainer.StreamDelay = 500 * time.Millisecond
+			})
+
+			It("writes stdin payloads to the process and closes it on eof", func(done Done) {
+				writeMessages(&protocol.RunRequest{
+					Handle:      proto.String(fakeContainer.Handle()),
+					Script:      proto.String("/some/script"),
+					StreamStdin: proto.Bool(true),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+				Expect(response.GetProcessId()).To(Equal(uint32(123)))
+
+				stdin := protocol.ProcessPayload_stdin
+
+				writeMessages(&protocol.ProcessPayload{
+					ProcessId: proto.Uint32(123),
+					Source:    &stdin,
+					Data:      proto.String("hello\n"),
+				})
+
+				writeMessages(&protocol.ProcessPayload{
+					ProcessId: proto.Uint32(123),
+					Source:    &stdin,
+					Eof:       proto.Bool(true),
+				})
+
+				Eventually(fakeContainer.StdinWriter.Written).Should(Equal("hello\n"))
+				Eventually(fakeContainer.StdinWriter.IsClosed).Should(BeTrue())
+
+				Expect(fakeContainer.RunningProcesses).To(ContainElement(
+					backend.ProcessSpec{
+						Script:      "/some/script",
+						StreamStdin: true,
+					},
+				))
+
+				close(done)
+			}, 2.0)
+
+			It("resizes the process's tty when sent a window size", func(done Done) {
+				writeMessages(&protocol.RunRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+					Script: proto.String("/some/script"),
+					Tty: &protocol.TTY{
+						WindowSize: &protocol.TTY_WindowSize{
+							Columns: proto.Uint32(80),
+							Rows:    proto.Uint32(24),
+						},
+					},
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+
+				writeMessages(&protocol.ProcessPayload{
+					ProcessId: proto.Uint32(123),
+					Tty: &protocol.TTY{
+						WindowSize: &protocol.TTY_WindowSize{
+							Columns: proto.Uint32(100),
+							Rows:    proto.Uint32(50),
+						},
+					},
+				})
+
+				Eventually(fakeContainer.WindowSizes).Should(ContainElement(
+					fake_backend.WindowSizeSpec{
+						ProcessID:  123,
+						WindowSize: backend.WindowSize{Columns: 100, Rows: 50},
+					},
+				))
+
+				Expect(fakeContainer.StdinWriter.Written()).To(BeEmpty())
+
+				Expect(fakeContainer.RunningProcesses).To(ContainElement(
+					backend.ProcessSpec{
+						Script: "/some/script",
+						TTY: &backend.TTYSpec{
+							WindowSize: &backend.WindowSize{
+								Columns: 80,
+								Rows:    24,
+							},
+						},
+					},
+				))
+
+				close(done)
+			}, 2.0)
+
+			It("writes binary stdin payloads to the process", func(done Done) {
+				writeMessages(&protocol.RunRequest{
+					Handle:      proto.String(fakeContainer.Handle()),
+					Script:      proto.String("/some/script"),
+					StreamStdin: proto.Bool(true),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+
+				writeMessages(&protocol.ProcessPayload{
+					ProcessId: proto.Uint32(123),
+					DataBytes: []byte{0xff, 0x00, 0xfe},
+				})
+
+				Eventually(fakeContainer.StdinWriter.Written).Should(Equal(string([]byte{0xff, 0x00, 0xfe})))
+
+				close(done)
+			}, 2.0)
+
+			It("handles requests sent while the process is streaming once it completes", func(done Done) {
+				writeMessages(&protocol.RunRequest{
+					Handle:      proto.String(fakeContainer.Handle()),
+					Script:      proto.String("/some/script"),
+					StreamStdin: proto.Bool(true),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+
+				writeMessages(&protocol.PingRequest{})
+
+				readResponse(&response)
+				Expect(response.GetData()).To(Equal("process out\n"))
+
+				readResponse(&response)
+				Expect(response.GetExitStatus()).To(Equal(uint32(0)))
+
+				var pingResponse protocol.PingResponse
+				readResponse(&pingResponse)
+
+				close(done)
+			}, 2.0)
+		})
+
 		Context("when the container is not found", func() {
 			BeforeEach(func() {
 				serverBackend.Destroy(fakeContainer.Handle())
@@ -877,12 +1676,143 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
 			}, 1.0)
 		})
 
+		Context("with a timeout", func() {
+			It("runs the process with the timeout and reports whether it timed out", func(done Done) {
+				fakeContainer.RunningProcessID = 123
+				exitStatus := uint32(143)
+
+				fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+					{
+						ExitStatus: &exitStatus,
+						TimedOut:   true,
+					},
+				}
+
+				writeMessages(&protocol.RunRequest{
+					Handle:  proto.String(fakeContainer.Handle()),
+					Script:  proto.String("/some/script"),
+					Timeout: proto.Uint32(30),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+				Expect(response.GetProcessId()).To(Equal(uint32(123)))
+
+				readResponse(&response)
+				Expect(response.GetExitStatus()).To(Equal(uint32(143)))
+				Expect(response.GetTimedOut()).To(BeTrue())
+
+				Expect(fakeContainer.RunningProcesses).To(ContainElement(
+					backend.ProcessSpec{
+						Script:  "/some/script",
+						Timeout: 30 * time.Second,
+					},
+				))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the run is detached", func() {
+			It("responds with the process ID without streaming its output", func(done Done) {
+				fakeContainer.RunningProcessID = 123
+				exitStatus := uint32(42)
+
+				fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+					{
+						Source: backend.ProcessStreamSourceStdout,
+						Data:   []byte("process out\n"),
+					},
+					{
+						ExitStatus: &exitStatus,
+					},
+				}
+
+				writeMessages(&protocol.RunRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+					Script: proto.String("/some/script"),
+					Detach: proto.Bool(true),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+				Expect(response.GetProcessId()).To(Equal(uint32(123)))
+				Expect(response.Source).To(BeNil())
+				Expect(response.ExitStatus).To(BeNil())
+
+				writeMessages(&protocol.PingRequest{})
+
+				var pingResponse protocol.PingResponse
+				readResponse(&pingResponse)
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when the run has a restart policy", func() {
+			It("supervises the process and responds with its ID", func(done Done) {
+				fakeContainer.RunningProcessID = 123
+
+				writeMessages(&protocol.RunRequest{
+					Handle:         proto.String(fakeContainer.Handle()),
+					Script:         proto.String("/some/script"),
+					Restart:        protocol.RunRequest_on_failure.Enum(),
+					MaxRestarts:    proto.Uint32(3),
+					RestartBackoff: proto.Uint32(5),
+				})
+
+				var response protocol.ProcessPayload
+				readResponse(&response)
+				Expect(response.GetProcessId()).To(Equal(uint32(123)))
+				Expect(response.ExitStatus).To(BeNil())
+
+				Expect(fakeContainer.SupervisedProcesses).To(ContainElement(
+					fake_backend.SuperviseSpec{
+						Spec: backend.ProcessSpec{
+							Script: "/some/script",
+						},
+						Policy: backend.RestartPolicy{
+							Mode:        backend.RestartOnFailure,
+							MaxRestarts: 3,
+							Backoff:     5 * time.Second,
+						},
+					},
+				))
+
+				Expect(fakeContainer.RunningProcesses).To(BeEmpty())
+
+				close(done)
+			}, 1.0)
+
+			Context("when supervising fails", func() {
+				BeforeEach(func() {
+					fakeContainer.SuperviseError = errors.New("oh no!")
+				})
+
+				It("sends a WardenError response", func(done Done) {
+					writeMessages(&protocol.RunRequest{
+						Handle:  proto.String(fakeContainer.Handle()),
+						Script:  proto.String("/some/script"),
+						Restart: protocol.RunRequest_always.Enum(),
+					})
+
+					var response protocol.ProcessPayload
+
+					err := message_reader.ReadMessage(responses, &response)
+					Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+					close(done)
+				}, 1.0)
+			})
+		})
+
 		Context("when running fails", func() {
 			BeforeEach(func() {
 				fakeContainer.RunError = errors.New("oh no!")
@@ -909,53 +1839,337 @@ var _ = Describe("When a client connects", func() {
 				GraceTime: proto.Uint32(1),
 			})
 
-			var response protocol.CreateResponse
+			var response protocol.CreateResponse
+			readResponse(&response)
+
+			fakeContainer := serverBackend.CreatedContainers["some-handle"]
+
+			exitStatus := uint32(42)
+
+			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+				{
+					Source:     backend.ProcessStreamSourceStdout,
+					Data:       []byte("process out\n"),
+					ExitStatus: nil,
+				},
+				{
+					Source:     backend.ProcessStreamSourceStderr,
+					Data:       []byte("process err\n"),
+					ExitStatus: nil,
+				},
+				{
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			fakeContainer.StreamDelay = 1 * time.Second
+
+			writeMessages(&protocol.RunRequest{
+				Handle: proto.String("some-handle"),
+				Script: proto.String("/some/script"),
+			})
+
+			var payloadResponse protocol.ProcessPayload
+			readResponse(&payloadResponse) // read process id
+			readResponse(&payloadResponse) // read stdout
+			readResponse(&payloadResponse) // read stderr
+			readResponse(&payloadResponse) // read exit status
+
+			before := time.Now()
+
+			Eventually(func() error {
+				_, err := serverBackend.Lookup("some-handle")
+				return err
+			}, 2.0).Should(HaveOccurred())
+
+			Expect(time.Since(before)).To(BeNumerically(">", 1*time.Second))
+
+			close(done)
+		}, 5.0)
+	})
+
+	Context("and the client sends a SignalRequest", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeContainer = container.(*fake_backend.FakeContainer)
+		})
+
+		It("sends the signal to the process", func(done Done) {
+			writeMessages(&protocol.SignalRequest{
+				Handle:    proto.String(fakeContainer.Handle()),
+				ProcessId: proto.Uint32(123),
+				Signal:    protocol.SignalRequest_kill.Enum(),
+			})
+
+			var response protocol.SignalResponse
+			readResponse(&response)
+
+			Expect(fakeContainer.Signalled).To(ContainElement(
+				fake_backend.SignalSpec{
+					ProcessID: 123,
+					Signal:    syscall.SIGKILL,
+				},
+			))
+
+			close(done)
+		}, 1.0)
+
+		It("sends TERM by default", func(done Done) {
+			writeMessages(&protocol.SignalRequest{
+				Handle:    proto.String(fakeContainer.Handle()),
+				ProcessId: proto.Uint32(123),
+			})
+
+			var response protocol.SignalResponse
+			readResponse(&response)
+
+			Expect(fakeContainer.Signalled).To(ContainElement(
+				fake_backend.SignalSpec{
+					ProcessID: 123,
+					Signal:    syscall.SIGTERM,
+				},
+			))
+
+			close(done)
+		}, 1.0)
+
+		Context("when the container is not found", func() {
+			BeforeEach(func() {
+				serverBackend.Destroy(fakeContainer.Handle())
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.SignalRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				var response protocol.SignalResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when signalling fails", func() {
+			BeforeEach(func() {
+				fakeContainer.SignalError = errors.New("oh no!")
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.SignalRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				var response protocol.SignalResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+				close(done)
+			}, 1.0)
+		})
+	})
+
+	Context("and the client sends a WaitRequest", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeContainer = container.(*fake_backend.FakeContainer)
+		})
+
+		It("waits for the process and responds with its exit status", func(done Done) {
+			fakeContainer.WaitExitStatus = 42
+
+			writeMessages(&protocol.WaitRequest{
+				Handle:    proto.String(fakeContainer.Handle()),
+				ProcessId: proto.Uint32(123),
+				Timeout:   proto.Uint32(10),
+			})
+
+			var response protocol.WaitResponse
+			readResponse(&response)
+
+			Expect(response.GetExitStatus()).To(Equal(uint32(42)))
+
+			Expect(fakeContainer.Waited()).To(ContainElement(
+				fake_backend.WaitSpec{
+					ProcessID: 123,
+					Timeout:   time.Second,
+				},
+			))
+
+			close(done)
+		}, 1.0)
+
+		Context("when the process does not exit", func() {
+			BeforeEach(func() {
+				fakeContainer.WaitTimesOut = true
+			})
+
+			It("waits a second at a time until the timeout, then times out", func(done Done) {
+				writeMessages(&protocol.WaitRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+					Timeout:   proto.Uint32(2),
+				})
+
+				var response protocol.WaitResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*message_reader.WardenError).Code).To(Equal(protocol.ErrorResponse_timed_out))
+
+				Expect(fakeContainer.Waited()).To(HaveLen(2))
+				Expect(fakeContainer.Waited()[0]).To(Equal(fake_backend.WaitSpec{ProcessID: 123, Timeout: time.Second}))
+
+				close(done)
+			}, 3.0)
+
+			It("stops waiting once the client disconnects", func() {
+				writeMessages(&protocol.WaitRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				Eventually(fakeContainer.Waited, 1.0, 0.01).Should(HaveLen(1))
+
+				serverConnection.Close()
+
+				// the server only notices between waits, each of which lasts a second
+				Consistently(fakeContainer.Waited, 1.2, 0.05).Should(HaveLen(1))
+			})
+		})
+
+		Context("when the container is not found", func() {
+			BeforeEach(func() {
+				serverBackend.Destroy(fakeContainer.Handle())
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.WaitRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				var response protocol.WaitResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when waiting fails", func() {
+			BeforeEach(func() {
+				fakeContainer.WaitError = errors.New("oh no!")
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.WaitRequest{
+					Handle:    proto.String(fakeContainer.Handle()),
+					ProcessId: proto.Uint32(123),
+				})
+
+				var response protocol.WaitResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+				close(done)
+			}, 1.0)
+		})
+	})
+
+	Context("and the client sends a ListProcessesRequest", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeContainer = container.(*fake_backend.FakeContainer)
+		})
+
+		It("responds with the running and finished processes", func(done Done) {
+			exitStatus := uint32(42)
+
+			fakeContainer.ReportedProcesses = []backend.ProcessInfo{
+				{
+					ID:        1,
+					Command:   "/some/bin some args",
+					StartedAt: time.Unix(100, 0),
+				},
+				{
+					ID:         2,
+					Command:    "some script",
+					StartedAt:  time.Unix(200, 0),
+					FinishedAt: time.Unix(300, 0),
+					ExitStatus: &exitStatus,
+				},
+			}
+
+			writeMessages(&protocol.ListProcessesRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+			})
+
+			var response protocol.ListProcessesResponse
 			readResponse(&response)
 
-			fakeContainer := serverBackend.CreatedContainers["some-handle"]
-
-			exitStatus := uint32(42)
-
-			fakeContainer.StreamedProcessChunks = []backend.ProcessStream{
+			Expect(response.GetProcesses()).To(Equal([]*protocol.ListProcessesResponse_Process{
 				{
-					Source:     backend.ProcessStreamSourceStdout,
-					Data:       []byte("process out\n"),
-					ExitStatus: nil,
-				},
-				{
-					Source:     backend.ProcessStreamSourceStderr,
-					Data:       []byte("process err\n"),
-					ExitStatus: nil,
+					ProcessId: proto.Uint32(1),
+					Command:   proto.String("/some/bin some args"),
+					StartedAt: proto.Uint64(100),
 				},
 				{
-					ExitStatus: &exitStatus,
+					ProcessId:  proto.Uint32(2),
+					Command:    proto.String("some script"),
+					StartedAt:  proto.Uint64(200),
+					FinishedAt: proto.Uint64(300),
+					ExitStatus: proto.Uint32(42),
+					TimedOut:   proto.Bool(false),
 				},
-			}
+			}))
 
-			fakeContainer.StreamDelay = 1 * time.Second
+			close(done)
+		}, 1.0)
 
-			writeMessages(&protocol.RunRequest{
-				Handle: proto.String("some-handle"),
-				Script: proto.String("/some/script"),
+		Context("when listing fails", func() {
+			BeforeEach(func() {
+				fakeContainer.ProcessesError = errors.New("oh no!")
 			})
 
-			var payloadResponse protocol.ProcessPayload
-			readResponse(&payloadResponse) // read process id
-			readResponse(&payloadResponse) // read stdout
-			readResponse(&payloadResponse) // read stderr
-			readResponse(&payloadResponse) // read exit status
-
-			before := time.Now()
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.ListProcessesRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+				})
 
-			Eventually(func() error {
-				_, err := serverBackend.Lookup("some-handle")
-				return err
-			}, 2.0).Should(HaveOccurred())
+				var response protocol.ListProcessesResponse
 
-			Expect(time.Since(before)).To(BeNumerically(">", 1*time.Second))
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
 
-			close(done)
-		}, 5.0)
+				close(done)
+			}, 1.0)
+		})
 	})
 
 	Context("and the client sends a LimitBandwidthRequest", func() {
@@ -1020,6 +2234,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1139,6 +2354,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1461,6 +2677,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1584,6 +2801,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1681,6 +2899,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1757,6 +2976,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1784,6 +3004,134 @@ var _ = Describe("When a client connects", func() {
 		})
 	})
 
+	Context("and the client sends a HealthCheckRequest", func() {
+		var fakeContainer *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeContainer = container.(*fake_backend.FakeContainer)
+		})
+
+		It("sets the container's health check", func(done Done) {
+			writeMessages(&protocol.HealthCheckRequest{
+				Handle:     proto.String(fakeContainer.Handle()),
+				Script:     proto.String("/some/check"),
+				Privileged: proto.Bool(true),
+				User:       proto.String("bob"),
+				Env: []*protocol.EnvironmentVariable{
+					{Key: proto.String("PORT"), Value: proto.String("8080")},
+				},
+				Interval:         proto.Uint32(10),
+				Timeout:          proto.Uint32(2),
+				FailureThreshold: proto.Uint32(3),
+			})
+
+			var response protocol.HealthCheckResponse
+			readResponse(&response)
+
+			Expect(fakeContainer.HealthCheck).To(Equal(&backend.HealthCheckSpec{
+				Process: backend.ProcessSpec{
+					Script:     "/some/check",
+					Privileged: true,
+					User:       "bob",
+					Env:        []string{"PORT=8080"},
+				},
+				Interval:         10 * time.Second,
+				Timeout:          2 * time.Second,
+				FailureThreshold: 3,
+			}))
+
+			close(done)
+		}, 1.0)
+
+		Context("when removing the check", func() {
+			BeforeEach(func() {
+				fakeContainer.HealthCheck = &backend.HealthCheckSpec{
+					Process: backend.ProcessSpec{Script: "/some/check"},
+				}
+			})
+
+			It("removes the container's health check", func(done Done) {
+				writeMessages(&protocol.HealthCheckRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+					Remove: proto.Bool(true),
+				})
+
+				var response protocol.HealthCheckResponse
+				readResponse(&response)
+
+				Expect(fakeContainer.HealthCheck).To(BeNil())
+
+				close(done)
+			}, 1.0)
+
+			Context("and removing it fails", func() {
+				BeforeEach(func() {
+					fakeContainer.RemoveHealthCheckError = errors.New("oh no!")
+				})
+
+				It("sends a WardenError response", func(done Done) {
+					writeMessages(&protocol.HealthCheckRequest{
+						Handle: proto.String(fakeContainer.Handle()),
+						Remove: proto.Bool(true),
+					})
+
+					var response protocol.HealthCheckResponse
+					err := message_reader.ReadMessage(responses, &response)
+					Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+					close(done)
+				}, 1.0)
+			})
+		})
+
+		itResetsGraceTimeWhenHandling(&protocol.HealthCheckRequest{
+			Handle: proto.String("some-handle"),
+			Script: proto.String("/some/check"),
+		})
+
+		Context("when the container is not found", func() {
+			BeforeEach(func() {
+				serverBackend.Destroy(fakeContainer.Handle())
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.HealthCheckRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+				})
+
+				var response protocol.HealthCheckResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when setting the health check fails", func() {
+			BeforeEach(func() {
+				fakeContainer.SetHealthCheckError = errors.New("oh no!")
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.HealthCheckRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+				})
+
+				var response protocol.HealthCheckResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+				close(done)
+			}, 1.0)
+		})
+	})
+
 	Context("and the client sends a InfoRequest", func() {
 		var fakeContainer *fake_backend.FakeContainer
 
@@ -1907,6 +3255,136 @@ var _ = Describe("When a client connects", func() {
 			close(done)
 		}, 1.0)
 
+		It("reports the container's supervised processes", func(done Done) {
+			exitStatus := uint32(1)
+
+			fakeContainer.ReportedInfo = backend.ContainerInfo{
+				SupervisedProcesses: []backend.SupervisedProcessInfo{
+					{
+						ID: 1,
+						Policy: backend.RestartPolicy{
+							Mode:        backend.RestartAlways,
+							MaxRestarts: 5,
+						},
+						ProcessID:      3,
+						Running:        true,
+						Restarts:       2,
+						LastExitStatus: &exitStatus,
+					},
+					{
+						ID: 4,
+						Policy: backend.RestartPolicy{
+							Mode: backend.RestartOnFailure,
+						},
+						ProcessID: 4,
+						Running:   true,
+					},
+				},
+			}
+
+			writeMessages(&protocol.InfoRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+			})
+
+			var response protocol.InfoResponse
+			readResponse(&response)
+
+			Expect(response.GetSupervisedProcesses()).To(Equal([]*protocol.InfoResponse_SupervisedProcess{
+				{
+					Id:             proto.Uint32(1),
+					ProcessId:      proto.Uint32(3),
+					Running:        proto.Bool(true),
+					Restarts:       proto.Uint32(2),
+					LastExitStatus: proto.Uint32(1),
+					Restart:        protocol.RunRequest_always.Enum(),
+					MaxRestarts:    proto.Uint32(5),
+				},
+				{
+					Id:          proto.Uint32(4),
+					ProcessId:   proto.Uint32(4),
+					Running:     proto.Bool(true),
+					Restarts:    proto.Uint32(0),
+					Restart:     protocol.RunRequest_on_failure.Enum(),
+					MaxRestarts: proto.Uint32(0),
+				},
+			}))
+
+			close(done)
+		}, 1.0)
+
+		It("reports the container's properties, ordered by key", func(done Done) {
+			fakeContainer.ReportedInfo = backend.ContainerInfo{
+				Properties: backend.Properties{
+					"some-key":    "some-value",
+					"another-key": "another-value",
+				},
+			}
+
+			writeMessages(&protocol.InfoRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+			})
+
+			var response protocol.InfoResponse
+			readResponse(&response)
+
+			Expect(response.GetProperties()).To(Equal([]*protocol.Property{
+				{Key: proto.String("another-key"), Value: proto.String("another-value")},
+				{Key: proto.String("some-key"), Value: proto.String("some-value")},
+			}))
+
+			close(done)
+		}, 1.0)
+
+		It("reports the container's health check", func(done Done) {
+			lastCheckedAt := time.Unix(123, 0)
+
+			fakeContainer.ReportedInfo = backend.ContainerInfo{
+				HealthCheck: &backend.HealthCheckInfo{
+					Spec: backend.HealthCheckSpec{
+						Interval:         30 * time.Second,
+						Timeout:          5 * time.Second,
+						FailureThreshold: 3,
+					},
+					Status:              backend.HealthStatusUnhealthy,
+					ConsecutiveFailures: 4,
+					LastCheckedAt:       lastCheckedAt,
+				},
+			}
+
+			writeMessages(&protocol.InfoRequest{
+				Handle: proto.String(fakeContainer.Handle()),
+			})
+
+			var response protocol.InfoResponse
+			readResponse(&response)
+
+			Expect(response.GetHealthCheck()).To(Equal(&protocol.InfoResponse_HealthCheck{
+				Status:              proto.String("unhealthy"),
+				ConsecutiveFailures: proto.Uint32(4),
+				LastCheckedAt:       proto.Uint64(123),
+				Interval:            proto.Uint32(30),
+				Timeout:             proto.Uint32(5),
+				FailureThreshold:    proto.Uint32(3),
+			}))
+
+			close(done)
+		}, 1.0)
+
+		Context("when the container has no health check", func() {
+			It("does not report one", func(done Done) {
+				writeMessages(&protocol.InfoRequest{
+					Handle: proto.String(fakeContainer.Handle()),
+				})
+
+				var response protocol.InfoResponse
+				readResponse(&response)
+
+				Expect(response.HealthCheck).To(BeNil())
+
+				close(done)
+			}, 1.0)
+		})
+
 		itResetsGraceTimeWhenHandling(&protocol.InfoRequest{
 			Handle: proto.String("some-handle"),
 		})
@@ -1925,6 +3403,7 @@ var _ = Describe("When a client connects", func() {
 				err := message_reader.ReadMessage(responses, &response)
 				Expect(err).To(Equal(&message_reader.WardenError{
 					Message: "unknown handle: some-handle",
+					Code:    protocol.ErrorResponse_unknown_handle,
 				}))
 
 				close(done)
@@ -1949,4 +3428,106 @@ var _ = Describe("When a client connects", func() {
 			}, 1.0)
 		})
 	})
+
+	Context("and the client sends a BulkInfoRequest", func() {
+		var container1 *fake_backend.FakeContainer
+		var container2 *fake_backend.FakeContainer
+
+		BeforeEach(func() {
+			container, err := serverBackend.Create(backend.ContainerSpec{Handle: "handle-1"})
+			Expect(err).ToNot(HaveOccurred())
+
+			container1 = container.(*fake_backend.FakeContainer)
+			container1.ReportedInfo = backend.ContainerInfo{State: "active"}
+
+			container, err = serverBackend.Create(backend.ContainerSpec{Handle: "handle-2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			container2 = container.(*fake_backend.FakeContainer)
+			container2.ReportedInfo = backend.ContainerInfo{State: "stopped"}
+		})
+
+		It("reports information about each of the given containers", func(done Done) {
+			writeMessages(&protocol.BulkInfoRequest{
+				Handles: []string{"handle-2", "handle-1"},
+			})
+
+			var response protocol.BulkInfoResponse
+			readResponse(&response)
+
+			entries := response.GetEntries()
+			Expect(entries).To(HaveLen(2))
+
+			Expect(entries[0].GetHandle()).To(Equal("handle-2"))
+			Expect(entries[0].GetInfo().GetState()).To(Equal("stopped"))
+			Expect(entries[0].GetError()).To(BeNil())
+
+			Expect(entries[1].GetHandle()).To(Equal("handle-1"))
+			Expect(entries[1].GetInfo().GetState()).To(Equal("active"))
+			Expect(entries[1].GetError()).To(BeNil())
+
+			close(done)
+		}, 1.0)
+
+		Context("when no handles are given", func() {
+			It("reports information about every container", func(done Done) {
+				writeMessages(&protocol.BulkInfoRequest{})
+
+				var response protocol.BulkInfoResponse
+				readResponse(&response)
+
+				entries := response.GetEntries()
+				Expect(entries).To(HaveLen(2))
+
+				Expect(entries[0].GetHandle()).To(Equal("handle-1"))
+				Expect(entries[1].GetHandle()).To(Equal("handle-2"))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when some of the containers' info cannot be had", func() {
+			BeforeEach(func() {
+				container2.InfoError = errors.New("oh no!")
+			})
+
+			It("reports the errors in their place", func(done Done) {
+				writeMessages(&protocol.BulkInfoRequest{
+					Handles: []string{"handle-1", "handle-2", "bogus-handle"},
+				})
+
+				var response protocol.BulkInfoResponse
+				readResponse(&response)
+
+				entries := response.GetEntries()
+				Expect(entries).To(HaveLen(3))
+
+				Expect(entries[0].GetInfo().GetState()).To(Equal("active"))
+
+				Expect(entries[1].GetInfo()).To(BeNil())
+				Expect(entries[1].GetError().GetMessage()).To(Equal("oh no!"))
+
+				Expect(entries[2].GetInfo()).To(BeNil())
+				Expect(entries[2].GetError().GetMessage()).To(Equal("unknown handle: bogus-handle"))
+
+				close(done)
+			}, 1.0)
+		})
+
+		Context("when getting the containers fails", func() {
+			BeforeEach(func() {
+				serverBackend.ContainersError = errors.New("oh no!")
+			})
+
+			It("sends a WardenError response", func(done Done) {
+				writeMessages(&protocol.BulkInfoRequest{})
+
+				var response protocol.BulkInfoResponse
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(Equal(&message_reader.WardenError{Message: "oh no!"}))
+
+				close(done)
+			}, 1.0)
+		})
+	})
 })
@@ -2,30 +2,43 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	"code.google.com/p/gogoprotobuf/proto"
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/drain"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/message_reader"
 	protocol "github.com/pivotal-cf-experimental/garden/protocol"
 	"github.com/pivotal-cf-experimental/garden/server/bomberman"
 )
 
+// waitPollInterval is how long a Wait waits on the backend at a time
+// before checking that it is still wanted.
+var waitPollInterval = time.Second
+
 type WardenServer struct {
 	listenNetwork string
 	listenAddr    string
 
 	containerGraceTime time.Duration
 	backend            backend.Backend
+	eventHub           *event_hub.Hub
+
+	tlsConfig  *tls.Config
+	authorizer *Authorizer
 
 	listener     net.Listener
+	httpListener net.Listener
 	openRequests *drain.Drain
 
 	setStopping chan bool
@@ -42,10 +55,213 @@ func (e UnhandledRequestError) Error() string {
 	return fmt.Sprintf("unhandled request type: %T", e.Request)
 }
 
+type UnknownSignalError struct {
+	Signal protocol.SignalRequest_Signal
+}
+
+func (e UnknownSignalError) Error() string {
+	return fmt.Sprintf("unknown signal: %d", e.Signal)
+}
+
+// errorResponse reports the error to the client, along with its code.
+func errorResponse(err error) *protocol.ErrorResponse {
+	return &protocol.ErrorResponse{
+		Message: proto.String(err.Error()),
+		Code:    errorCode(err).Enum(),
+	}
+}
+
+func errorCode(err error) protocol.ErrorResponse_Code {
+	switch err.(type) {
+	case UnauthorizedError:
+		// indistinguishable from a handle that does not exist
+		return protocol.ErrorResponse_unknown_handle
+	case PolicyViolationError:
+		return protocol.ErrorResponse_not_permitted
+	case BadRequestError, UnknownSignalError, UnhandledRequestError:
+		return protocol.ErrorResponse_invalid_request
+	}
+
+	coded, ok := err.(backend.CodedError)
+	if !ok {
+		return protocol.ErrorResponse_unknown
+	}
+
+	switch coded.Code() {
+	case backend.ErrorCodeUnknownHandle:
+		return protocol.ErrorResponse_unknown_handle
+	case backend.ErrorCodeUnknownProcess:
+		return protocol.ErrorResponse_unknown_process
+	case backend.ErrorCodeCapacityExhausted:
+		return protocol.ErrorResponse_capacity_exhausted
+	case backend.ErrorCodeInvalidRequest:
+		return protocol.ErrorResponse_invalid_request
+	case backend.ErrorCodeTimedOut:
+		return protocol.ErrorResponse_timed_out
+	default:
+		return protocol.ErrorResponse_unknown
+	}
+}
+
+// multiplexedRequests tracks a connection's multiplexed requests until
+// they have been handled.
+type multiplexedRequests struct {
+	inFlight map[uint32]*multiplexedRequest
+	mutex    *sync.Mutex
+}
+
+type multiplexedRequest struct {
+	input chan proto.Message
+	done  chan struct{}
+
+	// queued takes input as it is read, to be passed on to input when the
+	// request is ready for it, so that a request slow to take its input
+	// does not hold up the rest of the connection
+	queued chan proto.Message
+}
+
+// forward passes queued input on to the request in order, closing its input
+// once the queue is closed and drained, or once the request is done.
+func (r *multiplexedRequest) forward() {
+	defer close(r.input)
+
+	pending := []proto.Message{}
+	queued := r.queued
+
+	for queued != nil || len(pending) > 0 {
+		var input chan proto.Message
+		var next proto.Message
+
+		if len(pending) > 0 {
+			input = r.input
+			next = pending[0]
+		}
+
+		select {
+		case message, ok := <-queued:
+			if !ok {
+				queued = nil
+				continue
+			}
+
+			pending = append(pending, message)
+
+		case input <- next:
+			pending = pending[1:]
+
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (m *multiplexedRequests) lookup(requestID uint32) (*multiplexedRequest, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	request, found := m.inFlight[requestID]
+	return request, found
+}
+
+func (m *multiplexedRequests) add(requestID uint32) *multiplexedRequest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	request := &multiplexedRequest{
+		input: make(chan proto.Message),
+		done:  make(chan struct{}),
+
+		queued: make(chan proto.Message),
+	}
+
+	go request.forward()
+
+	m.inFlight[requestID] = request
+
+	return request
+}
+
+func (m *multiplexedRequests) remove(requestID uint32, request *multiplexedRequest) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	close(request.done)
+
+	// the ID may have been reused already
+	if m.inFlight[requestID] == request {
+		delete(m.inFlight, requestID)
+	}
+}
+
+// closeInputs tells requests still in flight that no more input is coming.
+func (m *multiplexedRequests) closeInputs() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for requestID, request := range m.inFlight {
+		close(request.queued)
+		delete(m.inFlight, requestID)
+	}
+}
+
+// responseWriter writes responses to a connection, tagging them with the ID
+// of the multiplexed request they belong to, if any. Writes are serialized
+// so that responses to concurrent requests do not interleave.
+type responseWriter struct {
+	conn      net.Conn
+	requestID *uint32
+	writing   *sync.Mutex
+}
+
+func (w *responseWriter) Write(response proto.Message) error {
+	w.writing.Lock()
+	defer w.writing.Unlock()
+
+	var messages *bytes.Buffer
+
+	if w.requestID != nil {
+		messages = protocol.MessagesForRequest(*w.requestID, response)
+	} else {
+		messages = protocol.Messages(response)
+	}
+
+	_, err := messages.WriteTo(w.conn)
+	return err
+}
+
+func (w *responseWriter) forRequest(requestID uint32) *responseWriter {
+	return &responseWriter{
+		conn:      w.conn,
+		requestID: &requestID,
+		writing:   w.writing,
+	}
+}
+
+// requestQueue yields the requests read from a connection. A request that
+// arrives while a process is being streamed is held until the stream ends.
+type requestQueue struct {
+	incoming <-chan proto.Message
+	held     proto.Message
+}
+
+func (q *requestQueue) Next() (proto.Message, bool) {
+	if q.held != nil {
+		request := q.held
+		q.held = nil
+		return request, true
+	}
+
+	request, ok := <-q.incoming
+	return request, ok
+}
+
 func New(
 	listenNetwork, listenAddr string,
 	containerGraceTime time.Duration,
 	backend backend.Backend,
+	eventHub *event_hub.Hub,
+	tlsConfig *tls.Config,
+	authorizer *Authorizer,
 ) *WardenServer {
 	return &WardenServer{
 		listenNetwork: listenNetwork,
@@ -53,6 +269,10 @@ func New(
 
 		containerGraceTime: containerGraceTime,
 		backend:            backend,
+		eventHub:           eventHub,
+
+		tlsConfig:  tlsConfig,
+		authorizer: authorizer,
 
 		setStopping: make(chan bool),
 		stopping:    make(chan bool),
@@ -72,7 +292,7 @@ func (s *WardenServer) Start() error {
 		return err
 	}
 
-	listener, err := net.Listen(s.listenNetwork, s.listenAddr)
+	listener, err := s.listen(s.listenNetwork, s.listenAddr)
 	if err != nil {
 		return err
 	}
@@ -103,10 +323,30 @@ func (s *WardenServer) Start() error {
 func (s *WardenServer) Stop() {
 	s.setStopping <- true
 	s.listener.Close()
+
+	if s.httpListener != nil {
+		s.httpListener.Close()
+	}
+
 	s.openRequests.Wait()
 	s.backend.Stop()
 }
 
+// listen listens on the address, over TLS if the server was configured
+// with it.
+func (s *WardenServer) listen(listenNetwork, listenAddr string) (net.Listener, error) {
+	listener, err := net.Listen(listenNetwork, listenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	return listener, nil
+}
+
 func (s *WardenServer) trackStopping() {
 	stopping := false
 
@@ -130,88 +370,226 @@ func (s *WardenServer) handleConnections(listener net.Listener) {
 	}
 }
 
+// serveConnection handles a connection's requests. Requests tagged with a
+// request ID are multiplexed: each is handled as soon as it arrives, and its
+// responses are tagged with the same ID. Any other request is handled in
+// turn, as legacy clients expect.
 func (s *WardenServer) serveConnection(conn net.Conn) {
-	read := bufio.NewReader(conn)
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// fail early, rather than on every attempt to read a request
+		err := tlsConn.Handshake()
+		if err != nil {
+			log.Println("tls handshake failed:", err)
+			conn.Close()
+			return
+		}
+	}
 
-	for {
-		var response proto.Message
-		var err error
+	incoming := make(chan proto.Message)
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	responses := &responseWriter{
+		conn:    conn,
+		writing: &sync.Mutex{},
+	}
+
+	client := clientName(conn)
+
+	go s.readRequests(client, conn, responses, incoming, finished)
 
+	requests := &requestQueue{incoming: incoming}
+
+	for {
 		if <-s.stopping {
 			conn.Close()
 			break
 		}
 
-		request, err := message_reader.ReadRequest(read)
-		if err == io.EOF {
+		request, ok := requests.Next()
+		if !ok {
 			break
 		}
 
-		if err != nil {
-			log.Println("error reading request:", err)
-			continue
-		}
-
 		if <-s.stopping {
 			conn.Close()
 			break
 		}
 
-		s.openRequests.Incr()
+		s.serveRequest(client, request, requests, responses)
+	}
+}
 
-		switch req := request.(type) {
-		case *protocol.PingRequest:
-			response, err = s.handlePing(req)
-		case *protocol.EchoRequest:
-			response, err = s.handleEcho(req)
-		case *protocol.CreateRequest:
-			response, err = s.handleCreate(req)
-		case *protocol.DestroyRequest:
-			response, err = s.handleDestroy(req)
-		case *protocol.ListRequest:
-			response, err = s.handleList(req)
-		case *protocol.StopRequest:
-			response, err = s.handleStop(req)
-		case *protocol.CopyInRequest:
-			response, err = s.handleCopyIn(req)
-		case *protocol.CopyOutRequest:
-			response, err = s.handleCopyOut(req)
-		case *protocol.RunRequest:
-			s.openRequests.Decr()
-			response, err = s.handleRun(conn, req)
-			s.openRequests.Incr()
-		case *protocol.AttachRequest:
-			s.openRequests.Decr()
-			response, err = s.handleAttach(conn, req)
-			s.openRequests.Incr()
-		case *protocol.LimitBandwidthRequest:
-			response, err = s.handleLimitBandwidth(req)
-		case *protocol.LimitMemoryRequest:
-			response, err = s.handleLimitMemory(req)
-		case *protocol.LimitDiskRequest:
-			response, err = s.handleLimitDisk(req)
-		case *protocol.LimitCpuRequest:
-			response, err = s.handleLimitCpu(req)
-		case *protocol.NetInRequest:
-			response, err = s.handleNetIn(req)
-		case *protocol.NetOutRequest:
-			response, err = s.handleNetOut(req)
-		case *protocol.InfoRequest:
-			response, err = s.handleInfo(req)
-		default:
-			err = UnhandledRequestError{request}
-		}
+// serveRequest handles a request from the client, writing its response, if
+// it has one. Streaming requests read any further input from requests.
+func (s *WardenServer) serveRequest(client string, request proto.Message, requests *requestQueue, responses *responseWriter) {
+	var response proto.Message
+	var err error
 
-		if err != nil {
-			response = &protocol.ErrorResponse{
-				Message: proto.String(err.Error()),
-			}
-		}
+	if _, ok := request.(*protocol.ProcessPayload); ok {
+		// stdin for a process that is no longer being streamed; there is no
+		// request to respond to
+		return
+	}
 
-		protocol.Messages(response).WriteTo(conn)
+	s.openRequests.Incr()
+	defer s.openRequests.Decr()
 
+	switch req := request.(type) {
+	case *protocol.RunRequest:
+		s.openRequests.Decr()
+		response, err = s.handleRun(client, responses, requests, req)
+		s.openRequests.Incr()
+	case *protocol.AttachRequest:
+		s.openRequests.Decr()
+		response, err = s.handleAttach(client, responses, requests, req)
+		s.openRequests.Incr()
+	case *protocol.EventsRequest:
+		s.openRequests.Decr()
+		response, err = s.handleEvents(client, responses, requests, req)
+		s.openRequests.Incr()
+	case *protocol.WaitRequest:
 		s.openRequests.Decr()
+		response, err = s.handleWait(client, req, abandonedBy(requests))
+		s.openRequests.Incr()
+	default:
+		response, err = s.handleRequest(client, request)
+	}
+
+	if err != nil {
+		response = errorResponse(err)
+	}
+
+	// streams that end without a final message, such as events, have
+	// nothing left to send
+	if response != nil {
+		responses.Write(response)
+	}
+}
+
+// handleRequest handles a request from the client that has a single
+// response.
+func (s *WardenServer) handleRequest(client string, request proto.Message) (proto.Message, error) {
+	err := s.authorize(client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req := request.(type) {
+	case *protocol.PingRequest:
+		return s.handlePing(req)
+	case *protocol.EchoRequest:
+		return s.handleEcho(req)
+	case *protocol.CreateRequest:
+		return s.handleCreate(client, req)
+	case *protocol.DestroyRequest:
+		return s.handleDestroy(req)
+	case *protocol.ListRequest:
+		return s.handleList(client, req)
+	case *protocol.StopRequest:
+		return s.handleStop(req)
+	case *protocol.CopyInRequest:
+		return s.handleCopyIn(req)
+	case *protocol.CopyOutRequest:
+		return s.handleCopyOut(req)
+	case *protocol.SignalRequest:
+		return s.handleSignal(req)
+	case *protocol.ListProcessesRequest:
+		return s.handleListProcesses(req)
+	case *protocol.LimitBandwidthRequest:
+		return s.handleLimitBandwidth(req)
+	case *protocol.LimitMemoryRequest:
+		return s.handleLimitMemory(req)
+	case *protocol.LimitDiskRequest:
+		return s.handleLimitDisk(req)
+	case *protocol.LimitCpuRequest:
+		return s.handleLimitCpu(req)
+	case *protocol.NetInRequest:
+		return s.handleNetIn(req)
+	case *protocol.NetOutRequest:
+		return s.handleNetOut(req)
+	case *protocol.InfoRequest:
+		return s.handleInfo(req)
+	case *protocol.BulkInfoRequest:
+		return s.handleBulkInfo(client, req)
+	case *protocol.HealthCheckRequest:
+		return s.handleHealthCheck(req)
+	default:
+		return nil, UnhandledRequestError{request}
+	}
+}
+
+// authorize checks that the client may make the request, if the server was
+// configured with an authorizer.
+func (s *WardenServer) authorize(client string, request proto.Message) error {
+	if s.authorizer == nil {
+		return nil
+	}
+
+	switch req := request.(type) {
+	case *protocol.CreateRequest:
+		return s.authorizer.AuthorizeCreate(client, req.GetRootfs(), bindMounts(req))
+	case *protocol.RunRequest:
+		return s.authorizer.AuthorizeRun(client, req.GetHandle(), req.GetPrivileged() || s.isRootUser(req.GetHandle(), req.GetUser()))
+	case *protocol.HealthCheckRequest:
+		return s.authorizer.AuthorizeRun(client, req.GetHandle(), req.GetPrivileged() || s.isRootUser(req.GetHandle(), req.GetUser()))
+	case *protocol.CopyInRequest:
+		return s.authorizer.AuthorizeCopy(client, req.GetHandle(), req.GetSrcPath(), s.isRootUser(req.GetHandle(), req.GetUser()))
+	case *protocol.CopyOutRequest:
+		return s.authorizer.AuthorizeCopy(client, req.GetHandle(), req.GetDstPath(), s.isRootUser(req.GetHandle(), req.GetUser()))
+	case interface {
+		GetHandle() string
+	}:
+		return s.authorizer.Authorize(client, req.GetHandle())
+	default:
+		return nil
+	}
+}
+
+// isRootUser reports whether the user is root in the container. If that
+// cannot be told, the user is assumed to be root, so that the policy errs
+// on the side of forbidding it.
+func (s *WardenServer) isRootUser(handle, user string) bool {
+	if backend.IsRoot(user) {
+		return true
+	}
+
+	container, err := s.backend.Lookup(handle)
+	if err != nil {
+		// the request fails for the unknown handle anyway
+		return false
+	}
+
+	root, err := container.IsRootUser(user)
+	if err != nil {
+		log.Println("failed to resolve user", user, "in", handle+":", err)
+		return true
+	}
+
+	return root
+}
+
+func (s *WardenServer) authorizeHandle(client, handle string) error {
+	if s.authorizer == nil {
+		return nil
+	}
+
+	return s.authorizer.Authorize(client, handle)
+}
+
+// subscribe subscribes to events, only letting through those for
+// containers the client owns.
+func (s *WardenServer) subscribe(client string, handles []string, types []backend.EventType) *event_hub.Subscription {
+	if s.authorizer == nil {
+		return s.eventHub.Subscribe(handles, types)
 	}
+
+	// checked as events are published, so that a container's destroyed
+	// event still reaches its owner
+	return s.eventHub.SubscribeMatching(handles, types, func(event backend.ContainerEvent) bool {
+		return s.authorizer.Owns(client, event.Handle)
+	})
 }
 
 func (s *WardenServer) removeExistingSocket() error {
@@ -232,7 +610,104 @@ func (s *WardenServer) removeExistingSocket() error {
 	return nil
 }
 
+// readRequests reads requests from the connection, sending those that are
+// not multiplexed to be handled in turn. A multiplexed request is handled
+// right away, and later messages with its ID, such as a process's stdin,
+// are its input until it has been handled.
+func (s *WardenServer) readRequests(
+	client string,
+	conn net.Conn,
+	responses *responseWriter,
+	requests chan<- proto.Message,
+	finished <-chan struct{},
+) {
+	multiplexed := &multiplexedRequests{
+		inFlight: map[uint32]*multiplexedRequest{},
+		mutex:    &sync.Mutex{},
+	}
+
+	defer func() {
+		multiplexed.closeInputs()
+		close(requests)
+	}()
+
+	read := bufio.NewReader(conn)
+
+	for {
+		request, requestID, err := message_reader.ReadRequestWithID(read)
+		if err == io.EOF {
+			return
+		}
+
+		if err != nil {
+			select {
+			case <-finished:
+				return
+			default:
+			}
+
+			log.Println("error reading request:", err)
+			continue
+		}
+
+		if requestID == nil {
+			select {
+			case requests <- request:
+			case <-finished:
+				return
+			}
+
+			continue
+		}
+
+		if inFlight, found := multiplexed.lookup(*requestID); found {
+			select {
+			case inFlight.queued <- request:
+				continue
+			case <-inFlight.done:
+				// handled already; the ID is free to be used again
+			}
+		}
+
+		if <-s.stopping {
+			conn.Close()
+			return
+		}
+
+		inFlight := multiplexed.add(*requestID)
+
+		go func(request proto.Message, requestID uint32) {
+			defer multiplexed.remove(requestID, inFlight)
+
+			s.serveRequest(
+				client,
+				request,
+				&requestQueue{incoming: inFlight.input},
+				responses.forRequest(requestID),
+			)
+		}(request, *requestID)
+	}
+}
+
 func (s *WardenServer) reapContainer(container backend.Container) {
 	log.Printf("reaping %s (idle for %s)\n", container.Handle(), container.GraceTime())
-	s.backend.Destroy(container.Handle())
+
+	s.eventHub.Publish(backend.ContainerEvent{
+		Type:   backend.EventTypeReaped,
+		Time:   time.Now(),
+		Handle: container.Handle(),
+		Details: map[string]string{
+			"grace_time": container.GraceTime().String(),
+		},
+	})
+
+	err := s.backend.Destroy(container.Handle())
+	if err != nil {
+		log.Println("failed to reap", container.Handle()+":", err)
+		return
+	}
+
+	if s.authorizer != nil {
+		s.authorizer.Release(container.Handle())
+	}
 }
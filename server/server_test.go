@@ -2,6 +2,8 @@ package server_test
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/pivotal-cf-experimental/garden/backend"
 	"github.com/pivotal-cf-experimental/garden/backend/fake_backend"
+	"github.com/pivotal-cf-experimental/garden/event_hub"
 	"github.com/pivotal-cf-experimental/garden/message_reader"
 	protocol "github.com/pivotal-cf-experimental/garden/protocol"
 	"github.com/pivotal-cf-experimental/garden/server"
@@ -30,7 +33,7 @@ var _ = Describe("The Warden server", func() {
 
 			socketPath := path.Join(tmpdir, "warden.sock")
 
-			wardenServer := server.New("unix", socketPath, 0, fake_backend.New())
+			wardenServer := server.New("unix", socketPath, 0, fake_backend.New(), event_hub.New(), nil, nil)
 
 			err = wardenServer.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -54,7 +57,7 @@ var _ = Describe("The Warden server", func() {
 			socket.WriteString("oops")
 			socket.Close()
 
-			wardenServer := server.New("unix", socketPath, 0, fake_backend.New())
+			wardenServer := server.New("unix", socketPath, 0, fake_backend.New(), event_hub.New(), nil, nil)
 
 			err = wardenServer.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -63,7 +66,7 @@ var _ = Describe("The Warden server", func() {
 
 	Context("when passed a tcp addr", func() {
 		It("listens on the given addr", func() {
-			wardenServer := server.New("tcp", ":60123", 0, fake_backend.New())
+			wardenServer := server.New("tcp", ":60123", 0, fake_backend.New(), event_hub.New(), nil, nil)
 
 			err := wardenServer.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -80,7 +83,7 @@ var _ = Describe("The Warden server", func() {
 
 		fakeBackend := fake_backend.New()
 
-		wardenServer := server.New("unix", socketPath, 0, fakeBackend)
+		wardenServer := server.New("unix", socketPath, 0, fakeBackend, event_hub.New(), nil, nil)
 
 		err = wardenServer.Start()
 		Expect(err).ToNot(HaveOccurred())
@@ -102,7 +105,7 @@ var _ = Describe("The Warden server", func() {
 		})
 		Expect(err).ToNot(HaveOccurred())
 
-		wardenServer := server.New("unix", socketPath, 0, fakeBackend)
+		wardenServer := server.New("unix", socketPath, 0, fakeBackend, event_hub.New(), nil, nil)
 
 		before := time.Now()
 
@@ -132,13 +135,475 @@ var _ = Describe("The Warden server", func() {
 			fakeBackend := fake_backend.New()
 			fakeBackend.StartError = disaster
 
-			wardenServer := server.New("unix", socketPath, 0, fakeBackend)
+			wardenServer := server.New("unix", socketPath, 0, fakeBackend, event_hub.New(), nil, nil)
 
 			err = wardenServer.Start()
 			Expect(err).To(Equal(disaster))
 		})
 	})
 
+	Context("when configured with TLS", func() {
+		var certDir string
+
+		var serverCA testCertificate
+		var serverCert testCertificate
+
+		var roots *x509.CertPool
+
+		BeforeEach(func() {
+			var err error
+
+			certDir, err = ioutil.TempDir(os.TempDir(), "warden-server-test")
+			Expect(err).ToNot(HaveOccurred())
+
+			serverCA = generateCertificate(certDir, "server-ca", nil)
+			serverCert = generateCertificate(certDir, "server", &serverCA)
+
+			roots = x509.NewCertPool()
+			roots.AddCert(serverCA.Certificate)
+		})
+
+		startServer := func(addr, clientCAFile string) {
+			tlsConfig, err := server.NewTLSConfig(serverCert.CertFile, serverCert.KeyFile, clientCAFile)
+			Expect(err).ToNot(HaveOccurred())
+
+			wardenServer := server.New("tcp", addr, 0, fake_backend.New(), event_hub.New(), tlsConfig, nil)
+
+			err = wardenServer.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(ErrorDialing("tcp", addr)).ShouldNot(HaveOccurred())
+		}
+
+		ping := func(addr string, config *tls.Config) error {
+			conn, err := tls.Dial("tcp", addr, config)
+			if err != nil {
+				return err
+			}
+
+			defer conn.Close()
+
+			_, err = protocol.Messages(&protocol.PingRequest{}).WriteTo(conn)
+			if err != nil {
+				return err
+			}
+
+			return message_reader.ReadMessage(bufio.NewReader(conn), &protocol.PingResponse{})
+		}
+
+		It("serves requests over TLS", func() {
+			startServer("127.0.0.1:60124", "")
+
+			err := ping("127.0.0.1:60124", &tls.Config{RootCAs: roots})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not serve requests in plaintext", func() {
+			startServer("127.0.0.1:60125", "")
+
+			conn, err := net.Dial("tcp", "127.0.0.1:60125")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = protocol.Messages(&protocol.PingRequest{}).WriteTo(conn)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = message_reader.ReadMessage(bufio.NewReader(conn), &protocol.PingResponse{})
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("with a client CA", func() {
+			var clientCA testCertificate
+
+			BeforeEach(func() {
+				clientCA = generateCertificate(certDir, "client-ca", nil)
+			})
+
+			It("serves clients with a certificate signed by the CA", func() {
+				startServer("127.0.0.1:60126", clientCA.CertFile)
+
+				clientCert := generateCertificate(certDir, "client", &clientCA)
+
+				err := ping("127.0.0.1:60126", &tls.Config{
+					RootCAs:      roots,
+					Certificates: []tls.Certificate{clientCert.TLSCertificate()},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("rejects clients without a certificate", func() {
+				startServer("127.0.0.1:60127", clientCA.CertFile)
+
+				err := ping("127.0.0.1:60127", &tls.Config{RootCAs: roots})
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("rejects clients with a certificate signed by another CA", func() {
+				startServer("127.0.0.1:60128", clientCA.CertFile)
+
+				otherCert := generateCertificate(certDir, "other-client", &serverCA)
+
+				err := ping("127.0.0.1:60128", &tls.Config{
+					RootCAs:      roots,
+					Certificates: []tls.Certificate{otherCert.TLSCertificate()},
+				})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with an authorizer", func() {
+			var clientCA testCertificate
+
+			var fakeBackend *fake_backend.FakeBackend
+			var authorizer *server.Authorizer
+			var wardenServer *server.WardenServer
+
+			var aliceConfig *tls.Config
+			var bobConfig *tls.Config
+			var adminConfig *tls.Config
+
+			clientConfig := func(name string) *tls.Config {
+				clientCert := generateCertificate(certDir, name, &clientCA)
+
+				return &tls.Config{
+					RootCAs:      roots,
+					Certificates: []tls.Certificate{clientCert.TLSCertificate()},
+				}
+			}
+
+			request := func(addr string, config *tls.Config, request proto.Message, response proto.Message) error {
+				conn, err := tls.Dial("tcp", addr, config)
+				Expect(err).ToNot(HaveOccurred())
+
+				defer conn.Close()
+
+				_, err = protocol.Messages(request).WriteTo(conn)
+				Expect(err).ToNot(HaveOccurred())
+
+				return message_reader.ReadMessage(bufio.NewReader(conn), response)
+			}
+
+			BeforeEach(func() {
+				clientCA = generateCertificate(certDir, "client-ca", nil)
+
+				aliceConfig = clientConfig("alice")
+				bobConfig = clientConfig("bob")
+				adminConfig = clientConfig("admin")
+
+				tlsConfig, err := server.NewTLSConfig(serverCert.CertFile, serverCert.KeyFile, clientCA.CertFile)
+				Expect(err).ToNot(HaveOccurred())
+
+				authorizer, err = server.NewAuthorizer(server.AuthorizationPolicy{
+					Admins:           []string{"admin"},
+					ForbidPrivileged: true,
+					HostCopyRoot:     "/var/copies",
+				}, "")
+				Expect(err).ToNot(HaveOccurred())
+
+				fakeBackend = fake_backend.New()
+
+				wardenServer = server.New("tcp", "127.0.0.1:60129", 0, fakeBackend, event_hub.New(), tlsConfig, authorizer)
+
+				err = wardenServer.Start()
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(ErrorDialing("tcp", "127.0.0.1:60129")).ShouldNot(HaveOccurred())
+
+				err = request("127.0.0.1:60129", aliceConfig, &protocol.CreateRequest{
+					Handle: proto.String("alices-container"),
+				}, &protocol.CreateResponse{})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				wardenServer.Stop()
+			})
+
+			It("only lists the containers a client created", func() {
+				var aliceList protocol.ListResponse
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.ListRequest{}, &aliceList)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(aliceList.GetHandles()).To(Equal([]string{"alices-container"}))
+
+				var bobList protocol.ListResponse
+				err = request("127.0.0.1:60129", bobConfig, &protocol.ListRequest{}, &bobList)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bobList.GetHandles()).To(BeEmpty())
+			})
+
+			It("only reports bulk info for the containers a client created", func() {
+				var bobInfo protocol.BulkInfoResponse
+				err := request("127.0.0.1:60129", bobConfig, &protocol.BulkInfoRequest{}, &bobInfo)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bobInfo.GetEntries()).To(BeEmpty())
+
+				err = request("127.0.0.1:60129", bobConfig, &protocol.BulkInfoRequest{
+					Handles: []string{"alices-container"},
+				}, &bobInfo)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bobInfo.GetEntries()).To(HaveLen(1))
+				Expect(bobInfo.GetEntries()[0].GetInfo()).To(BeNil())
+				Expect(bobInfo.GetEntries()[0].GetError().GetMessage()).To(Equal("unknown handle: alices-container"))
+			})
+
+			It("does not let other clients operate on a container", func() {
+				err := request("127.0.0.1:60129", bobConfig, &protocol.DestroyRequest{
+					Handle: proto.String("alices-container"),
+				}, &protocol.DestroyResponse{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "unknown handle: alices-container",
+					Code:    protocol.ErrorResponse_unknown_handle,
+				}))
+
+				Expect(fakeBackend.DestroyedContainers).To(BeEmpty())
+			})
+
+			It("does not let other clients take a container's handle", func() {
+				err := request("127.0.0.1:60129", bobConfig, &protocol.CreateRequest{
+					Handle: proto.String("alices-container"),
+				}, &protocol.CreateResponse{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "handle already exists: alices-container",
+					Code:    protocol.ErrorResponse_invalid_request,
+				}))
+
+				Expect(authorizer.Owns("alice", "alices-container")).To(BeTrue())
+				Expect(authorizer.Owns("bob", "alices-container")).To(BeFalse())
+			})
+
+			It("claims the handle before creating the container", func() {
+				ownedWhileCreating := make(chan bool, 1)
+
+				fakeBackend.CreateCallback = func(spec backend.ContainerSpec) {
+					ownedWhileCreating <- authorizer.Owns("bob", spec.Handle)
+				}
+
+				var response protocol.CreateResponse
+				err := request("127.0.0.1:60129", bobConfig, &protocol.CreateRequest{}, &response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(response.GetHandle()).ToNot(BeEmpty())
+				Expect(<-ownedWhileCreating).To(BeTrue())
+				Expect(authorizer.Owns("bob", response.GetHandle())).To(BeTrue())
+			})
+
+			Context("when creating the container fails", func() {
+				BeforeEach(func() {
+					fakeBackend.CreateError = errors.New("oh no!")
+				})
+
+				It("releases the handle", func() {
+					err := request("127.0.0.1:60129", bobConfig, &protocol.CreateRequest{
+						Handle: proto.String("bobs-container"),
+					}, &protocol.CreateResponse{})
+					Expect(err).To(HaveOccurred())
+
+					Expect(authorizer.Owns("bob", "bobs-container")).To(BeFalse())
+				})
+			})
+
+			It("lets admins operate on any container", func() {
+				err := request("127.0.0.1:60129", adminConfig, &protocol.DestroyRequest{
+					Handle: proto.String("alices-container"),
+				}, &protocol.DestroyResponse{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeBackend.DestroyedContainers).To(ContainElement("alices-container"))
+			})
+
+			It("does not let other clients create containers over a path on the host", func() {
+				err := request("127.0.0.1:60129", bobConfig, &protocol.CreateRequest{
+					Handle: proto.String("bobs-container"),
+					Rootfs: proto.String("/depot/alices-container"),
+				}, &protocol.CreateResponse{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "not permitted: rootfs paths",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}))
+
+				Expect(fakeBackend.CreatedContainers).ToNot(HaveKey("bobs-container"))
+			})
+
+			It("enforces the policy for other clients", func() {
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.RunRequest{
+					Handle:     proto.String("alices-container"),
+					Script:     proto.String("/some/script"),
+					Privileged: proto.Bool(true),
+				}, &protocol.ProcessPayload{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "not permitted: privileged processes",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}))
+
+				Expect(fakeBackend.CreatedContainers["alices-container"].RunningProcesses).To(BeEmpty())
+			})
+
+			It("treats running or copying as root as privileged", func() {
+				notPermitted := &message_reader.WardenError{
+					Message: "not permitted: privileged processes",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}
+
+				for _, user := range []string{"root", "root:vcap", "0", "0:0", "000", "1000:0"} {
+					err := request("127.0.0.1:60129", aliceConfig, &protocol.RunRequest{
+						Handle: proto.String("alices-container"),
+						Script: proto.String("/some/script"),
+						User:   proto.String(user),
+					}, &protocol.ProcessPayload{})
+					Expect(err).To(Equal(notPermitted), "running as "+user)
+
+					err = request("127.0.0.1:60129", aliceConfig, &protocol.HealthCheckRequest{
+						Handle: proto.String("alices-container"),
+						Script: proto.String("/some/check"),
+						User:   proto.String(user),
+					}, &protocol.HealthCheckResponse{})
+					Expect(err).To(Equal(notPermitted), "checking health as "+user)
+
+					err = request("127.0.0.1:60129", aliceConfig, &protocol.CopyInRequest{
+						Handle:  proto.String("alices-container"),
+						SrcPath: proto.String("/src"),
+						DstPath: proto.String("/dst"),
+						User:    proto.String(user),
+					}, &protocol.CopyInResponse{})
+					Expect(err).To(Equal(notPermitted), "copying in as "+user)
+
+					err = request("127.0.0.1:60129", aliceConfig, &protocol.CopyOutRequest{
+						Handle:  proto.String("alices-container"),
+						SrcPath: proto.String("/src"),
+						DstPath: proto.String("/dst"),
+						User:    proto.String(user),
+					}, &protocol.CopyOutResponse{})
+					Expect(err).To(Equal(notPermitted), "copying out as "+user)
+				}
+
+				container := fakeBackend.CreatedContainers["alices-container"]
+				Expect(container.RunningProcesses).To(BeEmpty())
+				Expect(container.HealthCheck).To(BeNil())
+				Expect(container.CopiedIn).To(BeEmpty())
+				Expect(container.CopiedOut).To(BeEmpty())
+			})
+
+			It("treats users that are root in the container as privileged", func() {
+				container := fakeBackend.CreatedContainers["alices-container"]
+				container.RootUsers = []string{"toor"}
+
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.RunRequest{
+					Handle: proto.String("alices-container"),
+					Script: proto.String("/some/script"),
+					User:   proto.String("toor"),
+				}, &protocol.ProcessPayload{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "not permitted: privileged processes",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}))
+
+				Expect(container.RunningProcesses).To(BeEmpty())
+			})
+
+			Context("when the user cannot be resolved in the container", func() {
+				It("treats them as privileged", func() {
+					container := fakeBackend.CreatedContainers["alices-container"]
+					container.IsRootUserError = errors.New("oh no!")
+
+					err := request("127.0.0.1:60129", aliceConfig, &protocol.CopyInRequest{
+						Handle:  proto.String("alices-container"),
+						SrcPath: proto.String("/var/copies/src"),
+						DstPath: proto.String("/dst"),
+						User:    proto.String("vcap"),
+					}, &protocol.CopyInResponse{})
+					Expect(err).To(Equal(&message_reader.WardenError{
+						Message: "not permitted: privileged processes",
+						Code:    protocol.ErrorResponse_not_permitted,
+					}))
+
+					Expect(container.CopiedIn).To(BeEmpty())
+				})
+			})
+
+			It("lets other clients run and copy as users other than root", func() {
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.CopyInRequest{
+					Handle:  proto.String("alices-container"),
+					SrcPath: proto.String("/var/copies/src"),
+					DstPath: proto.String("/dst"),
+					User:    proto.String("1000:1000"),
+				}, &protocol.CopyInResponse{})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = request("127.0.0.1:60129", aliceConfig, &protocol.RunRequest{
+					Handle: proto.String("alices-container"),
+					Script: proto.String("/some/script"),
+					User:   proto.String("rooter"),
+					Detach: proto.Bool(true),
+				}, &protocol.ProcessPayload{})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("confines other clients' copies in from the host to the copy root", func() {
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.CopyInRequest{
+					Handle:  proto.String("alices-container"),
+					SrcPath: proto.String("/var/copies/../../depot/bobs-container"),
+					DstPath: proto.String("/dst"),
+				}, &protocol.CopyInResponse{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "not permitted: copying outside /var/copies",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}))
+
+				err = request("127.0.0.1:60129", adminConfig, &protocol.CopyInRequest{
+					Handle:  proto.String("alices-container"),
+					SrcPath: proto.String("/depot/bobs-container"),
+					DstPath: proto.String("/dst"),
+				}, &protocol.CopyInResponse{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeBackend.CreatedContainers["alices-container"].CopiedIn).To(Equal([][]string{
+					{"/depot/bobs-container", "/dst", ""},
+				}))
+			})
+
+			It("confines other clients' copies out to the host to the copy root", func() {
+				err := request("127.0.0.1:60129", aliceConfig, &protocol.CopyOutRequest{
+					Handle:  proto.String("alices-container"),
+					SrcPath: proto.String("/src"),
+					DstPath: proto.String("/etc/cron.d"),
+				}, &protocol.CopyOutResponse{})
+				Expect(err).To(Equal(&message_reader.WardenError{
+					Message: "not permitted: copying outside /var/copies",
+					Code:    protocol.ErrorResponse_not_permitted,
+				}))
+
+				Expect(fakeBackend.CreatedContainers["alices-container"].CopiedOut).To(BeEmpty())
+
+				err = request("127.0.0.1:60129", aliceConfig, &protocol.CopyOutRequest{
+					Handle:  proto.String("alices-container"),
+					SrcPath: proto.String("/src"),
+					DstPath: proto.String("/var/copies/dst"),
+				}, &protocol.CopyOutResponse{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeBackend.CreatedContainers["alices-container"].CopiedOut).To(HaveLen(1))
+			})
+		})
+
+		Context("when the client CA file has no certificates", func() {
+			It("fails to configure TLS", func() {
+				bogusCA := path.Join(certDir, "bogus-ca.crt")
+
+				err := ioutil.WriteFile(bogusCA, []byte("bogus"), 0600)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = server.NewTLSConfig(serverCert.CertFile, serverCert.KeyFile, bogusCA)
+				Expect(err).To(Equal(server.InvalidClientCAError{bogusCA}))
+			})
+		})
+
+		Context("when the key does not match the certificate", func() {
+			It("fails to configure TLS", func() {
+				_, err := server.NewTLSConfig(serverCert.CertFile, serverCA.KeyFile, "")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Context("when listening on the socket fails", func() {
 		It("fails to start", func() {
 			tmpfile, err := ioutil.TempFile(os.TempDir(), "warden-server-test")
@@ -150,6 +615,9 @@ var _ = Describe("The Warden server", func() {
 				path.Join(tmpfile.Name(), "warden.sock"),
 				0,
 				fake_backend.New(),
+				event_hub.New(),
+				nil,
+				nil,
 			)
 
 			err = wardenServer.Start()
@@ -179,7 +647,7 @@ var _ = Describe("The Warden server", func() {
 		})
 
 		JustBeforeEach(func() {
-			wardenServer = server.New("unix", socketPath, 0, serverBackend)
+			wardenServer = server.New("unix", socketPath, 0, serverBackend, event_hub.New(), nil, nil)
 
 			err := wardenServer.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -252,6 +720,36 @@ var _ = Describe("The Warden server", func() {
 			})
 		})
 
+		Context("when a Wait request is in-flight", func() {
+			BeforeEach(func() {
+				container, err := serverBackend.Create(backend.ContainerSpec{Handle: "some-handle"})
+				Expect(err).ToNot(HaveOccurred())
+
+				container.(*fake_backend.FakeContainer).WaitTimesOut = true
+			})
+
+			It("does not wait for it to complete, and gives up waiting", func() {
+				writeMessages(&protocol.WaitRequest{
+					Handle:    proto.String("some-handle"),
+					ProcessId: proto.Uint32(1),
+				})
+
+				time.Sleep(10 * time.Millisecond)
+
+				before := time.Now()
+
+				wardenServer.Stop()
+
+				Expect(time.Since(before)).To(BeNumerically("<", 50*time.Millisecond))
+
+				var response protocol.WaitResponse
+
+				err := message_reader.ReadMessage(responses, &response)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*message_reader.WardenError).Code).To(Equal(protocol.ErrorResponse_timed_out))
+			})
+		})
+
 		dontWaitRequests := []proto.Message{
 			&protocol.RunRequest{
 				Handle: proto.String("some-handle"),